@@ -0,0 +1,662 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/spf13/cobra"
+)
+
+var analyzeTop int
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze <input.pdf>",
+	Short: "Show what is taking up space in a PDF",
+	Long: fmt.Sprintf(`Attribute the bytes of a PDF to images, fonts, page content, metadata,
+embedded files, annotations and unused objects, list the largest objects,
+and suggest which optimize options would help.
+
+%s
+  pdfed analyze report.pdf
+  pdfed analyze report.pdf --top 25
+  pdfed analyze report.pdf --json
+
+%s
+  Sizes are estimated from each object's encoded length. Objects packed into
+  object streams share their container's compressed size proportionally.
+  Cross-reference data and trailers are counted as structure.`, bold("Examples:"), bold("Notes:")),
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAnalyze(args[0])
+	},
+}
+
+func init() {
+	analyzeCmd.Flags().IntVarP(&analyzeTop, "top", "t", 10, "Number of largest objects to list")
+	rootCmd.AddCommand(analyzeCmd)
+}
+
+// sizeCategory is the bucket an object's bytes are attributed to.
+type sizeCategory string
+
+const (
+	catImages      sizeCategory = "images"
+	catFonts       sizeCategory = "fonts"
+	catContent     sizeCategory = "content"
+	catMetadata    sizeCategory = "metadata"
+	catEmbedded    sizeCategory = "embedded_files"
+	catAnnotations sizeCategory = "annotations"
+	catUnused      sizeCategory = "unused"
+	catStructure   sizeCategory = "structure"
+)
+
+var sizeCategories = []sizeCategory{catImages, catFonts, catContent, catMetadata, catEmbedded, catAnnotations, catUnused, catStructure}
+
+var sizeCategoryLabels = map[sizeCategory]string{
+	catImages:      "Images",
+	catFonts:       "Fonts",
+	catContent:     "Page content",
+	catMetadata:    "Metadata/XMP",
+	catEmbedded:    "Embedded files",
+	catAnnotations: "Annotations",
+	catUnused:      "Unused objects",
+	catStructure:   "Structure/other",
+}
+
+// objectSize is one indirect object with its estimated share of the file.
+type objectSize struct {
+	ObjNr    int          `json:"obj"`
+	Category sizeCategory `json:"category"`
+	Bytes    int64        `json:"bytes"`
+	Desc     string       `json:"description"`
+}
+
+type sizeReport struct {
+	fileSize   int64
+	byCategory map[sizeCategory]int64
+	counts     map[sizeCategory]int
+	objects    []objectSize // sorted by size, largest first
+
+	dupImages, dupFonts         int
+	dupImageBytes, dupFontBytes int64
+	usingObjectStreams          bool
+}
+
+func runAnalyze(inFile string) error {
+	printInfo(fmt.Sprintf("Analyzing %s…", inFile))
+
+	ctx, err := readContext(inFile, pdfConfig())
+	if err != nil {
+		return err
+	}
+
+	rep, err := analyzeSizes(ctx)
+	if err != nil {
+		return err
+	}
+	suggestions := sizeSuggestions(rep)
+
+	top := rep.objects
+	if analyzeTop >= 0 && len(top) > analyzeTop {
+		top = top[:analyzeTop]
+	}
+
+	if jsonOut {
+		cats := make([]map[string]interface{}, 0, len(sizeCategories))
+		for _, c := range sizeCategories {
+			cats = append(cats, map[string]interface{}{
+				"category": c,
+				"label":    sizeCategoryLabels[c],
+				"bytes":    rep.byCategory[c],
+				"human":    humanSize(rep.byCategory[c]),
+				"percent":  percentOf(rep.byCategory[c], rep.fileSize),
+				"objects":  rep.counts[c],
+			})
+		}
+		return jsonResultOK("analyze", map[string]interface{}{
+			"input":       inFile,
+			"size_bytes":  rep.fileSize,
+			"size_human":  humanSize(rep.fileSize),
+			"categories":  cats,
+			"largest":     top,
+			"suggestions": suggestions,
+		})
+	}
+
+	fmt.Println()
+	fmt.Println(bold(" Size breakdown") + "  " + dimStyle.Render(fmt.Sprintf("%s total", humanSize(rep.fileSize))))
+	fmt.Println(strings.Repeat("─", 50))
+	for _, c := range sizeCategories {
+		n := rep.byCategory[c]
+		if n == 0 {
+			continue
+		}
+		pct := percentOf(n, rep.fileSize)
+		bar := strings.Repeat("█", int(pct/5+0.5))
+		fmt.Printf("  %-16s %10s  %5.1f%%  %s\n", sizeCategoryLabels[c], humanSize(n), pct, cyan(bar))
+	}
+
+	fmt.Println()
+	fmt.Println(bold(" Largest objects"))
+	fmt.Println(strings.Repeat("─", 50))
+	for _, o := range top {
+		fmt.Printf("  %s  %-14s %10s  %s\n",
+			dimStyle.Render(fmt.Sprintf("#%-5d", o.ObjNr)), o.Category, humanSize(o.Bytes), o.Desc)
+	}
+
+	fmt.Println()
+	fmt.Println(bold(" Suggestions"))
+	fmt.Println(strings.Repeat("─", 50))
+	if len(suggestions) == 0 {
+		fmt.Println("  " + dimStyle.Render("Nothing obvious to gain — the file looks lean."))
+	}
+	for _, s := range suggestions {
+		fmt.Printf("  %s %s\n", cyan("→"), s)
+	}
+	fmt.Println()
+	return nil
+}
+
+func percentOf(n, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
+
+// analyzeSizes estimates the encoded size of every object in ctx and attributes it to a category.
+func analyzeSizes(ctx *model.Context) (*sizeReport, error) {
+	rep := &sizeReport{
+		fileSize:           ctx.Read.FileSize,
+		byCategory:         map[sizeCategory]int64{},
+		counts:             map[sizeCategory]int{},
+		usingObjectStreams: ctx.Read.UsingObjectStreams,
+	}
+
+	sizes, err := estimateObjectSizes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reachable, err := reachableObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cats, descs, err := categorizeObjects(ctx, reachable)
+	if err != nil {
+		return nil, err
+	}
+
+	var accounted int64
+	for objNr, n := range sizes {
+		cat, ok := cats[objNr]
+		if !ok {
+			cat = catStructure
+			if !reachable[objNr] {
+				cat = catUnused
+			}
+		}
+		desc := descs[objNr]
+		if desc == "" {
+			desc = describeObject(ctx, objNr)
+		}
+		rep.byCategory[cat] += n
+		rep.counts[cat]++
+		rep.objects = append(rep.objects, objectSize{ObjNr: objNr, Category: cat, Bytes: n, Desc: desc})
+		accounted += n
+	}
+
+	// Header, xref sections, trailers, xref streams and whitespace.
+	if rest := rep.fileSize - accounted; rest > 0 {
+		rep.byCategory[catStructure] += rest
+	}
+
+	sort.Slice(rep.objects, func(i, j int) bool {
+		if rep.objects[i].Bytes != rep.objects[j].Bytes {
+			return rep.objects[i].Bytes > rep.objects[j].Bytes
+		}
+		return rep.objects[i].ObjNr < rep.objects[j].ObjNr
+	})
+
+	scanStreams(ctx, rep)
+	return rep, nil
+}
+
+// estimateObjectSizes returns each object's estimated encoded size in bytes.
+// Object stream and xref stream containers are not listed: compressed objects
+// get a proportional share of their container instead.
+func estimateObjectSizes(ctx *model.Context) (map[int]int64, error) {
+	sizes := map[int]int64{}
+	members := map[int][]int{} // object stream → compressed objects inside it
+	var memberSum = map[int]int64{}
+
+	for objNr, e := range ctx.Table {
+		if objNr == 0 || e == nil || e.Free || e.Object == nil {
+			continue
+		}
+		switch e.Object.(type) {
+		case types.ObjectStreamDict, types.XRefStreamDict:
+			continue
+		}
+		n := int64(len(e.Object.PDFString()))
+		if raw := streamRaw(e.Object); raw != nil {
+			n += int64(len(raw)) + int64(len("stream\r\n\r\nendstream"))
+		}
+		if e.ObjectStream != nil {
+			members[*e.ObjectStream] = append(members[*e.ObjectStream], objNr)
+			memberSum[*e.ObjectStream] += n
+			sizes[objNr] = n
+			continue
+		}
+		sizes[objNr] = n + int64(len(fmt.Sprintf("%d 0 obj\n\nendobj\n", objNr)))
+	}
+
+	for osNr, objs := range members {
+		e, ok := ctx.Table[osNr]
+		if !ok || e.Object == nil || memberSum[osNr] == 0 {
+			continue
+		}
+		container := int64(len(streamRaw(e.Object)))
+		for _, objNr := range objs {
+			sizes[objNr] = sizes[objNr] * container / memberSum[osNr]
+		}
+	}
+	return sizes, nil
+}
+
+func streamRaw(o types.Object) []byte {
+	switch sd := o.(type) {
+	case types.StreamDict:
+		return sd.Raw
+	case *types.StreamDict:
+		return sd.Raw
+	case types.ObjectStreamDict:
+		return sd.Raw
+	case types.XRefStreamDict:
+		return sd.Raw
+	}
+	return nil
+}
+
+// objectDict returns the dictionary of a dict or stream object.
+func objectDict(o types.Object) (types.Dict, bool) {
+	switch d := o.(type) {
+	case types.Dict:
+		return d, true
+	case types.StreamDict:
+		return d.Dict, true
+	case *types.StreamDict:
+		return d.Dict, true
+	}
+	return nil, false
+}
+
+// forEachRef calls fn for every indirect reference directly contained in o.
+// key is the dictionary key the reference (or its enclosing array) sits under.
+func forEachRef(o types.Object, key string, fn func(key string, ref types.IndirectRef)) {
+	switch o := o.(type) {
+	case types.IndirectRef:
+		fn(key, o)
+	case types.Dict:
+		for k, v := range o {
+			forEachRef(v, k, fn)
+		}
+	case types.StreamDict:
+		forEachRef(o.Dict, key, fn)
+	case *types.StreamDict:
+		forEachRef(o.Dict, key, fn)
+	case types.Array:
+		for _, v := range o {
+			forEachRef(v, key, fn)
+		}
+	}
+}
+
+// reachableObjects returns the object numbers reachable from the trailer.
+func reachableObjects(ctx *model.Context) (map[int]bool, error) {
+	seen := map[int]bool{}
+	var queue []types.IndirectRef
+	for _, ir := range []*types.IndirectRef{ctx.Root, ctx.Info, ctx.Encrypt} {
+		if ir != nil {
+			queue = append(queue, *ir)
+		}
+	}
+	if ctx.AdditionalStreams != nil {
+		forEachRef(*ctx.AdditionalStreams, "", func(_ string, ir types.IndirectRef) {
+			queue = append(queue, ir)
+		})
+	}
+
+	for len(queue) > 0 {
+		ir := queue[0]
+		queue = queue[1:]
+		objNr := ir.ObjectNumber.Value()
+		if seen[objNr] {
+			continue
+		}
+		seen[objNr] = true
+		o, err := ctx.Dereference(ir)
+		if err != nil || o == nil {
+			continue
+		}
+		forEachRef(o, "", func(_ string, ref types.IndirectRef) {
+			if !seen[ref.ObjectNumber.Value()] {
+				queue = append(queue, ref)
+			}
+		})
+	}
+	return seen, nil
+}
+
+// categorizeObjects assigns a category to reachable objects that clearly belong to
+// one, and returns short descriptions for objects whose role is only known from
+// their referrer (content streams, font files, embedded file streams).
+func categorizeObjects(ctx *model.Context, reachable map[int]bool) (map[int]sizeCategory, map[int]string, error) {
+	cats := map[int]sizeCategory{}
+	descs := map[int]string{}
+
+	// mark assigns cat to objNr and everything it references that is not yet
+	// categorized, without crossing into the page tree or the catalog.
+	var mark func(objNr int, cat sizeCategory)
+	mark = func(objNr int, cat sizeCategory) {
+		if _, done := cats[objNr]; done || !reachable[objNr] {
+			return
+		}
+		o, err := ctx.Dereference(*types.NewIndirectRef(objNr, 0))
+		if err != nil || o == nil {
+			return
+		}
+		if d, ok := objectDict(o); ok {
+			if t := d.Type(); t != nil && (*t == "Page" || *t == "Pages" || *t == "Catalog") {
+				return
+			}
+		}
+		cats[objNr] = cat
+		forEachRef(o, "", func(key string, ref types.IndirectRef) {
+			switch key {
+			case "Parent", "P", "Dest", "D", "A", "Next":
+				return
+			}
+			mark(ref.ObjectNumber.Value(), cat)
+		})
+	}
+
+	objNrs := make([]int, 0, len(reachable))
+	for objNr := range reachable {
+		objNrs = append(objNrs, objNr)
+	}
+	sort.Ints(objNrs)
+
+	// Objects that identify themselves.
+	for _, objNr := range objNrs {
+		o, err := ctx.Dereference(*types.NewIndirectRef(objNr, 0))
+		if err != nil || o == nil {
+			continue
+		}
+		d, ok := objectDict(o)
+		if !ok {
+			continue
+		}
+		typ, sub := "", ""
+		if t := d.Type(); t != nil {
+			typ = *t
+		}
+		if s := d.Subtype(); s != nil {
+			sub = *s
+		}
+		switch {
+		case sub == "Image":
+			mark(objNr, catImages)
+		case typ == "Metadata":
+			mark(objNr, catMetadata)
+		case typ == "EmbeddedFile":
+			mark(objNr, catEmbedded)
+		case typ == "Filespec" || d.DictEntry("EF") != nil:
+			name := ""
+			if s := d.StringEntry("UF"); s != nil {
+				name = *s
+			} else if s := d.StringEntry("F"); s != nil {
+				name = *s
+			}
+			if ef := d.DictEntry("EF"); ef != nil {
+				for _, v := range ef {
+					if ir, ok := v.(types.IndirectRef); ok && name != "" {
+						descs[ir.ObjectNumber.Value()] = "Embedded file " + name
+					}
+				}
+			}
+			mark(objNr, catEmbedded)
+		case typ == "FontDescriptor":
+			fontName := ""
+			if n := d.NameEntry("FontName"); n != nil {
+				fontName = " " + *n
+			}
+			for _, k := range []string{"FontFile", "FontFile2", "FontFile3"} {
+				if ir := d.IndirectRefEntry(k); ir != nil {
+					descs[ir.ObjectNumber.Value()] = fmt.Sprintf("Font program (%s)%s", k, fontName)
+				}
+			}
+			mark(objNr, catFonts)
+		case typ == "Font":
+			mark(objNr, catFonts)
+		}
+	}
+
+	if ctx.Info != nil {
+		mark(ctx.Info.ObjectNumber.Value(), catMetadata)
+	}
+
+	// Page content streams, annotations and thumbnails.
+	for pageNr := 1; pageNr <= ctx.PageCount; pageNr++ {
+		pageDict, _, _, err := ctx.PageDict(pageNr, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if o, found := pageDict.Find("Contents"); found {
+			forEachRef(o, "", func(_ string, ir types.IndirectRef) {
+				objNr := ir.ObjectNumber.Value()
+				if _, done := cats[objNr]; !done {
+					descs[objNr] = fmt.Sprintf("Page content stream (page %d)", pageNr)
+				}
+				mark(objNr, catContent)
+			})
+		}
+		if o, found := pageDict.Find("Annots"); found {
+			if o, err = ctx.Dereference(o); err == nil {
+				forEachRef(o, "", func(_ string, ir types.IndirectRef) {
+					mark(ir.ObjectNumber.Value(), catAnnotations)
+				})
+			}
+		}
+		if ir := pageDict.IndirectRefEntry("Thumb"); ir != nil {
+			descs[ir.ObjectNumber.Value()] = fmt.Sprintf("Thumbnail (page %d)", pageNr)
+			mark(ir.ObjectNumber.Value(), catImages)
+		}
+	}
+
+	// Form XObjects not owned by an annotation are drawn as page content.
+	for _, objNr := range objNrs {
+		o, err := ctx.Dereference(*types.NewIndirectRef(objNr, 0))
+		if err != nil || o == nil {
+			continue
+		}
+		if d, ok := objectDict(o); ok {
+			if s := d.Subtype(); s != nil && *s == "Form" {
+				mark(objNr, catContent)
+			}
+		}
+	}
+
+	return cats, descs, nil
+}
+
+// describeObject returns a one-line human description of an object.
+func describeObject(ctx *model.Context, objNr int) string {
+	e, ok := ctx.Table[objNr]
+	if !ok || e.Object == nil {
+		return ""
+	}
+	o, err := ctx.Dereference(*types.NewIndirectRef(objNr, 0))
+	if err != nil || o == nil {
+		return ""
+	}
+	d, ok := objectDict(o)
+	if !ok {
+		switch o.(type) {
+		case types.Array:
+			return "Array"
+		default:
+			return "Value"
+		}
+	}
+
+	typ, sub := "", ""
+	if t := d.Type(); t != nil {
+		typ = *t
+	}
+	if s := d.Subtype(); s != nil {
+		sub = *s
+	}
+	filters := streamFilters(d)
+
+	switch {
+	case sub == "Image":
+		w, h, bpc := 0, 0, 0
+		if v := d.IntEntry("Width"); v != nil {
+			w = *v
+		}
+		if v := d.IntEntry("Height"); v != nil {
+			h = *v
+		}
+		if v := d.IntEntry("BitsPerComponent"); v != nil {
+			bpc = *v
+		}
+		s := fmt.Sprintf("Image %d×%d", w, h)
+		if bpc > 0 {
+			s += fmt.Sprintf(", %d bpc", bpc)
+		}
+		if filters != "" {
+			s += ", " + filters
+		}
+		return s
+	case typ == "Font":
+		s := "Font"
+		if n := d.NameEntry("BaseFont"); n != nil {
+			s += " " + *n
+		}
+		if sub != "" {
+			s += " (" + sub + ")"
+		}
+		return s
+	case typ == "FontDescriptor":
+		if n := d.NameEntry("FontName"); n != nil {
+			return "Font descriptor " + *n
+		}
+		return "Font descriptor"
+	case typ == "Metadata":
+		return "XMP metadata stream"
+	case sub == "Form":
+		return "Form XObject"
+	case typ == "Annot" || d.ArrayEntry("Rect") != nil && sub != "":
+		return "Annotation (" + sub + ")"
+	case typ != "":
+		return typ
+	case streamRaw(o) != nil:
+		if filters != "" {
+			return "Stream, " + filters
+		}
+		return "Stream (uncompressed)"
+	}
+	if objNr == infoObjNr(ctx) {
+		return "Document info dictionary"
+	}
+	return "Dictionary"
+}
+
+func infoObjNr(ctx *model.Context) int {
+	if ctx.Info == nil {
+		return -1
+	}
+	return ctx.Info.ObjectNumber.Value()
+}
+
+// streamFilters renders a stream's /Filter entry, e.g. "FlateDecode" or "ASCII85Decode+DCTDecode".
+func streamFilters(d types.Dict) string {
+	o, found := d.Find("Filter")
+	if !found {
+		return ""
+	}
+	switch f := o.(type) {
+	case types.Name:
+		return string(f)
+	case types.Array:
+		var names []string
+		for _, v := range f {
+			if n, ok := v.(types.Name); ok {
+				names = append(names, string(n))
+			}
+		}
+		return strings.Join(names, "+")
+	}
+	return ""
+}
+
+// scanStreams records duplicate image and font streams.
+func scanStreams(ctx *model.Context, rep *sizeReport) {
+	seen := map[[32]byte]bool{}
+	for _, o := range rep.objects {
+		e := ctx.Table[o.ObjNr]
+		raw := streamRaw(e.Object)
+		if raw == nil || (o.Category != catImages && o.Category != catFonts) {
+			continue
+		}
+		sum := sha256.Sum256(raw)
+		if !seen[sum] {
+			seen[sum] = true
+			continue
+		}
+		if o.Category == catImages {
+			rep.dupImages++
+			rep.dupImageBytes += o.Bytes
+		} else {
+			rep.dupFonts++
+			rep.dupFontBytes += o.Bytes
+		}
+	}
+}
+
+// sizeSuggestions turns a size report into concrete advice.
+func sizeSuggestions(rep *sizeReport) []string {
+	var out []string
+	share := func(c sizeCategory) float64 { return percentOf(rep.byCategory[c], rep.fileSize) }
+
+	if rep.dupImages > 0 || rep.dupFonts > 0 {
+		out = append(out, fmt.Sprintf("`pdfed optimize` will merge %d duplicate image(s) and %d duplicate font(s) (~%s)",
+			rep.dupImages, rep.dupFonts, humanSize(rep.dupImageBytes+rep.dupFontBytes)))
+	}
+	if n := rep.byCategory[catUnused]; n > 0 {
+		out = append(out, fmt.Sprintf("%d unreferenced object(s) take %s; `pdfed optimize` drops them when rewriting",
+			rep.counts[catUnused], humanSize(n)))
+	}
+	if !rep.usingObjectStreams && rep.counts[catStructure] > 50 {
+		out = append(out, "The file does not use object streams; `pdfed optimize` packs small objects into compressed object streams")
+	}
+	if share(catImages) >= 50 {
+		out = append(out, fmt.Sprintf("Images are %.0f%% of the file; `pdfed optimize --preset ebook` downsamples them to 150 DPI and recompresses JPEGs (or --preset screen for 72 DPI)", share(catImages)))
+	}
+	if share(catFonts) >= 25 {
+		out = append(out, fmt.Sprintf("Fonts are %.0f%% of the file; `pdfed optimize --subset-fonts` cuts embedded fonts down to the glyphs used", share(catFonts)))
+	}
+	if share(catMetadata) >= 10 {
+		out = append(out, fmt.Sprintf("Metadata is %.0f%% of the file; XMP edit history is often the culprit", share(catMetadata)))
+	}
+	if share(catEmbedded) >= 10 {
+		out = append(out, fmt.Sprintf("Embedded files are %.0f%% of the file; check whether the attachments are still needed", share(catEmbedded)))
+	}
+	return out
+}
@@ -0,0 +1,159 @@
+package cmd
+
+// CCITT Group 4 (ITU-T T.6) encoding for 1-bit images, the compression fax
+// machines and PDF scanners use for black-and-white pages.
+
+// Run-length codes from T.4 tables 2 and 3, as bit strings.
+var (
+	whiteTermCodes = [64]string{
+		"00110101", "000111", "0111", "1000", "1011", "1100", "1110", "1111",
+		"10011", "10100", "00111", "01000", "001000", "000011", "110100", "110101",
+		"101010", "101011", "0100111", "0001100", "0001000", "0010111", "0000011", "0000100",
+		"0101000", "0101011", "0010011", "0100100", "0011000", "00000010", "00000011", "00011010",
+		"00011011", "00010010", "00010011", "00010100", "00010101", "00010110", "00010111", "00101000",
+		"00101001", "00101010", "00101011", "00101100", "00101101", "00000100", "00000101", "00001010",
+		"00001011", "01010010", "01010011", "01010100", "01010101", "00100100", "00100101", "01011000",
+		"01011001", "01011010", "01011011", "01001010", "01001011", "00110010", "00110011", "00110100",
+	}
+	blackTermCodes = [64]string{
+		"0000110111", "010", "11", "10", "011", "0011", "0010", "00011",
+		"000101", "000100", "0000100", "0000101", "0000111", "00000100", "00000111", "000011000",
+		"0000010111", "0000011000", "0000001000", "00001100111", "00001101000", "00001101100", "00000110111", "00000101000",
+		"00000010111", "00000011000", "000011001010", "000011001011", "000011001100", "000011001101", "000001101000", "000001101001",
+		"000001101010", "000001101011", "000011010010", "000011010011", "000011010100", "000011010101", "000011010110", "000011010111",
+		"000001101100", "000001101101", "000011011010", "000011011011", "000001010100", "000001010101", "000001010110", "000001010111",
+		"000001100100", "000001100101", "000001010010", "000001010011", "000000100100", "000000110111", "000000111000", "000000100111",
+		"000000101000", "000001011000", "000001011001", "000000101011", "000000101100", "000001011010", "000001100110", "000001100111",
+	}
+	// Make-up codes for runs of 64 to 1728, indexed by run/64-1.
+	whiteMakeupCodes = [27]string{
+		"11011", "10010", "010111", "0110111", "00110110", "00110111", "01100100", "01100101",
+		"01101000", "01100111", "011001100", "011001101", "011010010", "011010011", "011010100", "011010101",
+		"011010110", "011010111", "011011000", "011011001", "011011010", "011011011", "010011000", "010011001",
+		"010011010", "011000", "010011011",
+	}
+	blackMakeupCodes = [27]string{
+		"0000001111", "000011001000", "000011001001", "000001011011", "000000110011", "000000110100", "000000110101", "0000001101100",
+		"0000001101101", "0000001001010", "0000001001011", "0000001001100", "0000001001101", "0000001110010", "0000001110011", "0000001110100",
+		"0000001110101", "0000001110110", "0000001110111", "0000001010010", "0000001010011", "0000001010100", "0000001010101", "0000001011010",
+		"0000001011011", "0000001100100", "0000001100101",
+	}
+	// Make-up codes for runs of 1792 to 2560, shared by both colors.
+	extMakeupCodes = [13]string{
+		"00000001000", "00000001100", "00000001101", "000000010010", "000000010011", "000000010100", "000000010101",
+		"000000010110", "000000010111", "000000011100", "000000011101", "000000011110", "000000011111",
+	}
+	// Vertical mode codes for a1-b1 = -3 … 3.
+	verticalCodes = [7]string{"0000010", "000010", "010", "1", "011", "000011", "0000011"}
+)
+
+type bitWriter struct {
+	buf  []byte
+	cur  byte
+	bits uint
+}
+
+func (w *bitWriter) write(code string) {
+	for i := 0; i < len(code); i++ {
+		w.cur <<= 1
+		if code[i] == '1' {
+			w.cur |= 1
+		}
+		if w.bits++; w.bits == 8 {
+			w.buf = append(w.buf, w.cur)
+			w.cur, w.bits = 0, 0
+		}
+	}
+}
+
+// writeBits writes the low n bits of v, most significant first.
+func (w *bitWriter) writeBits(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.cur <<= 1
+		w.cur |= byte(v>>uint(i)) & 1
+		if w.bits++; w.bits == 8 {
+			w.buf = append(w.buf, w.cur)
+			w.cur, w.bits = 0, 0
+		}
+	}
+}
+
+// bytes pads the last byte with zero bits and returns everything written;
+// writing may continue at the next byte.
+func (w *bitWriter) bytes() []byte {
+	if w.bits > 0 {
+		w.buf = append(w.buf, w.cur<<(8-w.bits))
+		w.cur, w.bits = 0, 0
+	}
+	return w.buf
+}
+
+func (w *bitWriter) run(n int, black bool) {
+	term, makeup := whiteTermCodes, whiteMakeupCodes
+	if black {
+		term, makeup = blackTermCodes, blackMakeupCodes
+	}
+	for n >= 2560 {
+		w.write(extMakeupCodes[12])
+		n -= 2560
+	}
+	switch {
+	case n >= 1792:
+		w.write(extMakeupCodes[n/64-28])
+	case n >= 64:
+		w.write(makeup[n/64-1])
+	}
+	w.write(term[n%64])
+}
+
+// nextChange returns the first position at or after from where the color of
+// line differs from the pixel before it (white before the line starts), or
+// len(line) if there is none.
+func nextChange(line []bool, from int) int {
+	for i := from; i < len(line); i++ {
+		if (i == 0 && line[i]) || (i > 0 && line[i] != line[i-1]) {
+			return i
+		}
+	}
+	return len(line)
+}
+
+// encodeCCITTG4 encodes rows of pixels (true is black) with Group 4
+// compression and an end-of-block marker, as CCITTFaxDecode with /K -1
+// expects.
+func encodeCCITTG4(rows [][]bool, width int) []byte {
+	var w bitWriter
+	ref := make([]bool, width)
+	for _, cur := range rows {
+		a0, black := -1, false
+		for a0 < width {
+			from := a0 + 1
+			if a0 < 0 {
+				from = 0
+			}
+			a1 := nextChange(cur, from)
+			b1 := nextChange(ref, from)
+			if b1 < width && ref[b1] == black {
+				b1 = nextChange(ref, b1+1)
+			}
+			b2 := nextChange(ref, b1+1)
+			switch {
+			case b2 < a1:
+				w.write("0001") // pass
+				a0 = b2
+			case a1-b1 >= -3 && a1-b1 <= 3:
+				w.write(verticalCodes[a1-b1+3])
+				a0, black = a1, !black
+			default:
+				a2 := nextChange(cur, a1+1)
+				w.write("001") // horizontal
+				w.run(a1-max(a0, 0), black)
+				w.run(a2-a1, !black)
+				a0 = a2
+			}
+		}
+		ref = cur
+	}
+	w.write("000000000001000000000001") // EOFB
+	return w.bytes()
+}
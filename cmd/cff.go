@@ -0,0 +1,865 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Compact Font Format programs (FontFile3 /Type1C and /CIDFontType0C) as far
+// as subsetting needs them. Unused glyphs get an empty charstring; glyph
+// ids, the charset and the encoding stay the same. The font is then laid
+// out again, since everything after the CharStrings moves.
+
+// cffIndex is a parsed INDEX: its items and the offset just past its end.
+type cffIndex struct {
+	items [][]byte
+	end   int
+}
+
+func readCFFIndex(b []byte, off int) (cffIndex, error) {
+	if off < 0 || off+2 > len(b) {
+		return cffIndex{}, fmt.Errorf("INDEX at %d out of bounds", off)
+	}
+	count := int(binary.BigEndian.Uint16(b[off:]))
+	if count == 0 {
+		return cffIndex{end: off + 2}, nil
+	}
+	if off+3 > len(b) {
+		return cffIndex{}, fmt.Errorf("truncated INDEX at %d", off)
+	}
+	offSize := int(b[off+2])
+	if offSize < 1 || offSize > 4 || off+3+(count+1)*offSize > len(b) {
+		return cffIndex{}, fmt.Errorf("bad INDEX at %d", off)
+	}
+	readOff := func(i int) int {
+		v := 0
+		for _, c := range b[off+3+i*offSize : off+3+(i+1)*offSize] {
+			v = v<<8 | int(c)
+		}
+		return v
+	}
+	base := off + 3 + (count+1)*offSize - 1
+	idx := cffIndex{items: make([][]byte, count)}
+	for i := 0; i < count; i++ {
+		s, e := readOff(i), readOff(i+1)
+		if s < 1 || e < s || base+e > len(b) {
+			return cffIndex{}, fmt.Errorf("bad INDEX offsets at %d", off)
+		}
+		idx.items[i] = b[base+s : base+e]
+	}
+	idx.end = base + readOff(count)
+	return idx, nil
+}
+
+func writeCFFIndex(items [][]byte) []byte {
+	if len(items) == 0 {
+		return []byte{0, 0}
+	}
+	total := 1
+	for _, it := range items {
+		total += len(it)
+	}
+	offSize := 1
+	for total >= 1<<(8*offSize) {
+		offSize++
+	}
+	out := binary.BigEndian.AppendUint16(nil, uint16(len(items)))
+	out = append(out, byte(offSize))
+	putOff := func(v int) {
+		for i := offSize - 1; i >= 0; i-- {
+			out = append(out, byte(v>>(8*i)))
+		}
+	}
+	o := 1
+	putOff(o)
+	for _, it := range items {
+		o += len(it)
+		putOff(o)
+	}
+	for _, it := range items {
+		out = append(out, it...)
+	}
+	return out
+}
+
+// cffDictEntry is one operator of a DICT with its operands, both raw (for
+// writing back unchanged) and as numbers.
+type cffDictEntry struct {
+	op   int // escaped operators are 1200+n
+	raw  []byte
+	args []float64
+}
+
+// Operators whose operands are offsets, which a new layout changes.
+const (
+	cffCharset     = 15
+	cffEncoding    = 16
+	cffCharStrings = 17
+	cffPrivate     = 18
+	cffSubrs       = 19
+	cffROS         = 1230
+	cffFDArray     = 1236
+	cffFDSelect    = 1237
+)
+
+func parseCFFDict(b []byte) ([]cffDictEntry, error) {
+	var out []cffDictEntry
+	start := 0
+	var args []float64
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c <= 21:
+			op := int(c)
+			i++
+			if c == 12 {
+				if i >= len(b) {
+					return nil, fmt.Errorf("truncated DICT")
+				}
+				op = 1200 + int(b[i])
+				i++
+			}
+			raw := b[start:i]
+			// Keep only the operand bytes in raw.
+			if op >= 1200 {
+				raw = raw[:len(raw)-2]
+			} else {
+				raw = raw[:len(raw)-1]
+			}
+			out = append(out, cffDictEntry{op, raw, args})
+			args, start = nil, i
+		case c == 28:
+			if i+3 > len(b) {
+				return nil, fmt.Errorf("truncated DICT")
+			}
+			args = append(args, float64(int16(binary.BigEndian.Uint16(b[i+1:]))))
+			i += 3
+		case c == 29:
+			if i+5 > len(b) {
+				return nil, fmt.Errorf("truncated DICT")
+			}
+			args = append(args, float64(int32(binary.BigEndian.Uint32(b[i+1:]))))
+			i += 5
+		case c == 30:
+			// A real; its value is never an offset, so skip the nibbles.
+			for i++; i < len(b); i++ {
+				if b[i]&0x0F == 0x0F || b[i]>>4 == 0x0F {
+					i++
+					break
+				}
+			}
+			args = append(args, 0)
+		case c >= 32 && c <= 246:
+			args = append(args, float64(int(c)-139))
+			i++
+		case c >= 247 && c <= 250:
+			if i+2 > len(b) {
+				return nil, fmt.Errorf("truncated DICT")
+			}
+			args = append(args, float64((int(c)-247)*256+int(b[i+1])+108))
+			i += 2
+		case c >= 251 && c <= 254:
+			if i+2 > len(b) {
+				return nil, fmt.Errorf("truncated DICT")
+			}
+			args = append(args, float64(-(int(c)-251)*256-int(b[i+1])-108))
+			i += 2
+		default:
+			return nil, fmt.Errorf("bad DICT byte %d", c)
+		}
+	}
+	return out, nil
+}
+
+func cffDictLookup(d []cffDictEntry, op int) []float64 {
+	for _, e := range d {
+		if e.op == op {
+			return e.args
+		}
+	}
+	return nil
+}
+
+// writeCFFDict encodes d, writing the operands of the operators in set as
+// 5-byte integers so the size does not depend on their values.
+func writeCFFDict(d []cffDictEntry, set map[int][]int) []byte {
+	var out []byte
+	for _, e := range d {
+		if vals, ok := set[e.op]; ok {
+			for _, v := range vals {
+				out = append(out, 29)
+				out = binary.BigEndian.AppendUint32(out, uint32(int32(v)))
+			}
+		} else {
+			out = append(out, e.raw...)
+		}
+		if e.op >= 1200 {
+			out = append(out, 12, byte(e.op-1200))
+		} else {
+			out = append(out, byte(e.op))
+		}
+	}
+	return out
+}
+
+// cffFont is a parsed single-font CFF program.
+type cffFont struct {
+	data        []byte
+	header      []byte
+	names       []byte // the Name INDEX, as is
+	top         []cffDictEntry
+	strings     cffIndex
+	stringsRaw  []byte
+	gsubrsRaw   []byte
+	gsubrs      [][]byte
+	charStrings [][]byte
+	cid         bool
+	charset     []int // SID (or CID) per glyph
+}
+
+func parseCFF(b []byte) (*cffFont, error) {
+	if len(b) < 4 || b[0] != 1 {
+		return nil, fmt.Errorf("not a CFF font")
+	}
+	f := &cffFont{data: b, header: b[:b[2]]}
+	names, err := readCFFIndex(b, int(b[2]))
+	if err != nil {
+		return nil, err
+	}
+	if len(names.items) != 1 {
+		return nil, fmt.Errorf("CFF font set with %d fonts", len(names.items))
+	}
+	f.names = b[b[2]:names.end]
+	tops, err := readCFFIndex(b, names.end)
+	if err != nil {
+		return nil, err
+	}
+	if len(tops.items) != 1 {
+		return nil, fmt.Errorf("no Top DICT")
+	}
+	if f.top, err = parseCFFDict(tops.items[0]); err != nil {
+		return nil, err
+	}
+	if f.strings, err = readCFFIndex(b, tops.end); err != nil {
+		return nil, err
+	}
+	f.stringsRaw = b[tops.end:f.strings.end]
+	gsubrs, err := readCFFIndex(b, f.strings.end)
+	if err != nil {
+		return nil, err
+	}
+	f.gsubrs = gsubrs.items
+	f.gsubrsRaw = b[f.strings.end:gsubrs.end]
+	f.cid = cffDictLookup(f.top, cffROS) != nil
+	if cffDictLookup(f.top, 1220) != nil { // SyntheticBase
+		return nil, fmt.Errorf("synthetic CFF font")
+	}
+
+	cs := cffDictLookup(f.top, cffCharStrings)
+	if len(cs) != 1 {
+		return nil, fmt.Errorf("no CharStrings")
+	}
+	idx, err := readCFFIndex(b, int(cs[0]))
+	if err != nil {
+		return nil, err
+	}
+	f.charStrings = idx.items
+	if f.charset, err = f.readCharset(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// rangeLength returns how many bytes the charset, encoding or FDSelect data
+// starting at off takes.
+func (f *cffFont) rangeLength(op, off int) (int, error) {
+	b, n := f.data, len(f.charStrings)
+	if off < 0 || off >= len(b) {
+		return 0, fmt.Errorf("offset %d out of bounds", off)
+	}
+	p := off + 1
+	switch op {
+	case cffCharset:
+		switch b[off] {
+		case 0:
+			p += 2 * (n - 1)
+		case 1, 2:
+			size := 1
+			if b[off] == 2 {
+				size = 2
+			}
+			for covered := 1; covered < n; {
+				if p+2+size > len(b) {
+					return 0, fmt.Errorf("truncated charset")
+				}
+				left := int(b[p+2])
+				if size == 2 {
+					left = int(binary.BigEndian.Uint16(b[p+2:]))
+				}
+				covered += left + 1
+				p += 2 + size
+			}
+		default:
+			return 0, fmt.Errorf("unknown charset format %d", b[off])
+		}
+	case cffEncoding:
+		if p >= len(b) {
+			return 0, fmt.Errorf("truncated encoding")
+		}
+		switch b[off] & 0x7F {
+		case 0:
+			p += 1 + int(b[p])
+		case 1:
+			p += 1 + 2*int(b[p])
+		default:
+			return 0, fmt.Errorf("unknown encoding format %d", b[off]&0x7F)
+		}
+		if b[off]&0x80 != 0 {
+			if p >= len(b) {
+				return 0, fmt.Errorf("truncated encoding")
+			}
+			p += 1 + 3*int(b[p])
+		}
+	case cffFDSelect:
+		switch b[off] {
+		case 0:
+			p += n
+		case 3:
+			if p+2 > len(b) {
+				return 0, fmt.Errorf("truncated FDSelect")
+			}
+			p += 2 + 3*int(binary.BigEndian.Uint16(b[p:])) + 2
+		default:
+			return 0, fmt.Errorf("unknown FDSelect format %d", b[off])
+		}
+	}
+	if p > len(b) {
+		return 0, fmt.Errorf("data at %d out of bounds", off)
+	}
+	return p - off, nil
+}
+
+// readCharset returns the SID (CID in CID-keyed fonts) of every glyph.
+func (f *cffFont) readCharset() ([]int, error) {
+	n := len(f.charStrings)
+	out := make([]int, n)
+	args := cffDictLookup(f.top, cffCharset)
+	if args == nil || args[0] == 0 {
+		// ISOAdobe: glyph i is SID i.
+		for i := range out {
+			out[i] = i
+		}
+		return out, nil
+	}
+	off := int(args[0])
+	if off <= 2 {
+		return nil, fmt.Errorf("expert charset")
+	}
+	if _, err := f.rangeLength(cffCharset, off); err != nil {
+		return nil, err
+	}
+	b := f.data
+	p := off + 1
+	switch b[off] {
+	case 0:
+		for gid := 1; gid < n; gid++ {
+			out[gid] = int(binary.BigEndian.Uint16(b[p:]))
+			p += 2
+		}
+	default:
+		size := int(b[off])
+		for gid := 1; gid < n; {
+			first := int(binary.BigEndian.Uint16(b[p:]))
+			left := int(b[p+2])
+			if size == 2 {
+				left = int(binary.BigEndian.Uint16(b[p+2:]))
+			}
+			for i := 0; i <= left && gid < n; i++ {
+				out[gid] = first + i
+				gid++
+			}
+			p += 2 + size
+		}
+	}
+	return out, nil
+}
+
+// builtinEncoding returns the glyph for each code of a non-CID font's own
+// encoding.
+func (f *cffFont) builtinEncoding() (map[int]int, error) {
+	enc := map[int]int{}
+	sidGID := map[int]int{}
+	for gid, sid := range f.charset {
+		if _, ok := sidGID[sid]; !ok {
+			sidGID[sid] = gid
+		}
+	}
+	args := cffDictLookup(f.top, cffEncoding)
+	if args == nil || args[0] == 0 {
+		for code, sid := range standardEncodingSIDs() {
+			if gid, ok := sidGID[sid]; ok {
+				enc[code] = gid
+			}
+		}
+		return enc, nil
+	}
+	off := int(args[0])
+	if off == 1 {
+		return nil, fmt.Errorf("expert encoding")
+	}
+	if _, err := f.rangeLength(cffEncoding, off); err != nil {
+		return nil, err
+	}
+	b := f.data
+	p := off + 1
+	switch b[off] & 0x7F {
+	case 0:
+		n := int(b[p])
+		for i := 0; i < n; i++ {
+			enc[int(b[p+1+i])] = i + 1
+		}
+		p += 1 + n
+	case 1:
+		n := int(b[p])
+		gid := 1
+		for i := 0; i < n; i++ {
+			first, left := int(b[p+1+2*i]), int(b[p+2+2*i])
+			for c := first; c <= first+left; c++ {
+				enc[c] = gid
+				gid++
+			}
+		}
+		p += 1 + 2*n
+	}
+	if b[off]&0x80 != 0 {
+		n := int(b[p])
+		for i := 0; i < n; i++ {
+			code := int(b[p+1+3*i])
+			sid := int(binary.BigEndian.Uint16(b[p+2+3*i:]))
+			if gid, ok := sidGID[sid]; ok {
+				enc[code] = gid
+			}
+		}
+	}
+	return enc, nil
+}
+
+// sid returns the string id of a glyph name, or -1.
+func (f *cffFont) sid(name string) int {
+	for i, s := range cffStandardStrings {
+		if s == name {
+			return i
+		}
+	}
+	for i, s := range f.strings.items {
+		if string(s) == name {
+			return len(cffStandardStrings) + i
+		}
+	}
+	return -1
+}
+
+// glyphByName returns the glyph with a name, or -1.
+func (f *cffFont) glyphByName(name string) int {
+	sid := f.sid(name)
+	if sid < 0 || f.cid {
+		return -1
+	}
+	for gid, s := range f.charset {
+		if s == sid {
+			return gid
+		}
+	}
+	return -1
+}
+
+func cffSubrBias(n int) int {
+	switch {
+	case n < 1240:
+		return 107
+	case n < 33900:
+		return 1131
+	}
+	return 32768
+}
+
+// seacComponents returns the standard codes of the base and accent glyphs
+// a Type 2 charstring builds itself from with the deprecated endchar form
+// of seac, if it does.
+func seacComponents(cs []byte, gsubrs, lsubrs [][]byte) (int, int, bool) {
+	var stack []float64
+	stems := 0
+	depth := 0
+	var run func(cs []byte) (done bool, base, accent int, ok bool)
+	run = func(cs []byte) (bool, int, int, bool) {
+		if depth++; depth > 10 {
+			return true, 0, 0, false
+		}
+		defer func() { depth-- }()
+		for i := 0; i < len(cs); {
+			c := cs[i]
+			switch {
+			case c == 28:
+				if i+3 > len(cs) {
+					return true, 0, 0, false
+				}
+				stack = append(stack, float64(int16(binary.BigEndian.Uint16(cs[i+1:]))))
+				i += 3
+				continue
+			case c >= 32 && c <= 246:
+				stack = append(stack, float64(int(c)-139))
+				i++
+				continue
+			case c >= 247 && c <= 250:
+				if i+2 > len(cs) {
+					return true, 0, 0, false
+				}
+				stack = append(stack, float64((int(c)-247)*256+int(cs[i+1])+108))
+				i += 2
+				continue
+			case c >= 251 && c <= 254:
+				if i+2 > len(cs) {
+					return true, 0, 0, false
+				}
+				stack = append(stack, float64(-(int(c)-251)*256-int(cs[i+1])-108))
+				i += 2
+				continue
+			case c == 255:
+				if i+5 > len(cs) {
+					return true, 0, 0, false
+				}
+				stack = append(stack, float64(int32(binary.BigEndian.Uint32(cs[i+1:])))/65536)
+				i += 5
+				continue
+			}
+			i++
+			switch c {
+			case 1, 3, 18, 23: // hstem, vstem, hstemhm, vstemhm
+				stems += len(stack) / 2
+			case 19, 20: // hintmask, cntrmask
+				stems += len(stack) / 2
+				i += (stems + 7) / 8
+			case 10, 29: // callsubr, callgsubr
+				if len(stack) == 0 {
+					return true, 0, 0, false
+				}
+				subrs := lsubrs
+				if c == 29 {
+					subrs = gsubrs
+				}
+				n := int(stack[len(stack)-1]) + cffSubrBias(len(subrs))
+				stack = stack[:len(stack)-1]
+				if n < 0 || n >= len(subrs) {
+					return true, 0, 0, false
+				}
+				if done, base, accent, ok := run(subrs[n]); done {
+					return done, base, accent, ok
+				}
+				continue
+			case 11: // return
+				return false, 0, 0, false
+			case 14: // endchar
+				if len(stack) >= 4 {
+					n := len(stack)
+					return true, int(stack[n-2]), int(stack[n-1]), true
+				}
+				return true, 0, 0, false
+			case 12:
+				i++
+			}
+			stack = stack[:0]
+		}
+		return false, 0, 0, false
+	}
+	_, base, accent, ok := run(cs)
+	return base, accent, ok
+}
+
+// subsetCFF replaces the charstrings of the glyphs not in keep with an
+// empty one. It returns the new font and the number of glyphs with
+// outlines before and after.
+func subsetCFF(b []byte, keep map[int]bool) ([]byte, int, int, error) {
+	f, err := parseCFF(b)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	n := len(f.charStrings)
+	kept := map[int]bool{0: true}
+	for gid := range keep {
+		if gid >= 0 && gid < n {
+			kept[gid] = true
+		}
+	}
+
+	// Private dicts, one per font dict of a CID-keyed font.
+	type private struct {
+		dict  []cffDictEntry
+		subrs []byte // the Subrs INDEX, as is
+		local [][]byte
+	}
+	readPrivate := func(top []cffDictEntry) (*private, error) {
+		args := cffDictLookup(top, cffPrivate)
+		if len(args) != 2 {
+			return nil, nil
+		}
+		size, off := int(args[0]), int(args[1])
+		if off < 0 || size < 0 || off+size > len(b) {
+			return nil, fmt.Errorf("Private DICT out of bounds")
+		}
+		d, err := parseCFFDict(b[off : off+size])
+		if err != nil {
+			return nil, err
+		}
+		p := &private{dict: d}
+		if s := cffDictLookup(d, cffSubrs); len(s) == 1 {
+			idx, err := readCFFIndex(b, off+int(s[0]))
+			if err != nil {
+				return nil, err
+			}
+			p.subrs = b[off+int(s[0]) : idx.end]
+			p.local = idx.items
+		}
+		return p, nil
+	}
+	var (
+		fdDicts  [][]cffDictEntry
+		privates []*private
+	)
+	if f.cid {
+		args := cffDictLookup(f.top, cffFDArray)
+		if len(args) != 1 || len(cffDictLookup(f.top, cffFDSelect)) != 1 {
+			return nil, 0, 0, fmt.Errorf("CID-keyed font without FDArray")
+		}
+		fds, err := readCFFIndex(b, int(args[0]))
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		for _, item := range fds.items {
+			d, err := parseCFFDict(item)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			p, err := readPrivate(d)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			fdDicts = append(fdDicts, d)
+			privates = append(privates, p)
+		}
+	} else {
+		p, err := readPrivate(f.top)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		privates = append(privates, p)
+
+		// Accented glyphs may be drawn from two others (seac).
+		sidGID := map[int]int{}
+		for gid, sid := range f.charset {
+			sidGID[sid] = gid
+		}
+		var local [][]byte
+		if p != nil {
+			local = p.local
+		}
+		std := standardEncodingSIDs()
+		for gid := range kept {
+			base, accent, ok := seacComponents(f.charStrings[gid], f.gsubrs, local)
+			if !ok {
+				continue
+			}
+			for _, code := range []int{base, accent} {
+				sid, known := std[code]
+				g, found := sidGID[sid]
+				if !known || !found {
+					return nil, 0, 0, fmt.Errorf("accented glyph %d refers to missing code %d", gid, code)
+				}
+				kept[g] = true
+			}
+		}
+	}
+
+	before, after := 0, 0
+	cs := make([][]byte, n)
+	for gid, s := range f.charStrings {
+		if len(s) > 1 {
+			before++
+		}
+		if kept[gid] {
+			cs[gid] = s
+			if len(s) > 1 {
+				after++
+			}
+		} else {
+			cs[gid] = []byte{14} // endchar
+		}
+	}
+
+	// Copy the data the Top DICT points at, to lay it out again.
+	blob := func(op int) ([]byte, error) {
+		args := cffDictLookup(f.top, op)
+		if len(args) != 1 || (op == cffCharset && args[0] <= 2) || (op == cffEncoding && args[0] <= 1) {
+			return nil, nil
+		}
+		l, err := f.rangeLength(op, int(args[0]))
+		if err != nil {
+			return nil, err
+		}
+		return b[int(args[0]) : int(args[0])+l], nil
+	}
+	charset, err := blob(cffCharset)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	encoding, err := blob(cffEncoding)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	fdSelect, err := blob(cffFDSelect)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	charStrings := writeCFFIndex(cs)
+
+	// Everything after the global subrs, in order, with offsets assigned in
+	// a second pass once the sizes are known.
+	layout := func(offsets map[string]int) []byte {
+		top := map[int][]int{cffCharStrings: {offsets["charstrings"]}}
+		if charset != nil {
+			top[cffCharset] = []int{offsets["charset"]}
+		}
+		if encoding != nil {
+			top[cffEncoding] = []int{offsets["encoding"]}
+		}
+		if fdSelect != nil {
+			top[cffFDSelect] = []int{offsets["fdselect"]}
+		}
+		if f.cid {
+			top[cffFDArray] = []int{offsets["fdarray"]}
+		} else if privates[0] != nil {
+			top[cffPrivate] = []int{offsets["private0.size"], offsets["private0"]}
+		}
+		out := append([]byte(nil), f.header...)
+		out = append(out, f.names...)
+		out = append(out, writeCFFIndex([][]byte{writeCFFDict(f.top, top)})...)
+		out = append(out, f.stringsRaw...)
+		out = append(out, f.gsubrsRaw...)
+		place := func(name string, data []byte) {
+			offsets[name] = len(out)
+			out = append(out, data...)
+		}
+		if charset != nil {
+			place("charset", charset)
+		}
+		if encoding != nil {
+			place("encoding", encoding)
+		}
+		if fdSelect != nil {
+			place("fdselect", fdSelect)
+		}
+		place("charstrings", charStrings)
+		if f.cid {
+			items := make([][]byte, len(fdDicts))
+			for i, d := range fdDicts {
+				key := fmt.Sprintf("private%d", i)
+				items[i] = writeCFFDict(d, map[int][]int{cffPrivate: {offsets[key+".size"], offsets[key]}})
+			}
+			place("fdarray", writeCFFIndex(items))
+		}
+		for i, p := range privates {
+			if p == nil {
+				continue
+			}
+			key := fmt.Sprintf("private%d", i)
+			set := map[int][]int{}
+			if p.subrs != nil {
+				set[cffSubrs] = []int{offsets[key+".subrs"]}
+			}
+			d := writeCFFDict(p.dict, set)
+			offsets[key+".size"] = len(d)
+			offsets[key+".subrs"] = len(d)
+			place(key, d)
+			out = append(out, p.subrs...)
+		}
+		return out
+	}
+	offsets := map[string]int{}
+	layout(offsets)
+	out := layout(offsets)
+	return out, before, after, nil
+}
+
+// cffStandardStrings are the strings every CFF font has without storing
+// them, string ids 0 to 390 (CFF spec appendix A).
+var cffStandardStrings = [...]string{
+	".notdef", "space", "exclam", "quotedbl", "numbersign", "dollar", "percent",
+	"ampersand", "quoteright", "parenleft", "parenright", "asterisk", "plus", "comma",
+	"hyphen", "period", "slash", "zero", "one", "two", "three", "four", "five", "six",
+	"seven", "eight", "nine", "colon", "semicolon", "less", "equal", "greater",
+	"question", "at", "A", "B", "C", "D", "E", "F", "G", "H", "I", "J", "K", "L", "M",
+	"N", "O", "P", "Q", "R", "S", "T", "U", "V", "W", "X", "Y", "Z", "bracketleft",
+	"backslash", "bracketright", "asciicircum", "underscore", "quoteleft", "a", "b", "c",
+	"d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t",
+	"u", "v", "w", "x", "y", "z", "braceleft", "bar", "braceright", "asciitilde",
+	"exclamdown", "cent", "sterling", "fraction", "yen", "florin", "section", "currency",
+	"quotesingle", "quotedblleft", "guillemotleft", "guilsinglleft", "guilsinglright",
+	"fi", "fl", "endash", "dagger", "daggerdbl", "periodcentered", "paragraph", "bullet",
+	"quotesinglbase", "quotedblbase", "quotedblright", "guillemotright", "ellipsis",
+	"perthousand", "questiondown", "grave", "acute", "circumflex", "tilde", "macron",
+	"breve", "dotaccent", "dieresis", "ring", "cedilla", "hungarumlaut", "ogonek",
+	"caron", "emdash", "AE", "ordfeminine", "Lslash", "Oslash", "OE", "ordmasculine",
+	"ae", "dotlessi", "lslash", "oslash", "oe", "germandbls", "onesuperior", "logicalnot",
+	"mu", "trademark", "Eth", "onehalf", "plusminus", "Thorn", "onequarter", "divide",
+	"brokenbar", "degree", "thorn", "threequarters", "twosuperior", "registered", "minus",
+	"eth", "multiply", "threesuperior", "copyright", "Aacute", "Acircumflex", "Adieresis",
+	"Agrave", "Aring", "Atilde", "Ccedilla", "Eacute", "Ecircumflex", "Edieresis",
+	"Egrave", "Iacute", "Icircumflex", "Idieresis", "Igrave", "Ntilde", "Oacute",
+	"Ocircumflex", "Odieresis", "Ograve", "Otilde", "Scaron", "Uacute", "Ucircumflex",
+	"Udieresis", "Ugrave", "Yacute", "Ydieresis", "Zcaron", "aacute", "acircumflex",
+	"adieresis", "agrave", "aring", "atilde", "ccedilla", "eacute", "ecircumflex",
+	"edieresis", "egrave", "iacute", "icircumflex", "idieresis", "igrave", "ntilde",
+	"oacute", "ocircumflex", "odieresis", "ograve", "otilde", "scaron", "uacute",
+	"ucircumflex", "udieresis", "ugrave", "yacute", "ydieresis", "zcaron", "exclamsmall",
+	"Hungarumlautsmall", "dollaroldstyle", "dollarsuperior", "ampersandsmall",
+	"Acutesmall", "parenleftsuperior", "parenrightsuperior", "twodotenleader",
+	"onedotenleader", "zerooldstyle", "oneoldstyle", "twooldstyle", "threeoldstyle",
+	"fouroldstyle", "fiveoldstyle", "sixoldstyle", "sevenoldstyle", "eightoldstyle",
+	"nineoldstyle", "commasuperior", "threequartersemdash", "periodsuperior",
+	"questionsmall", "asuperior", "bsuperior", "centsuperior", "dsuperior", "esuperior",
+	"isuperior", "lsuperior", "msuperior", "nsuperior", "osuperior", "rsuperior",
+	"ssuperior", "tsuperior", "ff", "ffi", "ffl", "parenleftinferior",
+	"parenrightinferior", "Circumflexsmall", "hyphensuperior", "Gravesmall", "Asmall",
+	"Bsmall", "Csmall", "Dsmall", "Esmall", "Fsmall", "Gsmall", "Hsmall", "Ismall",
+	"Jsmall", "Ksmall", "Lsmall", "Msmall", "Nsmall", "Osmall", "Psmall", "Qsmall",
+	"Rsmall", "Ssmall", "Tsmall", "Usmall", "Vsmall", "Wsmall", "Xsmall", "Ysmall",
+	"Zsmall", "colonmonetary", "onefitted", "rupiah", "Tildesmall", "exclamdownsmall",
+	"centoldstyle", "Lslashsmall", "Scaronsmall", "Zcaronsmall", "Dieresissmall",
+	"Brevesmall", "Caronsmall", "Dotaccentsmall", "Macronsmall", "figuredash",
+	"hypheninferior", "Ogoneksmall", "Ringsmall", "Cedillasmall", "questiondownsmall",
+	"oneeighth", "threeeighths", "fiveeighths", "seveneighths", "onethird", "twothirds",
+	"zerosuperior", "foursuperior", "fivesuperior", "sixsuperior", "sevensuperior",
+	"eightsuperior", "ninesuperior", "zeroinferior", "oneinferior", "twoinferior",
+	"threeinferior", "fourinferior", "fiveinferior", "sixinferior", "seveninferior",
+	"eightinferior", "nineinferior", "centinferior", "dollarinferior", "periodinferior",
+	"commainferior", "Agravesmall", "Aacutesmall", "Acircumflexsmall", "Atildesmall",
+	"Adieresissmall", "Aringsmall", "AEsmall", "Ccedillasmall", "Egravesmall",
+	"Eacutesmall", "Ecircumflexsmall", "Edieresissmall", "Igravesmall", "Iacutesmall",
+	"Icircumflexsmall", "Idieresissmall", "Ethsmall", "Ntildesmall", "Ogravesmall",
+	"Oacutesmall", "Ocircumflexsmall", "Otildesmall", "Odieresissmall", "OEsmall",
+	"Oslashsmall", "Ugravesmall", "Uacutesmall", "Ucircumflexsmall", "Udieresissmall",
+	"Yacutesmall", "Thornsmall", "Ydieresissmall", "001.000", "001.001", "001.002",
+	"001.003", "Black", "Bold", "Book", "Light", "Medium", "Regular", "Roman", "Semibold",
+}
+
+// standardEncodingSIDs maps the codes of StandardEncoding to string ids.
+func standardEncodingSIDs() map[int]int {
+	m := map[int]int{}
+	for c := 32; c <= 126; c++ {
+		m[c] = c - 31
+	}
+	// The rest are string ids 96 to 149, in code order.
+	for i, c := range []int{161, 162, 163, 164, 165, 166, 167, 168, 169, 170, 171, 172, 173, 174, 175, 177, 178, 179, 180, 182, 183, 184, 185, 186, 187, 188, 189, 191, 193, 194, 195, 196, 197, 198, 199, 200, 202, 203, 205, 206, 207, 208, 225, 227, 232, 233, 234, 235, 241, 245, 248, 249, 250, 251} {
+		m[c] = 96 + i
+	}
+	return m
+}
@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
 	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
 	"github.com/spf13/cobra"
 )
@@ -12,22 +15,68 @@ import (
 // ── encrypt ───────────────────────────────────────────────────────────────────
 
 var (
-	encryptOutput   string
-	encryptUserPW   string
-	encryptOwnerPW  string
+	encryptOutput    string
+	encryptUserPW    string
+	encryptOwnerPW   string
+	encryptUserFrom  string
+	encryptOwnerFrom string
+	encryptAllow     []string
+	encryptDeny      []string
+	encryptAlgorithm string
 )
 
 var encryptCmd = &cobra.Command{
 	Use:   "encrypt <input.pdf>",
 	Short: "Password-protect a PDF",
 	Long: `Encrypts a PDF with a user password (required to open) and optional owner
-password (required to edit/print). Without -o, encrypts in-place.`,
+password (required to edit/print). Without -o, encrypts in-place.
+
+Users who open the file with the user password may print it and nothing
+else, unless --allow and --deny say otherwise. Both take print, copy,
+modify, annotate, fill-forms, assemble or all; repeat them or separate
+names with commas. --deny wins over --allow, so "--allow all --deny copy"
+grants everything but copying. Annotating also lets users fill forms, and
+screen readers may always extract text.
+
+--algorithm chooses aes-256 (the default) or aes-128. PDF 2.0 files can
+only use aes-256.
+
+Passwords given as flags show up in shell history and process lists.
+--user-pw-from and --owner-pw-from read them instead from: prompt (asks
+twice, without echo), stdin, env:NAME, file:PATH or fd:N. Files and
+streams give one password per line; both passwords may come from stdin.`,
+	Example: `  pdfed encrypt report.pdf --user-pw open --owner-pw edit
+  pdfed encrypt report.pdf --owner-pw edit --allow all --deny copy,modify
+  pdfed encrypt form.pdf --user-pw open --owner-pw edit --allow fill-forms --algorithm aes-128
+  pdfed encrypt report.pdf --user-pw-from prompt --owner-pw-from env:PDF_OWNER_PW`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if encryptUserPW == "" && encryptOwnerPW == "" {
+		if encryptUserPW == "" && encryptOwnerPW == "" && encryptUserFrom == "" && encryptOwnerFrom == "" {
 			return fmt.Errorf("provide at least --user-pw or --owner-pw")
 		}
-		return runEncrypt(args[0])
+		perms, err := encryptPermissions(encryptAllow, encryptDeny)
+		if err != nil {
+			return err
+		}
+		keyLength, err := encryptKeyLength(encryptAlgorithm)
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		if encryptUserFrom != "" {
+			if encryptUserPW, err = readPassword(encryptUserFrom, "user password", true); err != nil {
+				return err
+			}
+		}
+		if encryptOwnerFrom != "" {
+			if encryptOwnerPW, err = readPassword(encryptOwnerFrom, "owner password", true); err != nil {
+				return err
+			}
+		}
+		if encryptUserPW == "" && encryptOwnerPW == "" {
+			return fmt.Errorf("both passwords are empty")
+		}
+		return runEncrypt(args[0], perms, keyLength)
 	},
 }
 
@@ -35,10 +84,82 @@ func init() {
 	encryptCmd.Flags().StringVarP(&encryptOutput, "output", "o", "", "Output file (default: in-place)")
 	encryptCmd.Flags().StringVar(&encryptUserPW, "user-pw", "", "User password (required to open)")
 	encryptCmd.Flags().StringVar(&encryptOwnerPW, "owner-pw", "", "Owner password (required to modify)")
+	encryptCmd.Flags().StringVar(&encryptUserFrom, "user-pw-from", "", "Read the user password from "+passwordSourceHelp)
+	encryptCmd.Flags().StringVar(&encryptOwnerFrom, "owner-pw-from", "", "Read the owner password from "+passwordSourceHelp)
+	encryptCmd.MarkFlagsMutuallyExclusive("user-pw", "user-pw-from")
+	encryptCmd.MarkFlagsMutuallyExclusive("owner-pw", "owner-pw-from")
+	encryptCmd.Flags().StringSliceVar(&encryptAllow, "allow", nil, "Permission to grant users: print, copy, modify, annotate, fill-forms, assemble, all (repeatable)")
+	encryptCmd.Flags().StringSliceVar(&encryptDeny, "deny", nil, "Permission to withhold from users (repeatable; wins over --allow)")
+	encryptCmd.Flags().StringVar(&encryptAlgorithm, "algorithm", "aes-256", "Encryption algorithm: aes-256 or aes-128")
 	rootCmd.AddCommand(encryptCmd)
 }
 
-func runEncrypt(inFile string) error {
+// permissionBits are the P bits each --allow/--deny name controls
+// (ISO 32000 table 22). Printing sets both the low- and high-quality bits.
+var permissionBits = []struct {
+	name string
+	bits model.PermissionFlags
+}{
+	{"print", model.PermissionPrintRev2 | model.PermissionPrintRev3},
+	{"copy", model.PermissionExtract},
+	{"modify", model.PermissionModify},
+	{"annotate", model.PermissionModAnnFillForm},
+	{"fill-forms", model.PermissionFillRev3},
+	{"assemble", model.PermissionAssembleRev3},
+}
+
+// encryptPermissions applies allow, then deny, to pdfcpu's default
+// permissions (print only). Extraction for accessibility is always granted,
+// as PDF 2.0 asks: screen readers rely on it, and it does not allow copying.
+func encryptPermissions(allow, deny []string) (model.PermissionFlags, error) {
+	lookup := func(name string) (model.PermissionFlags, error) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		var all model.PermissionFlags
+		for _, p := range permissionBits {
+			if p.name == name {
+				return p.bits, nil
+			}
+			all |= p.bits
+		}
+		if name == "all" {
+			return all, nil
+		}
+		names := make([]string, 0, len(permissionBits)+1)
+		for _, p := range permissionBits {
+			names = append(names, p.name)
+		}
+		return 0, fmt.Errorf("unknown permission %q (use %s or all)", name, strings.Join(names, ", "))
+	}
+	perms := model.PermissionsPrint | model.PermissionExtractRev3
+	for _, name := range allow {
+		bits, err := lookup(name)
+		if err != nil {
+			return 0, fmt.Errorf("--allow: %w", err)
+		}
+		perms |= bits
+	}
+	for _, name := range deny {
+		bits, err := lookup(name)
+		if err != nil {
+			return 0, fmt.Errorf("--deny: %w", err)
+		}
+		perms &^= bits
+	}
+	return perms, nil
+}
+
+// encryptKeyLength returns the AES key length --algorithm names.
+func encryptKeyLength(algorithm string) (int, error) {
+	switch strings.ToLower(strings.ReplaceAll(algorithm, "-", "")) {
+	case "aes256":
+		return 256, nil
+	case "aes128":
+		return 128, nil
+	}
+	return 0, fmt.Errorf("unknown algorithm %q (use aes-256 or aes-128)", algorithm)
+}
+
+func runEncrypt(inFile string, perms model.PermissionFlags, keyLength int) error {
 	printInfo(fmt.Sprintf("Encrypting %s…", inFile))
 
 	conf := model.NewDefaultConfiguration()
@@ -47,6 +168,9 @@ func runEncrypt(inFile string) error {
 	if conf.OwnerPW == "" {
 		conf.OwnerPW = conf.UserPW // sensible default
 	}
+	conf.EncryptUsingAES = true
+	conf.EncryptKeyLength = keyLength
+	conf.Permissions = perms
 
 	outFile := encryptOutput
 	if outFile == "" {
@@ -57,17 +181,24 @@ func runEncrypt(inFile string) error {
 		return err
 	}
 
+	enc, err := readBackEncryption(outFile, conf.UserPW, conf.OwnerPW)
+	if err != nil {
+		return err
+	}
+
 	fi, _ := os.Stat(outFile)
 	size := ""
 	if fi != nil {
 		size = fmt.Sprintf(" (%s)", humanSize(fi.Size()))
 	}
 	printSuccess(fmt.Sprintf("Encrypted: %s%s", outFile, size))
+	printEncryptionSummary(enc, conf.UserPW, conf.OwnerPW)
 	if jsonOut {
 		fields := map[string]interface{}{
-			"input":    inFile,
-			"output":   outFile,
-			"in_place": outFile == inFile,
+			"input":      inFile,
+			"output":     outFile,
+			"in_place":   outFile == inFile,
+			"encryption": enc,
 		}
 		if fi != nil {
 			fields["size_bytes"] = fi.Size()
@@ -78,20 +209,84 @@ func runEncrypt(inFile string) error {
 	return nil
 }
 
+// readBackEncryption reads the encryption of a file just written, as a
+// reader opening it with these passwords will see it.
+func readBackEncryption(file, userPW, ownerPW string) (*encryptionInfo, error) {
+	conf := pdfConfig()
+	conf.UserPW = userPW
+	conf.OwnerPW = ownerPW
+	ctx, err := readContext(file, conf)
+	if err != nil {
+		return nil, fmt.Errorf("reading back %s: %w", file, err)
+	}
+	enc, err := describeEncryption(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if enc == nil {
+		return nil, fmt.Errorf("%s was written without encryption", file)
+	}
+	return enc, nil
+}
+
+// printEncryptionSummary prints the algorithm and what the user password
+// allows.
+func printEncryptionSummary(enc *encryptionInfo, userPW, ownerPW string) {
+	printInfo(fmt.Sprintf("%s (%d-bit key), revision %d", enc.Algorithm, enc.KeyBits, enc.Revision))
+	var granted, withheld []string
+	for _, p := range enc.Permissions.rows() {
+		if p.on {
+			granted = append(granted, p.label)
+		} else {
+			withheld = append(withheld, p.label)
+		}
+	}
+	if len(granted) == 0 {
+		granted = []string{"nothing"}
+	}
+	printInfo("Users may: " + strings.Join(granted, ", "))
+	if len(withheld) > 0 {
+		printf("    %s\n", dimStyle.Render("withheld: "+strings.Join(withheld, ", ")))
+	}
+	if ownerPW == userPW {
+		printWarning("The owner password is the user password: whoever opens the file has every permission")
+	}
+}
+
 // ── decrypt ───────────────────────────────────────────────────────────────────
 
 var (
 	decryptOutput string
 	decryptPW     string
+	decryptPWFrom string
 )
 
 var decryptCmd = &cobra.Command{
 	Use:   "decrypt <input.pdf>",
 	Short: "Remove password protection from a PDF",
-	Args:  cobra.ExactArgs(1),
+	Long: `Removes the encryption from a PDF, given its user or owner password.
+Without -o, decrypts in-place.
+
+--password-from reads the password instead of taking it on the command line:
+prompt (without echo), stdin, env:NAME, file:PATH or fd:N. Without either
+flag, the empty password is used, which is all a file with only an owner
+password needs; if the file needs more and stdin is a terminal, the
+password is asked for.`,
+	Example: `  pdfed decrypt locked.pdf -o unlocked.pdf
+  pdfed decrypt locked.pdf --password-from env:PDF_PW
+  printf '%s\n' "$PW" | pdfed decrypt locked.pdf --password-from stdin`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if decryptPW == "" {
-			return fmt.Errorf("provide the password with --password")
+		source := decryptPWFrom
+		if source == "" && !cmd.Flags().Changed("password") && stdinIsTerminal() && needsUserPassword(args[0]) {
+			source = "prompt"
+		}
+		cmd.SilenceUsage = true
+		if source != "" {
+			var err error
+			if decryptPW, err = readPassword(source, "password", false); err != nil {
+				return err
+			}
 		}
 		return runDecrypt(args[0])
 	},
@@ -100,10 +295,20 @@ var decryptCmd = &cobra.Command{
 func init() {
 	decryptCmd.Flags().StringVarP(&decryptOutput, "output", "o", "", "Output file (default: in-place)")
 	decryptCmd.Flags().StringVar(&decryptPW, "password", "", "PDF password")
-	_ = decryptCmd.MarkFlagRequired("password")
+	decryptCmd.Flags().StringVar(&decryptPWFrom, "password-from", "", "Read the password from "+passwordSourceHelp)
+	decryptCmd.MarkFlagsMutuallyExclusive("password", "password-from")
 	rootCmd.AddCommand(decryptCmd)
 }
 
+// needsUserPassword reports whether inFile does not open with the empty
+// password. Other read errors are left for the command to report.
+func needsUserPassword(inFile string) bool {
+	conf := pdfConfig()
+	conf.Cmd = model.LISTINFO
+	_, err := readContext(inFile, conf)
+	return errors.Is(err, pdfcpu.ErrWrongPassword)
+}
+
 func runDecrypt(inFile string) error {
 	printInfo(fmt.Sprintf("Decrypting %s…", inFile))
 
@@ -117,6 +322,9 @@ func runDecrypt(inFile string) error {
 	}
 
 	if err := api.DecryptFile(inFile, outFile, conf); err != nil {
+		if decryptPW == "" && errors.Is(err, pdfcpu.ErrWrongPassword) {
+			return fmt.Errorf("%s needs a password; provide it with --password or --password-from", inFile)
+		}
 		return err
 	}
 
@@ -140,3 +348,114 @@ func runDecrypt(inFile string) error {
 	}
 	return nil
 }
+
+// ── security report ───────────────────────────────────────────────────────────
+
+// pdfPermissions is the effective meaning of the /P permission bits (ISO 32000 table 22).
+type pdfPermissions struct {
+	Print          bool `json:"print"`
+	PrintHighRes   bool `json:"print_high_quality"`
+	Modify         bool `json:"modify"`
+	Copy           bool `json:"copy"`
+	Annotate       bool `json:"annotate"`
+	FillForms      bool `json:"fill_forms"`
+	ExtractForA11y bool `json:"extract_accessibility"`
+	Assemble       bool `json:"assemble"`
+}
+
+// decodePermissions interprets the P entry for the given security handler revision.
+// Bits 9–12 only exist from revision 3 on; for revision 2 they follow bits 3–6.
+func decodePermissions(p, rev int) pdfPermissions {
+	bit := func(n int) bool { return p&(1<<(n-1)) != 0 }
+	perms := pdfPermissions{
+		Print:    bit(3),
+		Modify:   bit(4),
+		Copy:     bit(5),
+		Annotate: bit(6),
+	}
+	if rev >= 3 {
+		perms.PrintHighRes = bit(3) && bit(12)
+		perms.FillForms = bit(6) || bit(9)
+		perms.ExtractForA11y = bit(10)
+		perms.Assemble = bit(11)
+	} else {
+		perms.PrintHighRes = bit(3)
+		perms.FillForms = bit(6)
+		perms.ExtractForA11y = bit(5)
+		perms.Assemble = bit(4)
+	}
+	return perms
+}
+
+type permissionRow struct {
+	label string
+	on    bool
+}
+
+// rows lists the permissions in display order.
+func (p pdfPermissions) rows() []permissionRow {
+	return []permissionRow{
+		{"Print", p.Print},
+		{"High-quality print", p.PrintHighRes},
+		{"Modify contents", p.Modify},
+		{"Copy text & graphics", p.Copy},
+		{"Annotate", p.Annotate},
+		{"Fill forms", p.FillForms},
+		{"Extract for accessibility", p.ExtractForA11y},
+		{"Assemble (insert/rotate/delete pages)", p.Assemble},
+	}
+}
+
+// encryptionInfo describes how a document is encrypted.
+type encryptionInfo struct {
+	Handler         string         `json:"handler"`
+	Algorithm       string         `json:"algorithm"`
+	KeyBits         int            `json:"key_bits"`
+	Version         int            `json:"v"`
+	Revision        int            `json:"revision"`
+	EncryptMetadata bool           `json:"encrypt_metadata"`
+	P               int            `json:"p"`
+	Permissions     pdfPermissions `json:"permissions"`
+}
+
+// describeEncryption returns the encryption details of ctx, or nil if it is not encrypted.
+func describeEncryption(ctx *model.Context) (*encryptionInfo, error) {
+	if ctx.Encrypt == nil || ctx.E == nil {
+		return nil, nil
+	}
+	d, err := ctx.EncryptDict()
+	if err != nil {
+		return nil, err
+	}
+
+	e := ctx.E
+	info := &encryptionInfo{
+		Handler:         "Standard",
+		Version:         e.V,
+		Revision:        e.R,
+		EncryptMetadata: e.Emd,
+		P:               e.P,
+		Permissions:     decodePermissions(e.P, e.R),
+	}
+	if f := d.NameEntry("Filter"); f != nil {
+		info.Handler = *f
+	}
+
+	switch e.V {
+	case 5:
+		info.Algorithm, info.KeyBits = "AES-256", 256
+	case 4:
+		info.KeyBits = 128
+		info.Algorithm = "RC4-128"
+		if ctx.AES4Streams {
+			info.Algorithm = "AES-128"
+		}
+	default:
+		info.KeyBits = e.L
+		if info.KeyBits == 0 {
+			info.KeyBits = 40
+		}
+		info.Algorithm = fmt.Sprintf("RC4-%d", info.KeyBits)
+	}
+	return info, nil
+}
@@ -0,0 +1,700 @@
+package cmd
+
+import (
+	"fmt"
+	"hash/crc32"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// subsetFont is an embedded font program that was cut down to the glyphs
+// the document draws.
+type subsetFont struct {
+	Name         string `json:"name"`
+	Format       string `json:"format"`
+	GlyphsBefore int    `json:"glyphs_before"`
+	GlyphsAfter  int    `json:"glyphs_after"`
+	BytesBefore  int64  `json:"bytes_before"`
+	BytesAfter   int64  `json:"bytes_after"`
+}
+
+// skippedFont is an embedded font program that was left alone, and why.
+type skippedFont struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// fontStats is what subsetFonts did.
+type fontStats struct {
+	Subset     []subsetFont  `json:"subset"`
+	Skipped    []skippedFont `json:"skipped"`
+	Unchanged  int           `json:"unchanged"`
+	SavedBytes int64         `json:"saved_bytes"`
+}
+
+func printFontStats(st fontStats) {
+	switch {
+	case len(st.Subset) == 0 && len(st.Skipped) == 0 && st.Unchanged == 0:
+		printInfo("Fonts: no embedded fonts to subset")
+		return
+	case len(st.Subset) == 0:
+		printInfo("Fonts: nothing subset")
+	default:
+		printInfo(fmt.Sprintf("Fonts: subset %d font(s), saved %s", len(st.Subset), humanSize(st.SavedBytes)))
+	}
+	for _, f := range st.Subset {
+		printf("    %s\n", dimStyle.Render(fmt.Sprintf("%-32s %-8s %5d → %-5d glyphs  %s → %s",
+			f.Name, f.Format, f.GlyphsBefore, f.GlyphsAfter, humanSize(f.BytesBefore), humanSize(f.BytesAfter))))
+	}
+	if st.Unchanged > 0 {
+		printf("    %s\n", dimStyle.Render(fmt.Sprintf("%d font(s) kept: no glyph to drop", st.Unchanged)))
+	}
+	for _, f := range st.Skipped {
+		printf("    %s\n", dimStyle.Render(fmt.Sprintf("%-32s skipped: %s", f.Name, f.Reason)))
+	}
+}
+
+// fontUse is what the content drawn with a font dict shows with it.
+type fontUse struct {
+	dict       types.Dict
+	codes      map[int]bool // bytes for simple fonts, 2-byte codes for Type0
+	res        []types.Dict // Resources dicts that list the font
+	undecoded  bool         // listed for content that could not be decoded
+	selectedBy string       // how text was shown without Tf, if it was
+}
+
+// fontUsage collects the character codes every font dict is drawn with. It
+// returns a reason if text is shown without a font the walk can tell.
+func fontUsage(ctx *model.Context) (map[uintptr]*fontUse, *contentWalk, string) {
+	uses := map[uintptr]*fontUse{}
+	problem := ""
+	use := func(d types.Dict) *fontUse {
+		u := uses[dictID(d)]
+		if u == nil {
+			u = &fontUse{dict: d, codes: map[int]bool{}}
+			uses[dictID(d)] = u
+		}
+		return u
+	}
+	walk := walkDrawnContent(ctx, func(c drawnContent) {
+		fonts, _ := ctx.DereferenceDict(c.res["Font"])
+		for _, o := range fonts {
+			if d, _ := ctx.DereferenceDict(o); d != nil {
+				u := use(d)
+				u.res = append(u.res, c.res)
+				if c.content == nil {
+					u.undecoded = true
+				}
+			}
+		}
+		if c.content == nil {
+			return
+		}
+		gstates, _ := ctx.DereferenceDict(c.res["ExtGState"])
+		cur, stack := "", []string{}
+		scanContentOps(c.content, func(op string, args []string, start, end int) {
+			switch op {
+			case "q":
+				stack = append(stack, cur)
+			case "Q":
+				if n := len(stack); n > 0 {
+					cur, stack = stack[n-1], stack[:n-1]
+				}
+			case "Tf":
+				if len(args) > 0 && strings.HasPrefix(args[0], "/") {
+					cur = args[0][1:]
+				}
+			case "gs":
+				// A graphics state can select a font too. Such fonts are
+				// referenced from the graphics state, so they are never
+				// subset; text shown with them needs no codes.
+				if len(args) > 0 && strings.HasPrefix(args[0], "/") {
+					if gs, _ := ctx.DereferenceDict(gstates[args[0][1:]]); gs != nil && gs["Font"] != nil {
+						cur = "\x00"
+					}
+				}
+			case "Tj", "'", "\"", "TJ":
+				if cur == "" {
+					if problem == "" {
+						problem = fmt.Sprintf("text on page %d is shown with a font selected outside its content stream", c.page)
+					}
+					return
+				}
+				d, _ := ctx.DereferenceDict(fonts[cur])
+				if d == nil {
+					return
+				}
+				u := use(d)
+				type0 := d.Subtype() != nil && *d.Subtype() == "Type0"
+				for _, s := range textStrings(c.content[start:end]) {
+					if type0 {
+						for i := 0; i+1 < len(s); i += 2 {
+							u.codes[int(s[i])<<8|int(s[i+1])] = true
+						}
+						continue
+					}
+					for _, b := range s {
+						u.codes[int(b)] = true
+					}
+				}
+			}
+		})
+	})
+	return uses, walk, problem
+}
+
+// fontProgram is an embedded font program and the fonts that use it.
+type fontProgram struct {
+	nr      int    // object number of the FontFile2/FontFile3 stream
+	key     string // FontFile, FontFile2 or FontFile3
+	subtype string // of a FontFile3
+	fonts   []types.Dict
+	descs   []types.Dict // the font descriptors that point at it
+	cidFont map[uintptr]types.Dict
+	orphan  bool // a CIDFont without a Type0 parent uses it
+}
+
+// fontPrograms finds every embedded font program reachable from the
+// catalog, with all the font dicts that use each.
+func fontPrograms(ctx *model.Context) []*fontProgram {
+	if ctx.Root == nil {
+		return nil
+	}
+	var dicts []types.Dict
+	parent := map[uintptr]types.Dict{}
+	for _, nr := range reachableFrom(ctx, ctx.Root.ObjectNumber.Value(), func(int, types.Object) bool { return false }) {
+		o, _ := tableObject(ctx, nr)
+		d, ok := o.(types.Dict)
+		if !ok || d.Subtype() == nil || (d.Type() != nil && *d.Type() != "Font") {
+			continue
+		}
+		if *d.Subtype() == "Type0" {
+			desc, _ := ctx.DereferenceArray(d["DescendantFonts"])
+			for _, dd := range desc {
+				if cid, _ := ctx.DereferenceDict(dd); cid != nil {
+					parent[dictID(cid)] = d
+				}
+			}
+		}
+		dicts = append(dicts, d)
+	}
+
+	byNr := map[int]*fontProgram{}
+	var out []*fontProgram
+	for _, d := range dicts {
+		fd, _ := ctx.DereferenceDict(d["FontDescriptor"])
+		if fd == nil {
+			continue
+		}
+		for _, key := range []string{"FontFile", "FontFile2", "FontFile3"} {
+			ir, ok := fd[key].(types.IndirectRef)
+			if !ok {
+				continue
+			}
+			nr := ir.ObjectNumber.Value()
+			p := byNr[nr]
+			if p == nil {
+				p = &fontProgram{nr: nr, key: key, cidFont: map[uintptr]types.Dict{}}
+				if sd, _, err := ctx.DereferenceStreamDict(ir); err == nil && sd != nil && sd.Subtype() != nil {
+					p.subtype = *sd.Subtype()
+				}
+				byNr[nr] = p
+				out = append(out, p)
+			}
+			p.descs = append(p.descs, fd)
+			top := d
+			if st := *d.Subtype(); st == "CIDFontType0" || st == "CIDFontType2" {
+				if top = parent[dictID(d)]; top == nil {
+					p.orphan = true
+					continue
+				}
+				p.cidFont[dictID(top)] = d
+			}
+			p.fonts = append(p.fonts, top)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].nr < out[j].nr })
+	return out
+}
+
+// fontEncoding returns the base encoding name and the Differences of a
+// simple font's Encoding entry.
+func fontEncoding(ctx *model.Context, font types.Dict) (string, map[int]string) {
+	diffs := map[int]string{}
+	o, _ := ctx.Dereference(font["Encoding"])
+	switch enc := o.(type) {
+	case types.Name:
+		return string(enc), diffs
+	case types.Dict:
+		base := ""
+		if n, ok := enc["BaseEncoding"].(types.Name); ok {
+			base = string(n)
+		}
+		arr, _ := ctx.DereferenceArray(enc["Differences"])
+		code := 0
+		for _, e := range arr {
+			switch v := e.(type) {
+			case types.Integer:
+				code = v.Value()
+			case types.Name:
+				diffs[code] = string(v)
+				code++
+			}
+		}
+		return base, diffs
+	}
+	return "", diffs
+}
+
+// uniName returns the code point of a uniXXXX or uXXXX[XX] glyph name.
+func uniName(name string) (rune, bool) {
+	var hex string
+	switch {
+	case strings.HasPrefix(name, "uni") && len(name) == 7:
+		hex = name[3:]
+	case strings.HasPrefix(name, "u") && len(name) >= 5 && len(name) <= 7:
+		hex = name[1:]
+	default:
+		return 0, false
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	return rune(v), err == nil
+}
+
+// trueTypeGlyphs returns the glyphs a simple TrueType font draws for codes.
+// Viewers differ in how they look codes up, so it keeps what any of the
+// usual ways finds.
+func trueTypeGlyphs(ctx *model.Context, font types.Dict, data []byte, f *sfntFont, codes map[int]bool) (map[int]bool, error) {
+	gids := map[int]bool{}
+	_, diffs := fontEncoding(ctx, font)
+	t30, t31, t10 := f.cmapSubtable(3, 0), f.cmapSubtable(3, 1), f.cmapSubtable(1, 0)
+	var names map[string]int
+	if len(diffs) > 0 {
+		names = map[string]int{}
+		if sf, err := sfnt.Parse(data); err == nil {
+			var buf sfnt.Buffer
+			for gid := 0; gid < sf.NumGlyphs(); gid++ {
+				if n, err := sf.GlyphName(&buf, sfnt.GlyphIndex(gid)); err == nil && n != "" {
+					if _, dup := names[n]; !dup {
+						names[n] = gid
+					}
+				}
+			}
+		}
+	}
+	add := func(sub []byte, c int) {
+		if sub != nil {
+			if g := cmapLookup(sub, uint32(c)); g != 0 {
+				gids[g] = true
+			}
+		}
+	}
+	for c := range codes {
+		if name, ok := diffs[c]; ok {
+			if gid, ok := names[name]; ok {
+				gids[gid] = true
+			} else if r, ok := uniName(name); ok && t31 != nil {
+				add(t31, int(r))
+			} else if name != ".notdef" {
+				return nil, fmt.Errorf("glyph name %q is not in the font", name)
+			}
+		}
+		if f.tables["cmap"] == nil {
+			gids[c] = true
+		}
+		for _, base := range []int{0, 0xF000, 0xF100, 0xF200} {
+			add(t30, base+c)
+		}
+		add(t10, c)
+		add(t31, c)
+		add(t31, int(charmap.Windows1252.DecodeByte(byte(c))))
+		add(t31, int(charmap.Macintosh.DecodeByte(byte(c))))
+	}
+	return gids, nil
+}
+
+// winAnsiHighNames are the glyph names WinAnsiEncoding gives codes 128 to
+// 255; codes it leaves undefined are empty.
+var winAnsiHighNames = [128]string{
+	"Euro", "", "quotesinglbase", "florin", "quotedblbase", "ellipsis", "dagger", "daggerdbl",
+	"circumflex", "perthousand", "Scaron", "guilsinglleft", "OE", "", "Zcaron", "",
+	"", "quoteleft", "quoteright", "quotedblleft", "quotedblright", "bullet", "endash", "emdash",
+	"tilde", "trademark", "scaron", "guilsinglright", "oe", "", "zcaron", "Ydieresis",
+	"space", "exclamdown", "cent", "sterling", "currency", "yen", "brokenbar", "section",
+	"dieresis", "copyright", "ordfeminine", "guillemotleft", "logicalnot", "hyphen", "registered", "macron",
+	"degree", "plusminus", "twosuperior", "threesuperior", "acute", "mu", "paragraph", "periodcentered",
+	"cedilla", "onesuperior", "ordmasculine", "guillemotright", "onequarter", "onehalf", "threequarters", "questiondown",
+	"Agrave", "Aacute", "Acircumflex", "Atilde", "Adieresis", "Aring", "AE", "Ccedilla",
+	"Egrave", "Eacute", "Ecircumflex", "Edieresis", "Igrave", "Iacute", "Icircumflex", "Idieresis",
+	"Eth", "Ntilde", "Ograve", "Oacute", "Ocircumflex", "Otilde", "Odieresis", "multiply",
+	"Oslash", "Ugrave", "Uacute", "Ucircumflex", "Udieresis", "Yacute", "Thorn", "germandbls",
+	"agrave", "aacute", "acircumflex", "atilde", "adieresis", "aring", "ae", "ccedilla",
+	"egrave", "eacute", "ecircumflex", "edieresis", "igrave", "iacute", "icircumflex", "idieresis",
+	"eth", "ntilde", "ograve", "oacute", "ocircumflex", "otilde", "odieresis", "divide",
+	"oslash", "ugrave", "uacute", "ucircumflex", "udieresis", "yacute", "thorn", "ydieresis",
+}
+
+// baseEncodingSID returns the CFF string id of the glyph a base encoding
+// names for code.
+func baseEncodingSID(base string, code int) (int, bool) {
+	switch base {
+	case "StandardEncoding":
+		sid, ok := standardEncodingSIDs()[code]
+		return sid, ok
+	case "WinAnsiEncoding", "MacRomanEncoding":
+		switch {
+		case code == '\'':
+			return 104, true // quotesingle
+		case code == '`':
+			return 124, true // grave
+		case code >= 32 && code <= 126:
+			return code - 31, true
+		}
+	}
+	return 0, false
+}
+
+// cffGlyphs returns the glyphs a simple font with a CFF program draws for
+// codes: through Differences, the base encoding, and the font's own
+// encoding.
+func cffGlyphs(ctx *model.Context, font types.Dict, f *cffFont, codes map[int]bool) (map[int]bool, error) {
+	if f.cid {
+		return nil, fmt.Errorf("CID-keyed program in a simple font")
+	}
+	builtin, err := f.builtinEncoding()
+	if err != nil {
+		return nil, err
+	}
+	sidGID := map[int]int{}
+	for gid, sid := range f.charset {
+		if _, ok := sidGID[sid]; !ok {
+			sidGID[sid] = gid
+		}
+	}
+	base, diffs := fontEncoding(ctx, font)
+	gids := map[int]bool{}
+	for c := range codes {
+		if g, ok := builtin[c]; ok {
+			gids[g] = true
+		}
+		if name, ok := diffs[c]; ok {
+			g := f.glyphByName(name)
+			if g < 0 && name != ".notdef" {
+				return nil, fmt.Errorf("glyph name %q is not in the font", name)
+			}
+			gids[max(g, 0)] = true
+			continue
+		}
+		if base == "" {
+			continue
+		}
+		if base == "WinAnsiEncoding" && c >= 128 && c < 256 {
+			if g := f.glyphByName(winAnsiHighNames[c-128]); g > 0 {
+				gids[g] = true
+			}
+			continue
+		}
+		sid, ok := baseEncodingSID(base, c)
+		if !ok {
+			return nil, fmt.Errorf("code %d of %s", c, base)
+		}
+		if g, ok := sidGID[sid]; ok {
+			gids[g] = true
+		}
+	}
+	return gids, nil
+}
+
+// cidGlyphs returns the glyphs and CIDs a Type0 font with an Identity
+// encoding draws for codes.
+func cidGlyphs(ctx *model.Context, font, cidFont types.Dict, cff *cffFont, codes map[int]bool) (map[int]bool, map[int]bool, error) {
+	enc, _ := font["Encoding"].(types.Name)
+	if enc != "Identity-H" && enc != "Identity-V" {
+		return nil, nil, fmt.Errorf("encoding %s", pdfString(font["Encoding"]))
+	}
+	gids, cids := map[int]bool{}, map[int]bool{0: true}
+	var cidToGID []byte
+	switch m, _ := ctx.Dereference(cidFont["CIDToGIDMap"]); v := m.(type) {
+	case types.StreamDict:
+		if err := v.Decode(); err != nil {
+			return nil, nil, fmt.Errorf("CIDToGIDMap: %v", err)
+		}
+		cidToGID = v.Content
+	case types.Name, nil:
+	default:
+		return nil, nil, fmt.Errorf("bad CIDToGIDMap")
+	}
+	var cidGID map[int]int
+	if cff != nil && cff.cid {
+		cidGID = map[int]int{}
+		for gid, cid := range cff.charset {
+			cidGID[cid] = gid
+		}
+	}
+	for cid := range codes {
+		cids[cid] = true
+		gid := cid
+		switch {
+		case cidToGID != nil:
+			if 2*cid+1 >= len(cidToGID) {
+				continue
+			}
+			gid = int(cidToGID[2*cid])<<8 | int(cidToGID[2*cid+1])
+		case cidGID != nil:
+			g, ok := cidGID[cid]
+			if !ok {
+				continue
+			}
+			gid = g
+		}
+		gids[gid] = true
+	}
+	return gids, cids, nil
+}
+
+var subsetTagRe = regexp.MustCompile(`^[A-Z]{6}\+`)
+
+// subsetTag derives the six-letter tag that marks a font as a subset from
+// the glyphs it keeps.
+func subsetTag(name string, gids map[int]bool) string {
+	keys := make([]int, 0, len(gids))
+	for g := range gids {
+		keys = append(keys, g)
+	}
+	sort.Ints(keys)
+	h := crc32.NewIEEE()
+	h.Write([]byte(name))
+	for _, g := range keys {
+		h.Write([]byte{byte(g >> 8), byte(g)})
+	}
+	v := h.Sum32()
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + byte(v%26)
+		v /= 26
+	}
+	return string(tag)
+}
+
+func retag(o types.Object, tag string) types.Object {
+	n, ok := o.(types.Name)
+	if !ok {
+		return o
+	}
+	return types.Name(tag + "+" + subsetTagRe.ReplaceAllString(string(n), ""))
+}
+
+// subsetFonts cuts every embedded TrueType and CFF font program down to
+// the glyphs the document draws with it. Programs that something other
+// than page content may draw with (form fields, graphics states, content
+// that cannot be read) are left alone.
+func subsetFonts(ctx *model.Context) fontStats {
+	var st fontStats
+	uses, walk, problem := fontUsage(ctx)
+	for _, p := range fontPrograms(ctx) {
+		name := "?"
+		if len(p.fonts) > 0 {
+			if n := p.fonts[0].NameEntry("BaseFont"); n != nil {
+				name = *n
+			}
+		}
+		name = subsetTagRe.ReplaceAllString(name, "")
+		skip := func(reason string) {
+			st.Skipped = append(st.Skipped, skippedFont{name, reason})
+		}
+		switch {
+		case p.key == "FontFile":
+			skip("Type 1 font programs are not subset")
+			continue
+		case p.key == "FontFile3" && p.subtype != "Type1C" && p.subtype != "CIDFontType0C":
+			skip(fmt.Sprintf("%s font programs are not subset", p.subtype))
+			continue
+		case problem != "":
+			skip(problem)
+			continue
+		case p.orphan:
+			skip("used by a CIDFont outside a Type0 font")
+			continue
+		}
+		if reason := p.exposure(ctx, uses, walk); reason != "" {
+			skip(reason)
+			continue
+		}
+		before, after, format, err := p.subset(ctx, uses, name)
+		if err != nil {
+			skip(err.Error())
+			continue
+		}
+		if format == "" {
+			st.Unchanged++
+			continue
+		}
+		st.Subset = append(st.Subset, subsetFont{name, format, before.glyphs, after.glyphs, before.size, after.size})
+		st.SavedBytes += before.size - after.size
+	}
+	return st
+}
+
+// exposure returns why the fonts using p may draw glyphs the walk did not
+// see, or "".
+func (p *fontProgram) exposure(ctx *model.Context, uses map[uintptr]*fontUse, walk *contentWalk) string {
+	for _, font := range p.fonts {
+		u := uses[dictID(font)]
+		if u == nil {
+			return "also used outside page content"
+		}
+		if u.undecoded {
+			return "used by content that could not be decoded"
+		}
+		for _, res := range u.res {
+			if walk.exposed(res, "Font") {
+				return "also used outside page content"
+			}
+			fonts, _ := ctx.DereferenceDict(res["Font"])
+			for _, o := range fonts {
+				if ir, ok := o.(types.IndirectRef); ok && walk.foreign[ir.ObjectNumber.Value()] {
+					if d, _ := ctx.DereferenceDict(ir); dictID(d) == dictID(font) {
+						return "also used outside page content"
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+type programSize struct {
+	glyphs int
+	size   int64
+}
+
+// subset rewrites the font program of p with the glyphs its fonts use, if
+// that drops glyphs and makes it smaller. The returned format is empty if
+// the program was kept.
+func (p *fontProgram) subset(ctx *model.Context, uses map[uintptr]*fontUse, name string) (programSize, programSize, string, error) {
+	var before, after programSize
+	e := ctx.Table[p.nr]
+	if e == nil || e.Object == nil {
+		return before, after, "", fmt.Errorf("font program missing")
+	}
+	sd, ok := e.Object.(types.StreamDict)
+	if !ok {
+		return before, after, "", fmt.Errorf("font program is not a stream")
+	}
+	if err := sd.Decode(); err != nil {
+		return before, after, "", fmt.Errorf("font program could not be decoded: %v", err)
+	}
+	data := sd.Content
+	before.size = int64(len(sd.Raw))
+
+	var (
+		tt  *sfntFont
+		cff *cffFont
+		err error
+	)
+	format := "TrueType"
+	if p.key == "FontFile3" {
+		format = "CFF"
+		cff, err = parseCFF(data)
+	} else {
+		tt, err = parseSFNT(data)
+	}
+	if err != nil {
+		return before, after, "", err
+	}
+
+	gids := map[int]bool{}
+	cids := map[int]bool{}
+	for _, font := range p.fonts {
+		codes := uses[dictID(font)].codes
+		var g map[int]bool
+		if cidFont := p.cidFont[dictID(font)]; cidFont != nil {
+			var c map[int]bool
+			g, c, err = cidGlyphs(ctx, font, cidFont, cff, codes)
+			for cid := range c {
+				cids[cid] = true
+			}
+		} else if tt != nil {
+			g, err = trueTypeGlyphs(ctx, font, data, tt, codes)
+		} else {
+			g, err = cffGlyphs(ctx, font, cff, codes)
+		}
+		if err != nil {
+			return before, after, "", err
+		}
+		for gid := range g {
+			gids[gid] = true
+		}
+	}
+
+	var out []byte
+	if tt != nil {
+		out, before.glyphs, after.glyphs, err = subsetTrueType(data, gids)
+	} else {
+		out, before.glyphs, after.glyphs, err = subsetCFF(data, gids)
+	}
+	if err != nil {
+		return before, after, "", err
+	}
+	if err := setContentStream(&sd, out); err != nil {
+		return before, after, "", err
+	}
+	after.size = int64(len(sd.Raw))
+	if after.glyphs == before.glyphs || after.size >= before.size {
+		return before, after, "", nil // nothing to gain
+	}
+	if tt != nil {
+		sd.Dict["Length1"] = types.Integer(len(out))
+	}
+	e.Object = sd
+
+	tag := subsetTag(name, gids)
+	for _, font := range p.fonts {
+		font["BaseFont"] = retag(font["BaseFont"], tag)
+		if cidFont := p.cidFont[dictID(font)]; cidFont != nil {
+			cidFont["BaseFont"] = retag(cidFont["BaseFont"], tag)
+		}
+	}
+	for _, fd := range p.descs {
+		fd["FontName"] = retag(fd["FontName"], tag)
+		delete(fd, "CharSet")
+		if _, ok := fd["CIDSet"]; ok {
+			setCIDSet(ctx, fd, cids)
+		}
+	}
+	return before, after, format, nil
+}
+
+// setCIDSet replaces a font descriptor's CIDSet with one listing cids.
+func setCIDSet(ctx *model.Context, fd types.Dict, cids map[int]bool) {
+	top := 0
+	for cid := range cids {
+		top = max(top, cid)
+	}
+	bits := make([]byte, top/8+1)
+	for cid := range cids {
+		bits[cid/8] |= 0x80 >> uint(cid%8)
+	}
+	sd, err := ctx.NewStreamDictForBuf(bits)
+	if err != nil {
+		delete(fd, "CIDSet")
+		return
+	}
+	if err := sd.Encode(); err != nil {
+		delete(fd, "CIDSet")
+		return
+	}
+	ir, err := ctx.IndRefForNewObject(*sd)
+	if err != nil {
+		delete(fd, "CIDSet")
+		return
+	}
+	fd["CIDSet"] = *ir
+}
@@ -0,0 +1,675 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	grayOutput    string
+	grayDryRun    bool
+	grayPages     string
+	grayMono      bool
+	grayThreshold int
+)
+
+var grayscaleCmd = &cobra.Command{
+	Use:     "grayscale <input.pdf>",
+	Aliases: []string{"gray"},
+	Short:   "Convert pages to grayscale or black and white",
+	Long: `Converts images and the colors of text and vector graphics to grayscale.
+Without -o, the file is converted in-place.
+
+--mono turns images into 1-bit black and white with CCITT Group 4
+compression, the smallest form for scanned text. Each image gets its own
+threshold unless --threshold sets one (1-255; darker pixels become black).
+
+-p limits the conversion to some pages, and accepts page kinds and
+predicates as for rotate, e.g. "-p image" for the scanned pages only.
+Images and forms that selected pages share with other pages are left in
+color, so the other pages do not change.
+
+Shadings (gradients), inline images, annotation appearances and images in
+unusual encodings keep their colors; they are reported.`,
+	Example: `  pdfed grayscale handout.pdf -o handout-gray.pdf
+  pdfed grayscale scan.pdf --mono
+  pdfed grayscale report.pdf -p 3-5 --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if grayThreshold < 0 || grayThreshold > 255 {
+			return fmt.Errorf("--threshold must be between 1 and 255 (0 picks one per image)")
+		}
+		cmd.SilenceUsage = true
+		return runGrayscale(args[0])
+	},
+}
+
+func init() {
+	grayscaleCmd.Flags().StringVarP(&grayPages, "pages", "p", "", "Pages to convert (e.g. 1-3,5, image, odd)")
+	grayscaleCmd.Flags().StringVarP(&grayOutput, "output", "o", "", "Output file (default: in-place)")
+	grayscaleCmd.Flags().BoolVarP(&grayDryRun, "dry-run", "n", false, "Preview without writing")
+	grayscaleCmd.Flags().BoolVar(&grayMono, "mono", false, "Make images 1-bit black and white (CCITT G4)")
+	grayscaleCmd.Flags().IntVar(&grayThreshold, "threshold", 0, "Gray level below which --mono pixels turn black (default: per image)")
+	rootCmd.AddCommand(grayscaleCmd)
+}
+
+// grayJPEGQuality is used for JPEG images converted to grayscale.
+const grayJPEGQuality = 85
+
+// grayStats counts what a grayscale run converted and what it left alone.
+type grayStats struct {
+	GrayImages   int `json:"images_gray"`
+	MonoImages   int `json:"images_mono"`
+	Palettes     int `json:"palettes_gray"`
+	Operators    int `json:"color_operators"`
+	Streams      int `json:"content_streams"`
+	Shadings     int `json:"shadings_kept"`
+	InlineImages int `json:"inline_images_kept"`
+	Unsupported  int `json:"images_kept"`
+	Shared       int `json:"shared_objects_kept"`
+}
+
+// colorModel names the color model of a color space: "gray", "rgb" or
+// "cmyk", or "" for spaces that are not converted (indexed, separations,
+// patterns, Lab).
+func colorModel(ctx *model.Context, cs types.Object) string {
+	cs, _ = ctx.Dereference(cs)
+	switch c := cs.(type) {
+	case types.Name:
+		switch c {
+		case "DeviceGray", "CalGray", "G":
+			return "gray"
+		case "DeviceRGB", "CalRGB", "RGB":
+			return "rgb"
+		case "DeviceCMYK", "CMYK":
+			return "cmyk"
+		}
+	case types.Array:
+		if len(c) == 0 {
+			return ""
+		}
+		name, _ := c[0].(types.Name)
+		if name != "ICCBased" {
+			return colorModel(ctx, name)
+		}
+		if len(c) > 1 {
+			if sd, _, err := ctx.DereferenceStreamDict(c[1]); err == nil && sd != nil {
+				if n := sd.IntEntry("N"); n != nil {
+					return map[int]string{1: "gray", 3: "rgb", 4: "cmyk"}[*n]
+				}
+			}
+		}
+	}
+	return ""
+}
+
+var modelComponents = map[string]int{"gray": 1, "rgb": 3, "cmyk": 4}
+
+// grayLevel converts color components in 0..1 to a gray level in 0..1.
+func grayLevel(c []float64) float64 {
+	switch len(c) {
+	case 3:
+		return 0.299*c[0] + 0.587*c[1] + 0.114*c[2]
+	case 4:
+		return 1 - math.Min(1, 0.3*c[0]+0.59*c[1]+0.11*c[2]+c[3])
+	}
+	return c[0]
+}
+
+// pageObjects calls fn for the content streams, images and forms page nr
+// draws with, along with the resources that apply to them.
+func pageObjects(ctx *model.Context, nr int, fn func(objNr int, kind string, res types.Dict)) error {
+	d, _, inh, err := ctx.PageDict(nr, false)
+	if err != nil {
+		return err
+	}
+	res, _ := ctx.DereferenceDict(d["Resources"])
+	if res == nil && inh != nil {
+		res = inh.Resources
+	}
+	var refs []types.Object
+	switch c := d["Contents"].(type) {
+	case types.IndirectRef:
+		if a, err := ctx.DereferenceArray(c); err == nil && a != nil {
+			refs = a
+		} else {
+			refs = []types.Object{c}
+		}
+	case types.Array:
+		refs = c
+	}
+	for _, o := range refs {
+		if ir, ok := o.(types.IndirectRef); ok {
+			fn(ir.ObjectNumber.Value(), "content", res)
+		}
+	}
+
+	seen := map[int]bool{}
+	var walk func(res types.Dict, depth int)
+	walk = func(res types.Dict, depth int) {
+		xobjs, _ := ctx.DereferenceDict(res["XObject"])
+		for _, o := range xobjs {
+			ir, ok := o.(types.IndirectRef)
+			if !ok || seen[ir.ObjectNumber.Value()] {
+				continue
+			}
+			seen[ir.ObjectNumber.Value()] = true
+			sd, _, err := ctx.DereferenceStreamDict(ir)
+			if err != nil || sd == nil || sd.Subtype() == nil {
+				continue
+			}
+			switch *sd.Subtype() {
+			case "Image":
+				fn(ir.ObjectNumber.Value(), "image", res)
+			case "Form":
+				formRes, _ := ctx.DereferenceDict(sd.Dict["Resources"])
+				if formRes == nil {
+					formRes = res
+				}
+				fn(ir.ObjectNumber.Value(), "form", formRes)
+				if depth < 8 {
+					walk(formRes, depth+1)
+				}
+			}
+		}
+	}
+	walk(res, 0)
+	return nil
+}
+
+// colorState is the fill and stroke color model of a content stream, for
+// the operators that set color components in the current color space.
+type colorState struct {
+	fill, stroke string
+}
+
+// grayContent rewrites the color operators of a content stream to gray.
+// state carries the color spaces from one content stream of a page to the
+// next.
+func grayContent(ctx *model.Context, content []byte, res types.Dict, state *colorState, st *grayStats) ([]byte, int) {
+	csRes, _ := ctx.DereferenceDict(res["ColorSpace"])
+	modelOf := func(name string) string {
+		if m := colorModel(ctx, types.Name(name)); m != "" {
+			return m
+		}
+		if csRes != nil {
+			return colorModel(ctx, csRes[name])
+		}
+		return ""
+	}
+	numbers := func(args []string) ([]float64, bool) {
+		vals := make([]float64, len(args))
+		for i, a := range args {
+			v, err := strconv.ParseFloat(a, 64)
+			if err != nil {
+				return nil, false
+			}
+			vals[i] = v
+		}
+		return vals, true
+	}
+	gray := func(vals []float64) string {
+		return strconv.FormatFloat(math.Round(grayLevel(vals)*1000)/1000, 'f', -1, 64)
+	}
+
+	var out bytes.Buffer
+	last, changed := 0, 0
+	replace := func(start, end int, s string) {
+		out.Write(content[last:start])
+		out.WriteString(s)
+		last = end
+		changed++
+	}
+	var stack []colorState
+	scanContentOps(content, func(op string, args []string, start, end int) {
+		switch op {
+		case "q":
+			stack = append(stack, *state)
+		case "Q":
+			if len(stack) > 0 {
+				*state, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			}
+		case "rg", "RG", "k", "K":
+			want := 3
+			if op == "k" || op == "K" {
+				want = 4
+			}
+			vals, ok := numbers(args)
+			if !ok || len(vals) != want {
+				return
+			}
+			g := "g"
+			if op == "RG" || op == "K" {
+				g = "G"
+			}
+			replace(start, end, gray(vals)+" "+g)
+		case "cs", "CS":
+			if len(args) != 1 || !strings.HasPrefix(args[0], "/") {
+				return
+			}
+			m := modelOf(args[0][1:])
+			cur := &state.fill
+			if op == "CS" {
+				cur = &state.stroke
+			}
+			*cur = m
+			if m == "rgb" || m == "cmyk" {
+				replace(start, end, "/DeviceGray "+op)
+			}
+		case "sc", "scn", "SC", "SCN":
+			m := state.fill
+			if op == "SC" || op == "SCN" {
+				m = state.stroke
+			}
+			if m != "rgb" && m != "cmyk" {
+				return
+			}
+			vals, ok := numbers(args)
+			if !ok || len(vals) != modelComponents[m] {
+				return
+			}
+			replace(start, end, gray(vals)+" "+op)
+		case "sh":
+			st.Shadings++
+		case "BI":
+			st.InlineImages++
+		}
+	})
+	if changed == 0 {
+		return content, 0
+	}
+	out.Write(content[last:])
+	return out.Bytes(), changed
+}
+
+// setContentStream replaces the content of a stream and compresses it. sd
+// gets its own copy of the dict first, so the stream it was copied from is
+// unchanged until the caller stores sd.
+func setContentStream(sd *types.StreamDict, content []byte) error {
+	sd.Dict = sd.Dict.Clone().(types.Dict)
+	sd.Content = content
+	sd.FilterPipeline = []types.PDFFilter{{Name: "FlateDecode"}}
+	sd.Dict["Filter"] = types.Name("FlateDecode")
+	delete(sd.Dict, "DecodeParms")
+	return sd.Encode()
+}
+
+// grayImage converts one image XObject. It returns "gray", "mono" or
+// "palette" for what it did, "" for images that need nothing (masks and
+// images that are already gray), and "unsupported" otherwise.
+func grayImage(ctx *model.Context, sd *types.StreamDict, mono bool, threshold int) (string, error) {
+	if im := sd.BooleanEntry("ImageMask"); im != nil && *im {
+		return "", nil
+	}
+	cs, _ := ctx.Dereference(sd.Dict["ColorSpace"])
+	if a, ok := cs.(types.Array); ok && len(a) == 4 && a[0] == types.Name("Indexed") {
+		return grayPalette(ctx, sd, a)
+	}
+	w, h := sd.IntEntry("Width"), sd.IntEntry("Height")
+	bpc := sd.IntEntry("BitsPerComponent")
+	if w == nil || h == nil || bpc == nil || *w <= 0 || *h <= 0 {
+		return "unsupported", nil
+	}
+	m := colorModel(ctx, cs)
+	switch {
+	case m == "gray" && (!mono || *bpc != 8):
+		return "", nil
+	case m == "" || *bpc != 8 || sd.Dict["Decode"] != nil:
+		return "unsupported", nil
+	}
+	n := modelComponents[m]
+
+	var pix []byte
+	isJPEG := false
+	for _, f := range sd.FilterPipeline {
+		switch f.Name {
+		case "DCTDecode":
+			isJPEG = true
+		case "FlateDecode", "LZWDecode", "RunLengthDecode", "ASCII85Decode", "ASCIIHexDecode":
+		default:
+			return "unsupported", nil
+		}
+	}
+	if isJPEG {
+		if len(sd.FilterPipeline) != 1 || n == 4 {
+			return "unsupported", nil
+		}
+		img, err := jpeg.Decode(bytes.NewReader(sd.Raw))
+		if err != nil {
+			return "unsupported", nil
+		}
+		var comps int
+		if pix, comps = imagePixels(img); comps != n {
+			return "unsupported", nil
+		}
+	} else {
+		if err := sd.Decode(); err != nil {
+			return "unsupported", nil
+		}
+		pix = sd.Content
+	}
+	if len(pix) < *w**h*n {
+		return "unsupported", nil
+	}
+
+	g := make([]byte, *w**h)
+	vals := make([]float64, n)
+	for i := range g {
+		for c := 0; c < n; c++ {
+			vals[c] = float64(pix[i*n+c]) / 255
+		}
+		g[i] = byte(math.Round(grayLevel(vals) * 255))
+	}
+
+	if mono {
+		t := threshold
+		if t == 0 {
+			t = otsuThreshold(g)
+		}
+		rows := make([][]bool, *h)
+		for y := range rows {
+			rows[y] = make([]bool, *w)
+			for x := range rows[y] {
+				rows[y][x] = int(g[y**w+x]) < t
+			}
+		}
+		parms := types.Dict{"K": types.Integer(-1), "Columns": types.Integer(*w), "Rows": types.Integer(*h)}
+		setImageStream(sd, encodeCCITTG4(rows, *w), nil, types.PDFFilter{Name: "CCITTFaxDecode", DecodeParms: parms})
+		sd.Dict["ColorSpace"] = types.Name("DeviceGray")
+		sd.Dict["BitsPerComponent"] = types.Integer(1)
+		return "mono", nil
+	}
+
+	if isJPEG {
+		var buf bytes.Buffer
+		img := &image.Gray{Pix: g, Stride: *w, Rect: image.Rect(0, 0, *w, *h)}
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: grayJPEGQuality}); err != nil {
+			return "", err
+		}
+		setImageStream(sd, buf.Bytes(), nil, types.PDFFilter{Name: "DCTDecode"})
+	} else {
+		var buf bytes.Buffer
+		zw, _ := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+		zw.Write(pngPredict(g, *w, 1))
+		zw.Close()
+		parms := types.Dict{"Predictor": types.Integer(15), "Colors": types.Integer(1), "BitsPerComponent": types.Integer(8), "Columns": types.Integer(*w)}
+		setImageStream(sd, buf.Bytes(), g, types.PDFFilter{Name: "FlateDecode", DecodeParms: parms})
+	}
+	sd.Dict["ColorSpace"] = types.Name("DeviceGray")
+	return "gray", nil
+}
+
+// grayPalette converts the color table of an indexed image, which keeps
+// its pixel data.
+func grayPalette(ctx *model.Context, sd *types.StreamDict, cs types.Array) (string, error) {
+	m := colorModel(ctx, cs[1])
+	hival, ok := cs[2].(types.Integer)
+	if m == "gray" {
+		return "", nil
+	}
+	if m == "" || !ok {
+		return "unsupported", nil
+	}
+	n := modelComponents[m]
+	var table []byte
+	lookup, _ := ctx.Dereference(cs[3])
+	switch l := lookup.(type) {
+	case types.StringLiteral:
+		table, _ = types.Unescape(string(l))
+	case types.HexLiteral:
+		table, _ = l.Bytes()
+	case types.StreamDict:
+		if err := l.Decode(); err == nil {
+			table = l.Content
+		}
+	}
+	if len(table) < (int(hival)+1)*n {
+		return "unsupported", nil
+	}
+	gray := make([]byte, int(hival)+1)
+	vals := make([]float64, n)
+	for i := range gray {
+		for c := 0; c < n; c++ {
+			vals[c] = float64(table[i*n+c]) / 255
+		}
+		gray[i] = byte(math.Round(grayLevel(vals) * 255))
+	}
+	sd.Dict["ColorSpace"] = types.Array{types.Name("Indexed"), types.Name("DeviceGray"), hival, types.NewHexLiteral(gray)}
+	return "palette", nil
+}
+
+// otsuThreshold picks the gray level that best separates an image's dark
+// and light pixels (Otsu's method).
+func otsuThreshold(g []byte) int {
+	var hist [256]int
+	for _, v := range g {
+		hist[v]++
+	}
+	total, sum := len(g), 0
+	for i, c := range hist {
+		sum += i * c
+	}
+	best, bestVar := 128, -1.0
+	var wB, sumB int
+	for t := 0; t < 256; t++ {
+		wB += hist[t]
+		if wB == 0 {
+			continue
+		}
+		wF := total - wB
+		if wF == 0 {
+			break
+		}
+		sumB += t * hist[t]
+		mB, mF := float64(sumB)/float64(wB), float64(sum-sumB)/float64(wF)
+		if v := float64(wB) * float64(wF) * (mB - mF) * (mB - mF); v > bestVar {
+			best, bestVar = t+1, v
+		}
+	}
+	return best
+}
+
+// convertToGray converts the selected pages of ctx (nil for all).
+func convertToGray(ctx *model.Context, selected types.IntSet, mono bool, threshold int) (*grayStats, error) {
+	st := &grayStats{}
+	// Objects also drawn by unselected pages stay as they are.
+	outside := map[int]bool{}
+	if selected != nil {
+		for nr := 1; nr <= ctx.PageCount; nr++ {
+			if selected[nr] {
+				continue
+			}
+			if err := pageObjects(ctx, nr, func(objNr int, _ string, _ types.Dict) { outside[objNr] = true }); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	done := map[int]bool{}
+	for nr := 1; nr <= ctx.PageCount; nr++ {
+		if selected != nil && !selected[nr] {
+			continue
+		}
+		state := &colorState{}
+		var ferr error
+		err := pageObjects(ctx, nr, func(objNr int, kind string, res types.Dict) {
+			if done[objNr] || ferr != nil {
+				return
+			}
+			done[objNr] = true
+			if outside[objNr] {
+				st.Shared++
+				return
+			}
+			e := ctx.Table[objNr]
+			if e == nil || e.Object == nil {
+				return
+			}
+			sd, ok := e.Object.(types.StreamDict)
+			if !ok {
+				return
+			}
+			switch kind {
+			case "image":
+				what, err := grayImage(ctx, &sd, mono, threshold)
+				if err != nil {
+					ferr = fmt.Errorf("page %d: %w", nr, err)
+					return
+				}
+				switch what {
+				case "gray":
+					st.GrayImages++
+				case "mono":
+					st.MonoImages++
+				case "palette":
+					st.Palettes++
+				case "unsupported":
+					st.Unsupported++
+				}
+			default:
+				if err := sd.Decode(); err != nil {
+					return
+				}
+				s := state
+				if kind == "form" {
+					s = &colorState{}
+				}
+				content, n := grayContent(ctx, sd.Content, res, s, st)
+				if n == 0 {
+					return
+				}
+				if err := setContentStream(&sd, content); err != nil {
+					ferr = fmt.Errorf("page %d: %w", nr, err)
+					return
+				}
+				st.Operators += n
+				st.Streams++
+			}
+			e.Object = sd
+		})
+		if err == nil {
+			err = ferr
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+func printGrayStats(st *grayStats) {
+	if quiet {
+		return
+	}
+	var done, kept []string
+	add := func(list *[]string, n int, what string) {
+		if n > 0 {
+			*list = append(*list, fmt.Sprintf("%d %s", n, what))
+		}
+	}
+	add(&done, st.GrayImages, "image(s) to grayscale")
+	add(&done, st.MonoImages, "image(s) to black and white")
+	add(&done, st.Palettes, "indexed image palette(s)")
+	add(&done, st.Operators, fmt.Sprintf("color setting(s) in %d content stream(s)", st.Streams))
+	add(&kept, st.Shadings, "shading(s)")
+	add(&kept, st.InlineImages, "inline image(s)")
+	add(&kept, st.Unsupported, "image(s) in an unsupported color space or encoding")
+	add(&kept, st.Shared, "image(s) or form(s) shared with unselected pages")
+	if len(done) > 0 {
+		printInfo("Converted " + strings.Join(done, ", "))
+	}
+	if len(kept) > 0 {
+		printWarning("Left in color: " + strings.Join(kept, ", "))
+	}
+}
+
+func runGrayscale(inFile string) error {
+	ctx, err := readValidatedContext(inFile)
+	if err != nil {
+		return err
+	}
+	selected, err := selectedPages(ctx, inFile, grayPages)
+	if err != nil {
+		return err
+	}
+	pages := selectedPageList(selected, ctx.PageCount)
+	origSize := ctx.Read.FileSize
+
+	target := "grayscale"
+	if grayMono {
+		target = "black and white"
+	}
+	printInfo(fmt.Sprintf("Converting %d page(s) of %s to %s…", len(pages), inFile, target))
+
+	out := grayOutput
+	if out == "" {
+		out = inFile
+	}
+	fields := map[string]interface{}{
+		"input":    inFile,
+		"output":   out,
+		"in_place": out == inFile,
+		"dry_run":  grayDryRun,
+		"mono":     grayMono,
+		"selected": pages,
+	}
+	if grayPages != "" {
+		fields["pages"] = grayPages
+	}
+	if grayMono && grayThreshold > 0 {
+		fields["threshold"] = grayThreshold
+	}
+	if len(pages) == 0 {
+		printSuccess("No pages selected — nothing to do")
+		if jsonOut {
+			return jsonResultOK("grayscale", fields)
+		}
+		return nil
+	}
+
+	st, err := convertToGray(ctx, selected, grayMono, grayThreshold)
+	if err != nil {
+		return err
+	}
+	fields["converted"] = st
+	printGrayStats(st)
+
+	data, err := writeContextBytes(ctx)
+	if err != nil {
+		return err
+	}
+	size := int64(len(data))
+	fields["size_bytes"] = size
+	fields["size_human"] = humanSize(size)
+	sizes := fmt.Sprintf("%s → %s", humanSize(origSize), humanSize(size))
+
+	if grayDryRun {
+		printInfo(fmt.Sprintf("[dry-run] would write %s (no output written)", sizes))
+		if jsonOut {
+			return jsonResultOK("grayscale", fields)
+		}
+		return nil
+	}
+	if err := writeFileAtomic(out, data); err != nil {
+		return err
+	}
+	if out == inFile {
+		printSuccess(fmt.Sprintf("Converted in-place: %s (%s)", out, sizes))
+	} else {
+		printSuccess(fmt.Sprintf("Created: %s (%s)", out, sizes))
+	}
+	if jsonOut {
+		return jsonResultOK("grayscale", fields)
+	}
+	return nil
+}
@@ -0,0 +1,658 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// imageSettings controls how optimize treats images. Zero values leave that
+// aspect alone.
+type imageSettings struct {
+	DPI      int  `json:"dpi,omitempty"`          // downsample images drawn above this resolution
+	Quality  int  `json:"jpeg_quality,omitempty"` // recompress JPEGs at this quality (1-100)
+	Lossless bool `json:"lossless"`               // re-encode lossless images with Flate and PNG predictors
+}
+
+func (s imageSettings) active() bool {
+	return s.DPI > 0 || s.Quality > 0 || s.Lossless
+}
+
+func (s imageSettings) String() string {
+	var parts []string
+	if s.DPI > 0 {
+		parts = append(parts, fmt.Sprintf("%d DPI", s.DPI))
+	}
+	if s.Quality > 0 {
+		parts = append(parts, fmt.Sprintf("JPEG quality %d", s.Quality))
+	}
+	if s.Lossless {
+		parts = append(parts, "lossless Flate")
+	}
+	if len(parts) == 0 {
+		return "images unchanged"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// imagePresets are the named --preset settings.
+var imagePresets = map[string]imageSettings{
+	"screen": {DPI: 72, Quality: 50, Lossless: true},
+	"ebook":  {DPI: 150, Quality: 70, Lossless: true},
+	"print":  {DPI: 300, Quality: 85, Lossless: true},
+}
+
+var imagePresetNames = []string{"screen", "ebook", "print"}
+
+const (
+	// downsampleMargin keeps images that are only slightly above the target
+	// resolution; resampling them costs quality for little gain.
+	downsampleMargin = 1.25
+	// defaultResampleQuality is used for JPEGs that are downsampled without
+	// an explicit quality.
+	defaultResampleQuality = 85
+)
+
+// imageStats counts what the image pass did, with the bytes it saved.
+type imageStats struct {
+	Downsampled  int   `json:"downsampled"`
+	Recompressed int   `json:"jpeg_recompressed"`
+	Reencoded    int   `json:"lossless_reencoded"`
+	Unchanged    int   `json:"unchanged"`
+	Skipped      int   `json:"skipped"`
+	SavedBytes   int64 `json:"saved_bytes"`
+}
+
+// imageResolutions returns, for every image XObject drawn by a page, the
+// lowest resolution in DPI at which it is drawn. Images that are never drawn
+// from page content (patterns, annotations, soft masks) are absent.
+func imageResolutions(ctx *model.Context) map[int]float64 {
+	dpis := map[int]float64{}
+	for nr := 1; nr <= ctx.PageCount; nr++ {
+		d, _, inh, err := ctx.PageDict(nr, false)
+		if err != nil || d == nil {
+			continue
+		}
+		content, err := ctx.PageContent(d, nr)
+		if err != nil {
+			continue
+		}
+		res, _ := ctx.DereferenceDict(d["Resources"])
+		if res == nil && inh != nil {
+			res = inh.Resources
+		}
+		scanImagePlacements(ctx, content, res, identityMatrix, 0, dpis)
+	}
+	return dpis
+}
+
+type matrix [6]float64
+
+var identityMatrix = matrix{1, 0, 0, 1, 0, 0}
+
+// times returns m × n, i.e. m applied first.
+func (m matrix) times(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2], m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2], m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4], m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func scanImagePlacements(ctx *model.Context, content []byte, res types.Dict, ctm matrix, depth int, dpis map[int]float64) {
+	if depth > 8 {
+		return
+	}
+	xobjs, _ := ctx.DereferenceDict(res["XObject"])
+	var stack []matrix
+	scanContentOps(content, func(op string, args []string, _, _ int) {
+		switch op {
+		case "q":
+			stack = append(stack, ctm)
+		case "Q":
+			if len(stack) > 0 {
+				ctm, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			}
+		case "cm":
+			if m, ok := parseMatrix(args); ok {
+				ctm = m.times(ctm)
+			}
+		case "Do":
+			if len(args) != 1 || xobjs == nil || len(args[0]) < 2 {
+				return
+			}
+			ir, ok := xobjs[args[0][1:]].(types.IndirectRef)
+			if !ok {
+				return
+			}
+			sd, _, err := ctx.DereferenceStreamDict(ir)
+			if err != nil || sd == nil || sd.Subtype() == nil {
+				return
+			}
+			switch *sd.Subtype() {
+			case "Image":
+				w, h := sd.IntEntry("Width"), sd.IntEntry("Height")
+				if w == nil || h == nil {
+					return
+				}
+				wpt, hpt := math.Hypot(ctm[0], ctm[1]), math.Hypot(ctm[2], ctm[3])
+				if wpt < 0.01 || hpt < 0.01 {
+					return
+				}
+				dpi := math.Min(float64(*w)*72/wpt, float64(*h)*72/hpt)
+				nr := ir.ObjectNumber.Value()
+				if old, ok := dpis[nr]; !ok || dpi < old {
+					dpis[nr] = dpi
+				}
+			case "Form":
+				if err := sd.Decode(); err != nil {
+					return
+				}
+				m := identityMatrix
+				if a := sd.ArrayEntry("Matrix"); len(a) == 6 {
+					var s []string
+					for _, v := range a {
+						s = append(s, v.PDFString())
+					}
+					if fm, ok := parseMatrix(s); ok {
+						m = fm
+					}
+				}
+				formRes, _ := ctx.DereferenceDict(sd.Dict["Resources"])
+				if formRes == nil {
+					formRes = res
+				}
+				scanImagePlacements(ctx, sd.Content, formRes, m.times(ctm), depth+1, dpis)
+			}
+		}
+	})
+}
+
+func parseMatrix(args []string) (matrix, bool) {
+	if len(args) != 6 {
+		return matrix{}, false
+	}
+	var m matrix
+	for i, a := range args {
+		v, err := strconv.ParseFloat(a, 64)
+		if err != nil {
+			return matrix{}, false
+		}
+		m[i] = v
+	}
+	return m, true
+}
+
+// scanContentOps splits a content stream into operators and their operands.
+// Strings, arrays and dictionaries are passed over as single operands, and
+// inline image data is skipped. start and end delimit the operands and
+// operator in b, for callers that rewrite the stream.
+func scanContentOps(b []byte, fn func(op string, args []string, start, end int)) {
+	var args []string
+	start := -1
+	isDelim := func(c byte) bool { return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0 }
+	isSpace := func(c byte) bool { return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0 }
+	for i := 0; i < len(b); {
+		c := b[i]
+		if start < 0 && !isSpace(c) && c != '%' {
+			start = i
+		}
+		switch {
+		case isSpace(c):
+			i++
+		case c == '%':
+			for i < len(b) && b[i] != '\n' && b[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			depth := 0
+			for ; i < len(b); i++ {
+				if b[i] == '\\' {
+					i++
+				} else if b[i] == '(' {
+					depth++
+				} else if b[i] == ')' {
+					if depth--; depth == 0 {
+						i++
+						break
+					}
+				}
+			}
+			args = append(args, "()")
+		case c == '<' && i+1 < len(b) && b[i+1] == '<', c == '[':
+			// Skip to the matching close, minding nested strings.
+			open, close := byte('['), byte(']')
+			if c == '<' {
+				open, close = '<', '>'
+			}
+			depth := 0
+			for ; i < len(b); i++ {
+				switch b[i] {
+				case '(':
+					for n := 0; i < len(b); i++ {
+						if b[i] == '\\' {
+							i++
+						} else if b[i] == '(' {
+							n++
+						} else if b[i] == ')' {
+							if n--; n == 0 {
+								break
+							}
+						}
+					}
+				case open:
+					depth++
+				case close:
+					depth--
+				}
+				if depth == 0 {
+					i++
+					break
+				}
+			}
+			args = append(args, string(open))
+		case c == '<':
+			for i < len(b) && b[i] != '>' {
+				i++
+			}
+			i++
+			args = append(args, "<>")
+		case c == '/':
+			j := i + 1
+			for j < len(b) && !isSpace(b[j]) && !isDelim(b[j]) {
+				j++
+			}
+			args = append(args, string(b[i:j]))
+			i = j
+		case isDelim(c):
+			i++
+		default:
+			j := i
+			for j < len(b) && !isSpace(b[j]) && !isDelim(b[j]) {
+				j++
+			}
+			tok := string(b[i:j])
+			i = j
+			if tok[0] == '-' || tok[0] == '+' || tok[0] == '.' || (tok[0] >= '0' && tok[0] <= '9') {
+				args = append(args, tok)
+				continue
+			}
+			if tok == "ID" {
+				// Inline image data runs up to whitespace + EI + whitespace.
+				end := bytes.Index(b[i:], []byte("EI"))
+				for end >= 0 {
+					p := i + end
+					if p > 0 && isSpace(b[p-1]) && (p+2 == len(b) || isSpace(b[p+2])) {
+						break
+					}
+					next := bytes.Index(b[p+2:], []byte("EI"))
+					if next < 0 {
+						end = -1
+						break
+					}
+					end += 2 + next
+				}
+				if end < 0 {
+					return
+				}
+				i += end + 2
+				args, start = args[:0], -1
+				continue
+			}
+			fn(tok, args, start, i)
+			args, start = args[:0], -1
+		}
+	}
+}
+
+// optimizeImages recompresses the image XObjects in ctx according to s.
+// dpis is the output of imageResolutions.
+func optimizeImages(ctx *model.Context, s imageSettings, dpis map[int]float64) imageStats {
+	var st imageStats
+	nrs := make([]int, 0, len(ctx.Table))
+	for nr := range ctx.Table {
+		nrs = append(nrs, nr)
+	}
+	sort.Ints(nrs)
+	for _, nr := range nrs {
+		e := ctx.Table[nr]
+		if e == nil || e.Free || e.Object == nil {
+			continue
+		}
+		sd, ok := e.Object.(types.StreamDict)
+		if !ok || sd.Subtype() == nil || *sd.Subtype() != "Image" {
+			continue
+		}
+		before := int64(len(sd.Raw))
+		action, err := optimizeImage(ctx, &sd, s, dpis[nr])
+		switch {
+		case err != nil || action == "skipped":
+			st.Skipped++
+			continue
+		case action == "":
+			st.Unchanged++
+			continue
+		case action == "downsampled":
+			st.Downsampled++
+		case action == "recompressed":
+			st.Recompressed++
+		case action == "reencoded":
+			st.Reencoded++
+		}
+		st.SavedBytes += before - int64(len(sd.Raw))
+		e.Object = sd
+	}
+	return st
+}
+
+// imageComponents returns the number of color components of an image whose
+// samples can be averaged, or 0 (indexed and pattern images cannot).
+func imageComponents(ctx *model.Context, cs types.Object) int {
+	cs, _ = ctx.Dereference(cs)
+	switch c := cs.(type) {
+	case types.Name:
+		switch c {
+		case "DeviceGray", "CalGray", "G":
+			return 1
+		case "DeviceRGB", "CalRGB", "RGB":
+			return 3
+		case "DeviceCMYK", "CMYK":
+			return 4
+		}
+	case types.Array:
+		if len(c) == 0 {
+			return 0
+		}
+		name, _ := c[0].(types.Name)
+		switch name {
+		case "CalGray", "Separation":
+			return 1
+		case "CalRGB", "Lab":
+			return 3
+		case "ICCBased":
+			if len(c) > 1 {
+				if sd, _, err := ctx.DereferenceStreamDict(c[1]); err == nil && sd != nil {
+					if n := sd.IntEntry("N"); n != nil {
+						return *n
+					}
+				}
+			}
+		case "DeviceN":
+			if len(c) > 1 {
+				if a, err := ctx.DereferenceArray(c[1]); err == nil {
+					return len(a)
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// optimizeImage rewrites one image in place. It returns "downsampled",
+// "recompressed" or "reencoded" when it changed the image, "" when the
+// result would not be smaller, and "skipped" for images it cannot handle.
+func optimizeImage(ctx *model.Context, sd *types.StreamDict, s imageSettings, dpi float64) (string, error) {
+	if im := sd.BooleanEntry("ImageMask"); im != nil && *im {
+		return "skipped", nil
+	}
+	w, h := sd.IntEntry("Width"), sd.IntEntry("Height")
+	bpc := sd.IntEntry("BitsPerComponent")
+	if w == nil || h == nil || bpc == nil || *w <= 0 || *h <= 0 {
+		return "skipped", nil
+	}
+	n := imageComponents(ctx, sd.Dict["ColorSpace"])
+
+	scale := 1.0
+	if s.DPI > 0 && dpi > float64(s.DPI)*downsampleMargin {
+		scale = float64(s.DPI) / dpi
+	}
+	nw, nh := max(1, int(float64(*w)*scale+0.5)), max(1, int(float64(*h)*scale+0.5))
+	if nw == *w && nh == *h {
+		scale = 1
+	}
+
+	lossy := false
+	for _, f := range sd.FilterPipeline {
+		switch f.Name {
+		case "DCTDecode":
+			lossy = true
+		case "FlateDecode", "LZWDecode", "RunLengthDecode", "ASCII85Decode", "ASCIIHexDecode":
+		default:
+			return "skipped", nil
+		}
+	}
+
+	if lossy {
+		if len(sd.FilterPipeline) != 1 || (n != 1 && n != 3) || (scale == 1 && s.Quality == 0) {
+			if scale == 1 && s.Quality == 0 {
+				return "", nil
+			}
+			return "skipped", nil
+		}
+		img, err := jpeg.Decode(bytes.NewReader(sd.Raw))
+		if err != nil {
+			return "skipped", nil
+		}
+		pix, comps := imagePixels(img)
+		if comps != n {
+			return "skipped", nil
+		}
+		action := "recompressed"
+		if scale < 1 {
+			pix = resamplePixels(pix, *w, *h, n, nw, nh)
+			action = "downsampled"
+		} else {
+			nw, nh = *w, *h
+		}
+		q := s.Quality
+		if q == 0 {
+			q = defaultResampleQuality
+		}
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, pixelImage(pix, nw, nh, n), &jpeg.Options{Quality: q}); err != nil {
+			return "skipped", nil
+		}
+		if buf.Len() >= len(sd.Raw) {
+			return "", nil
+		}
+		setImageStream(sd, buf.Bytes(), nil, types.PDFFilter{Name: "DCTDecode"})
+		sd.Dict["Width"], sd.Dict["Height"] = types.Integer(nw), types.Integer(nh)
+		return action, nil
+	}
+
+	if scale == 1 && !s.Lossless {
+		return "", nil
+	}
+	if err := sd.Decode(); err != nil {
+		return "skipped", nil
+	}
+	pix := sd.Content
+	rowBytes := (*w*max(n, 1)**bpc + 7) / 8
+	action := "reencoded"
+	if scale < 1 {
+		if n == 0 || *bpc != 8 || len(pix) < *w**h*n {
+			if !s.Lossless {
+				return "skipped", nil
+			}
+		} else {
+			pix = resamplePixels(pix[:*w**h*n], *w, *h, n, nw, nh)
+			w, h = &nw, &nh
+			rowBytes = nw * n
+			action = "downsampled"
+		}
+	}
+
+	var parms types.Dict
+	data := pix
+	if n > 0 && len(pix) == rowBytes**h {
+		bpp := max(1, n**bpc/8)
+		data = pngPredict(pix, rowBytes, bpp)
+		parms = types.Dict{
+			"Predictor":        types.Integer(15),
+			"Colors":           types.Integer(n),
+			"BitsPerComponent": types.Integer(*bpc),
+			"Columns":          types.Integer(*w),
+		}
+	}
+	var buf bytes.Buffer
+	zw, _ := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	zw.Write(data)
+	zw.Close()
+	if buf.Len() >= len(sd.Raw) {
+		return "", nil
+	}
+	setImageStream(sd, buf.Bytes(), pix, types.PDFFilter{Name: "FlateDecode", DecodeParms: parms})
+	sd.Dict["Width"], sd.Dict["Height"] = types.Integer(*w), types.Integer(*h)
+	return action, nil
+}
+
+// setImageStream replaces the encoded data and filter of an image stream.
+func setImageStream(sd *types.StreamDict, raw, content []byte, f types.PDFFilter) {
+	sd.Raw = raw
+	sd.Content = content
+	sd.FilterPipeline = []types.PDFFilter{f}
+	sd.Dict["Filter"] = types.Name(f.Name)
+	delete(sd.Dict, "DecodeParms")
+	if f.DecodeParms != nil {
+		sd.Dict["DecodeParms"] = f.DecodeParms
+	}
+	l := int64(len(raw))
+	sd.StreamLength = &l
+	sd.Dict["Length"] = types.Integer(l)
+}
+
+// imagePixels flattens a decoded JPEG into 8-bit gray or RGB samples.
+func imagePixels(img image.Image) ([]byte, int) {
+	b := img.Bounds()
+	if g, ok := img.(*image.Gray); ok {
+		pix := make([]byte, 0, b.Dx()*b.Dy())
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			pix = append(pix, g.Pix[(y-b.Min.Y)*g.Stride:(y-b.Min.Y)*g.Stride+b.Dx()]...)
+		}
+		return pix, 1
+	}
+	if _, ok := img.(*image.CMYK); ok {
+		return nil, 4
+	}
+	pix := make([]byte, 0, b.Dx()*b.Dy()*3)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			pix = append(pix, byte(r>>8), byte(g>>8), byte(bl>>8))
+		}
+	}
+	return pix, 3
+}
+
+func pixelImage(pix []byte, w, h, n int) image.Image {
+	if n == 1 {
+		return &image.Gray{Pix: pix, Stride: w, Rect: image.Rect(0, 0, w, h)}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i, j := 0, 0; i+2 < len(pix); i, j = i+3, j+4 {
+		img.Pix[j], img.Pix[j+1], img.Pix[j+2], img.Pix[j+3] = pix[i], pix[i+1], pix[i+2], 255
+	}
+	return img
+}
+
+// resamplePixels shrinks 8-bit samples with n components from w×h to nw×nh
+// by averaging the source pixels under each target pixel.
+func resamplePixels(pix []byte, w, h, n, nw, nh int) []byte {
+	out := make([]byte, nw*nh*n)
+	sum := make([]int, n)
+	for ty := 0; ty < nh; ty++ {
+		y0, y1 := ty*h/nh, max((ty+1)*h/nh, ty*h/nh+1)
+		for tx := 0; tx < nw; tx++ {
+			x0, x1 := tx*w/nw, max((tx+1)*w/nw, tx*w/nw+1)
+			for c := range sum {
+				sum[c] = 0
+			}
+			for y := y0; y < y1; y++ {
+				row := pix[(y*w+x0)*n : (y*w+x1)*n]
+				for i, v := range row {
+					sum[i%n] += int(v)
+				}
+			}
+			count := (y1 - y0) * (x1 - x0)
+			for c := range sum {
+				out[(ty*nw+tx)*n+c] = byte((sum[c] + count/2) / count)
+			}
+		}
+	}
+	return out
+}
+
+// pngPredict applies PNG row filters, choosing for each row the filter with
+// the smallest sum of absolute differences, as PNG encoders do.
+func pngPredict(pix []byte, rowBytes, bpp int) []byte {
+	rows := len(pix) / rowBytes
+	out := make([]byte, 0, rows*(rowBytes+1))
+	prev := make([]byte, rowBytes)
+	cand := make([][]byte, 5)
+	for f := range cand {
+		cand[f] = make([]byte, rowBytes)
+	}
+	for r := 0; r < rows; r++ {
+		row := pix[r*rowBytes : (r+1)*rowBytes]
+		best, bestSum := 0, -1
+		for f := 0; f < 5; f++ {
+			dst := cand[f]
+			sum := 0
+			for i := range row {
+				var a, b, c byte
+				if i >= bpp {
+					a, c = row[i-bpp], prev[i-bpp]
+				}
+				b = prev[i]
+				var p byte
+				switch f {
+				case 1:
+					p = a
+				case 2:
+					p = b
+				case 3:
+					p = byte((int(a) + int(b)) / 2)
+				case 4:
+					p = paeth(a, b, c)
+				}
+				dst[i] = row[i] - p
+				sum += int(math.Abs(float64(int8(dst[i]))))
+			}
+			if bestSum < 0 || sum < bestSum {
+				best, bestSum = f, sum
+			}
+		}
+		out = append(out, byte(best))
+		out = append(out, cand[best]...)
+		prev = row
+	}
+	return out
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// pdfObjectUpdate is one object written by an incremental update. body is the
+// serialized object between "obj" and "endobj".
+type pdfObjectUpdate struct {
+	nr   int
+	body []byte
+}
+
+var startXRefRe = regexp.MustCompile(`startxref\s+(\d+)`)
+
+// lastStartXRef returns the offset recorded by the final startxref keyword.
+func lastStartXRef(src []byte) (int64, error) {
+	tail := src
+	if len(tail) > 2048 {
+		tail = tail[len(tail)-2048:]
+	}
+	m := startXRefRe.FindAllSubmatch(tail, -1)
+	if len(m) == 0 {
+		return 0, fmt.Errorf("no startxref found at end of file")
+	}
+	return strconv.ParseInt(string(m[len(m)-1][1]), 10, 64)
+}
+
+// nextObjectNumber returns the first object number not used by ctx.
+func nextObjectNumber(ctx *model.Context) int {
+	n := 0
+	if ctx.Size != nil {
+		n = *ctx.Size
+	}
+	for nr := range ctx.Table {
+		if nr >= n {
+			n = nr + 1
+		}
+	}
+	return n
+}
+
+// appendIncrementalUpdate returns src followed by an incremental update that
+// (re)defines objs. The new cross-reference section has the same form
+// (table or stream) as the file's own. info, when non-nil, becomes the
+// trailer's /Info. Encrypted files are rejected: new strings would have to be
+// encrypted too.
+func appendIncrementalUpdate(ctx *model.Context, src []byte, objs []pdfObjectUpdate, info *types.IndirectRef) ([]byte, error) {
+	if ctx.Encrypt != nil {
+		return nil, fmt.Errorf("file is encrypted; decrypt it first")
+	}
+	prev, err := lastStartXRef(src)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		info = ctx.Info
+	}
+
+	var buf bytes.Buffer
+	buf.Write(src)
+	if len(src) > 0 && src[len(src)-1] != '\n' && src[len(src)-1] != '\r' {
+		buf.WriteByte('\n')
+	}
+
+	offsets := map[int]int64{}
+	for _, o := range objs {
+		offsets[o.nr] = int64(buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n", o.nr)
+		buf.Write(o.body)
+		buf.WriteString("\nendobj\n")
+	}
+
+	size := nextObjectNumber(ctx)
+	trailer := types.NewDict()
+	trailer.Insert("Root", *ctx.Root)
+	if info != nil {
+		trailer.Insert("Info", *info)
+	}
+	if len(ctx.ID) > 0 {
+		trailer.Insert("ID", ctx.ID)
+	}
+	trailer.Insert("Prev", types.Integer(prev))
+
+	if !ctx.Read.UsingXRefStreams {
+		nrs := sortedObjectNumbers(offsets)
+		trailer.Insert("Size", types.Integer(max(size, nrs[len(nrs)-1]+1)))
+		xref := int64(buf.Len())
+		buf.WriteString("xref\n")
+		for _, nr := range nrs {
+			fmt.Fprintf(&buf, "%d 1\n%010d 00000 n \n", nr, offsets[nr])
+		}
+		fmt.Fprintf(&buf, "trailer\n%s\nstartxref\n%d\n%%%%EOF\n", trailer.PDFString(), xref)
+		return buf.Bytes(), nil
+	}
+
+	// The xref stream gets the next free number and lists itself.
+	xrefNr := size
+	for nr := range offsets {
+		if nr >= xrefNr {
+			xrefNr = nr + 1
+		}
+	}
+	xref := int64(buf.Len())
+	offsets[xrefNr] = xref
+	nrs := sortedObjectNumbers(offsets)
+	var index types.Array
+	var data bytes.Buffer
+	for _, nr := range nrs {
+		index = append(index, types.Integer(nr), types.Integer(1))
+		data.WriteByte(1)
+		_ = binary.Write(&data, binary.BigEndian, uint32(offsets[nr]))
+		data.Write([]byte{0, 0})
+	}
+	trailer.Insert("Type", types.Name("XRef"))
+	trailer.Insert("Size", types.Integer(xrefNr+1))
+	trailer.Insert("Index", index)
+	trailer.Insert("W", types.Array{types.Integer(1), types.Integer(4), types.Integer(2)})
+	trailer.Insert("Length", types.Integer(data.Len()))
+	fmt.Fprintf(&buf, "%d 0 obj\n%s\nstream\n", xrefNr, trailer.PDFString())
+	buf.Write(data.Bytes())
+	fmt.Fprintf(&buf, "\nendstream\nendobj\nstartxref\n%d\n%%%%EOF\n", xref)
+	return buf.Bytes(), nil
+}
+
+// rewriteKeepingInfo serializes ctx as a complete new file whose Info
+// dictionary is exactly the context's, rather than the one pdfcpu stamps with
+// its own Producer and the current dates on every write.
+func rewriteKeepingInfo(ctx *model.Context) ([]byte, error) {
+	info := types.NewDict()
+	if d, err := readInfoDict(ctx); err != nil {
+		return nil, err
+	} else if d != nil {
+		info = d.Clone().(types.Dict)
+	}
+	// Plain objects and a classic xref table let the Info object be patched.
+	ctx.WriteObjectStream = false
+	ctx.WriteXRefStream = false
+	var buf bytes.Buffer
+	if err := api.WriteContext(ctx, &buf); err != nil {
+		return nil, err
+	}
+	return replaceInfoObject(buf.Bytes(), info)
+}
+
+var (
+	trailerInfoRe = regexp.MustCompile(`/Info\s+(\d+)\s+0\s+R`)
+	xrefEntryRe   = regexp.MustCompile(`(\d{10}) (\d{5}) n`)
+)
+
+// replaceInfoObject swaps the body of the Info object in a file written with
+// a classic xref table for info, shifting the offsets of everything after it.
+func replaceInfoObject(src []byte, info types.Dict) ([]byte, error) {
+	xref, err := lastStartXRef(src)
+	if err != nil {
+		return nil, err
+	}
+	if xref <= 0 || xref >= int64(len(src)) || !bytes.HasPrefix(src[xref:], []byte("xref")) {
+		return nil, fmt.Errorf("unexpected cross-reference format in written file")
+	}
+	m := trailerInfoRe.FindSubmatch(src[xref:])
+	if m == nil {
+		return nil, fmt.Errorf("written file has no Info dictionary")
+	}
+	table := src[xref:]
+	tableEnd := bytes.Index(table, []byte("trailer"))
+	if tableEnd < 0 {
+		return nil, fmt.Errorf("written file has no trailer")
+	}
+	nr, _ := strconv.Atoi(string(m[1]))
+	offset := xrefOffset(table[:tableEnd], nr)
+	if offset <= 0 || offset >= xref || !bytes.HasPrefix(src[offset:], []byte(fmt.Sprintf("%d 0 obj", nr))) {
+		return nil, fmt.Errorf("Info object %d not found in written file", nr)
+	}
+	start := int(offset) + len(fmt.Sprintf("%d 0 obj", nr))
+	end := bytes.Index(src[start:], []byte("endobj"))
+	if end < 0 {
+		return nil, fmt.Errorf("Info object %d is not terminated", nr)
+	}
+	end += start
+	body := []byte("\n" + info.PDFString() + "\n")
+	delta := int64(len(body) - (end - start))
+
+	var out bytes.Buffer
+	out.Write(src[:start])
+	out.Write(body)
+	out.Write(src[end:xref])
+	out.Write(xrefEntryRe.ReplaceAllFunc(table[:tableEnd], func(e []byte) []byte {
+		off, _ := strconv.ParseInt(string(e[:10]), 10, 64)
+		if off > int64(start) {
+			off += delta
+		}
+		return []byte(fmt.Sprintf("%010d%s", off, e[10:]))
+	}))
+	out.Write(startXRefRe.ReplaceAllLiteral(table[tableEnd:], []byte(fmt.Sprintf("startxref\n%d", xref+delta))))
+	return out.Bytes(), nil
+}
+
+// xrefOffset looks up an in-use object in a classic xref section.
+func xrefOffset(table []byte, nr int) int64 {
+	f := strings.Fields(strings.TrimPrefix(string(table), "xref"))
+	for i := 0; i+1 < len(f); {
+		first, err1 := strconv.Atoi(f[i])
+		count, err2 := strconv.Atoi(f[i+1])
+		if err1 != nil || err2 != nil {
+			return -1
+		}
+		i += 2
+		if nr >= first && nr < first+count && i+3*(nr-first)+2 < len(f) {
+			e := f[i+3*(nr-first):]
+			if e[2] != "n" {
+				return -1
+			}
+			off, _ := strconv.ParseInt(e[0], 10, 64)
+			return off
+		}
+		i += 3 * count
+	}
+	return -1
+}
+
+func sortedObjectNumbers(m map[int]int64) []int {
+	nrs := make([]int, 0, len(m))
+	for nr := range m {
+		nrs = append(nrs, nr)
+	}
+	sort.Ints(nrs)
+	return nrs
+}
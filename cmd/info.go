@@ -1,49 +1,92 @@
 package cmd
 
 import (
+	"encoding/csv"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/pdfcpu/pdfcpu/pkg/api"
 	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
 	"github.com/spf13/cobra"
 )
 
+var (
+	infoFormat    string
+	infoRecursive bool
+	infoPassword  string
+	infoPWFrom    string
+)
+
 var infoCmd = &cobra.Command{
-	Use:   "info <input.pdf>",
+	Use:   "info <input.pdf|dir> [more...]",
 	Short: "Show PDF metadata and properties",
-	Args:  cobra.ExactArgs(1),
+	Long: fmt.Sprintf(`Show metadata and properties of a PDF. With several files or a directory,
+print one row per PDF instead.
+
+%s
+  pdfed info report.pdf
+  pdfed info a.pdf b.pdf c.pdf
+  pdfed info ./course-pdfs -r
+  pdfed info ./course-pdfs -r --format csv > audit.csv
+  pdfed info ./course-pdfs -r --format ndjson
+
+%s
+  table   aligned columns (default for several files)
+  csv     header row plus one row per PDF
+  ndjson  one JSON object per line, same fields as --json`, bold("Examples:"), bold("Formats:")),
+	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runInfo(args[0])
+		if infoPWFrom != "" {
+			var err error
+			if infoPassword, err = readPassword(infoPWFrom, "password", false); err != nil {
+				return err
+			}
+		}
+		switch infoFormat {
+		case "", "table", "csv", "ndjson":
+		default:
+			return fmt.Errorf("unknown --format %q (use table, csv or ndjson)", infoFormat)
+		}
+		files, err := collectPDFs(args, infoRecursive)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("no PDF files found")
+		}
+		if infoFormat == "" && len(args) == 1 && len(files) == 1 && files[0] == args[0] {
+			return runInfo(args[0])
+		}
+		return runInfoMulti(files)
 	},
 }
 
 func init() {
+	infoCmd.Flags().StringVarP(&infoFormat, "format", "f", "", "Output format for several files: table, csv, ndjson")
+	infoCmd.Flags().BoolVarP(&infoRecursive, "recursive", "r", false, "Descend into subdirectories")
+	infoCmd.Flags().StringVar(&infoPassword, "password", "", "Password for encrypted files (user or owner)")
+	infoCmd.Flags().StringVar(&infoPWFrom, "password-from", "", "Read the password from "+passwordSourceHelp)
+	infoCmd.MarkFlagsMutuallyExclusive("password", "password-from")
 	rootCmd.AddCommand(infoCmd)
 }
 
 func runInfo(inFile string) error {
 	printInfo(fmt.Sprintf("Reading %s…", inFile))
 
-	f, err := os.Open(inFile)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	info, err := api.PDFInfo(f, inFile, nil, false, pdfConfig())
-	if err != nil {
-		return err
-	}
-
-	fi, err := os.Stat(inFile)
+	d, err := readPDFInfo(inFile)
 	if err != nil {
 		return err
 	}
+	info, fi := d.info, d.fi
 
 	if jsonOut {
-		return emitInfoJSON(inFile, fi, info)
+		return emitInfoJSON(inFile, d)
 	}
 
 	row := func(label, value string) {
@@ -62,6 +105,18 @@ func runInfo(inFile string) error {
 	rowf("Size", "%s", humanSize(fi.Size()))
 	rowf("Version", "PDF %s", info.Version)
 	rowf("Pages", "%d", info.PageCount)
+	if d.kinds != nil {
+		row("Content", pageKindSummary(d.kinds))
+		if scans := pagesOfKind(d.kinds, kindImage); len(scans) > 0 {
+			noun := "pages "
+			if len(scans) == 1 {
+				noun = "page "
+			}
+			row("Image-only", noun+compactPageList(scans)+" "+dimStyle.Render("(no text layer, not searchable)"))
+		}
+	} else if d.kindsErr != nil {
+		row("Content", dimStyle.Render("unknown ("+d.kindsErr.Error()+")"))
+	}
 
 	if len(info.Dimensions) > 0 {
 		d := info.Dimensions[0]
@@ -84,6 +139,26 @@ func runInfo(inFile string) error {
 		row("Keywords", strings.Join(info.Keywords, ", "))
 	}
 
+	if d.xmpErr != nil {
+		fmt.Println()
+		printWarning(fmt.Sprintf("XMP metadata could not be parsed: %v", d.xmpErr))
+	}
+	if d.xmp != nil {
+		fmt.Println()
+		fmt.Println(bold(" XMP") + "  " + dimStyle.Render(humanSize(int64(d.xmp.Size))))
+		fmt.Println(strings.Repeat("─", 50))
+		row("PDF/A", d.xmp.pdfA())
+		for _, p := range d.xmp.Properties {
+			row(p.Key, strings.Join(p.Values, "; "))
+		}
+		if len(d.conflicts) > 0 {
+			fmt.Println()
+			for _, c := range d.conflicts {
+				printWarning(fmt.Sprintf("%s differs: Info %q vs %s %q", c.Field, c.Info, c.XMPKey, c.XMP))
+			}
+		}
+	}
+
 	fmt.Println()
 	fmt.Println(bold(" Features"))
 	fmt.Println(strings.Repeat("─", 50))
@@ -96,17 +171,384 @@ func runInfo(inFile string) error {
 	}
 	feature("Encrypted", info.Encrypted)
 	feature("Linearized (web optimized)", info.Linearized)
+	if lin := d.lin; lin != nil {
+		if lin.Valid {
+			fmt.Println("     " + dimStyle.Render(fmt.Sprintf("hint tables verified (%d page(s), %d shared object(s))", lin.Pages, lin.SharedObjects)))
+		} else {
+			for _, p := range lin.Problems {
+				fmt.Println("     " + yellow(p))
+			}
+		}
+	}
 	feature("Tagged", info.Tagged)
 	feature("Watermarked", info.Watermarked)
 	feature("Bookmarks/Outlines", info.Outlines)
 	feature("Form fields", info.Form)
 	feature("Signatures", info.Signatures)
 	feature("Attachments", len(info.Attachments) > 0)
+	if info.Signatures {
+		fmt.Println("  " + dimStyle.Render("Run `pdfed signatures "+inFile+"` to verify the signatures."))
+	}
+
+	if enc := d.enc; enc != nil {
+		fmt.Println()
+		fmt.Println(bold(" Security"))
+		fmt.Println(strings.Repeat("─", 50))
+		rowf("Handler", "%s (V%d, revision %d)", enc.Handler, enc.Version, enc.Revision)
+		rowf("Algorithm", "%s (%d-bit key)", enc.Algorithm, enc.KeyBits)
+		if !enc.EncryptMetadata {
+			row("Metadata", "stored unencrypted")
+		}
+		fmt.Println()
+		for _, p := range enc.Permissions.rows() {
+			feature(p.label, p.on)
+		}
+		fmt.Println("  " + dimStyle.Render("Restrictions apply to the user password; the owner password grants everything."))
+	}
 	fmt.Println()
 	return nil
 }
 
-func emitInfoJSON(path string, fi os.FileInfo, info *pdfcpu.PDFInfo) error {
+// pdfDetails is everything info reports about one file.
+type pdfDetails struct {
+	info      *pdfcpu.PDFInfo
+	fi        os.FileInfo
+	xmp       *xmpPacket // nil when the catalog has no Metadata stream
+	xmpErr    error      // set when the XMP packet is present but unreadable
+	conflicts []metaConflict
+	enc       *encryptionInfo // nil when the file is not encrypted
+	kinds     []pageKind      // per-page content classification; nil if it failed
+	kindsErr  error
+	lin       *linearizationCheck // nil when the file is not linearized
+}
+
+// readPDFInfo reads pdfcpu's info summary, the file's stat and its XMP metadata.
+func readPDFInfo(inFile string) (*pdfDetails, error) {
+	f, err := os.Open(inFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	conf := pdfConfig()
+	conf.Cmd = model.LISTINFO
+	if infoPassword != "" {
+		// Either password opens the file; pdfcpu tries owner first, then user.
+		conf.UserPW = infoPassword
+		conf.OwnerPW = infoPassword
+	}
+	ctx, err := api.ReadAndValidate(f, conf)
+	if err != nil {
+		return nil, err
+	}
+	if err := pdfcpu.DetectWatermarks(ctx); err != nil {
+		return nil, err
+	}
+	info, err := pdfcpu.Info(ctx, inFile, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(inFile)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := describeEncryption(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &pdfDetails{info: info, fi: fi, enc: enc}
+	if info.Linearized {
+		raw, err := os.ReadFile(inFile)
+		if err != nil {
+			return nil, err
+		}
+		d.lin = checkLinearization(ctx, raw)
+	}
+	if texts, err := pageTexts(ctx, inFile); err != nil {
+		d.kindsErr = err
+	} else {
+		d.kinds, d.kindsErr = classifyContextPages(ctx, texts)
+	}
+	raw, err := readCatalogXMP(ctx)
+	if err == nil && raw != nil {
+		d.xmp, err = parseXMP(raw)
+	}
+	if err != nil {
+		d.xmpErr = err
+	}
+	d.conflicts = compareInfoXMP(map[string]string{
+		"Title":        info.Title,
+		"Author":       info.Author,
+		"Subject":      info.Subject,
+		"Keywords":     strings.Join(info.Keywords, ", "),
+		"Creator":      info.Creator,
+		"Producer":     info.Producer,
+		"CreationDate": info.CreationDate,
+		"ModDate":      info.ModificationDate,
+	}, d.xmp)
+	return d, nil
+}
+
+// ── multi-file ────────────────────────────────────────────────────────────────
+
+// infoRow is one file's result in multi-file mode.
+type infoRow struct {
+	path string
+	d    *pdfDetails
+	err  error
+}
+
+var infoCSVHeader = []string{
+	"file", "pages", "size_bytes", "pdf_version", "title", "author", "subject",
+	"creator", "producer", "created", "modified", "encrypted", "linearized", "tagged", "outlines", "form_fields", "signatures",
+	"attachments", "encryption", "pdfa", "xmp_conflicts",
+	"text_pages", "image_only_pages", "mixed_pages", "blank_pages", "error",
+}
+
+func runInfoMulti(files []string) error {
+	if infoFormat == "" || infoFormat == "table" {
+		printInfo(fmt.Sprintf("Reading %d PDF(s)…", len(files)))
+	}
+
+	rows := make([]infoRow, 0, len(files))
+	failed := 0
+	for _, path := range files {
+		d, err := readPDFInfo(path)
+		if err != nil {
+			failed++
+		}
+		rows = append(rows, infoRow{path: path, d: d, err: err})
+	}
+
+	var err error
+	switch {
+	case infoFormat == "csv":
+		err = writeInfoCSV(rows)
+	case infoFormat == "ndjson":
+		err = writeInfoNDJSON(rows)
+	case jsonOut:
+		files := make([]map[string]interface{}, 0, len(rows))
+		for _, r := range rows {
+			files = append(files, infoRowJSON(r))
+		}
+		return jsonResultOK("info", map[string]interface{}{
+			"file_count": len(rows),
+			"failed":     failed,
+			"files":      files,
+		})
+	default:
+		err = writeInfoTable(rows)
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 && !jsonOut {
+		return fmt.Errorf("%d of %d file(s) could not be read", failed, len(rows))
+	}
+	return nil
+}
+
+func infoRowJSON(r infoRow) map[string]interface{} {
+	if r.err != nil {
+		return map[string]interface{}{"ok": false, "input": r.path, "error": r.err.Error()}
+	}
+	m := infoJSONFields(r.path, r.d)
+	m["ok"] = true
+	return m
+}
+
+func writeInfoNDJSON(rows []infoRow) error {
+	for _, r := range rows {
+		if err := jsonEmit(infoRowJSON(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeInfoCSV(rows []infoRow) error {
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write(infoCSVHeader); err != nil {
+		return err
+	}
+	yn := func(b bool) string { return strconv.FormatBool(b) }
+	for _, r := range rows {
+		if r.err != nil {
+			rec := make([]string, len(infoCSVHeader))
+			rec[0] = r.path
+			rec[len(rec)-1] = r.err.Error()
+			if err := w.Write(rec); err != nil {
+				return err
+			}
+			continue
+		}
+		info := r.d.info
+		rec := []string{
+			r.path,
+			strconv.Itoa(info.PageCount),
+			strconv.FormatInt(r.d.fi.Size(), 10),
+			info.Version,
+			info.Title,
+			info.Author,
+			info.Subject,
+			info.Creator,
+			info.Producer,
+			info.CreationDate,
+			info.ModificationDate,
+			yn(info.Encrypted),
+			yn(info.Linearized),
+			yn(info.Tagged),
+			yn(info.Outlines),
+			yn(info.Form),
+			yn(info.Signatures),
+			strconv.Itoa(len(info.Attachments)),
+			encryptionSummary(r.d.enc),
+			r.d.xmp.pdfA(),
+			strconv.Itoa(len(r.d.conflicts)),
+		}
+		for _, k := range pageKinds {
+			n := ""
+			if r.d.kinds != nil {
+				n = strconv.Itoa(countPageKinds(r.d.kinds)[k])
+			}
+			rec = append(rec, n)
+		}
+		rec = append(rec, "")
+		if err := w.Write(rec); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeInfoTable(rows []infoRow) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tPAGES\tSCAN\tSIZE\tVER\tENC\tTAG\tLIN\tFORM\tSIG\tTITLE")
+	mark := func(b bool) string {
+		if b {
+			return "✓"
+		}
+		return "·"
+	}
+	var totalPages int
+	var totalSize int64
+	for _, r := range rows {
+		if r.err != nil {
+			fmt.Fprintf(tw, "%s\t\t\t\t\t\t\t\t\t\t%s\n", r.path, "error: "+r.err.Error())
+			continue
+		}
+		info, fi := r.d.info, r.d.fi
+		totalPages += info.PageCount
+		totalSize += fi.Size()
+		// SCAN counts image-only pages, the ones search cannot see.
+		scan := "?"
+		if r.d.kinds != nil {
+			scan = "·"
+			if n := len(pagesOfKind(r.d.kinds, kindImage)); n > 0 {
+				scan = strconv.Itoa(n)
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.path, info.PageCount, scan, humanSize(fi.Size()), info.Version,
+			mark(info.Encrypted), mark(info.Tagged), mark(info.Linearized),
+			mark(info.Form), mark(info.Signatures), truncate(info.Title, 40))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	printf("\n%s %d file(s), %d pages, %s\n", cyan("Σ"), len(rows), totalPages, humanSize(totalSize))
+	return nil
+}
+
+// encryptionSummary is the one-cell form of an encryption report, e.g. "AES-128 R4".
+func encryptionSummary(enc *encryptionInfo) string {
+	if enc == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s R%d", enc.Algorithm, enc.Revision)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// collectPDFs expands directory arguments into the PDFs they contain (sorted by
+// name, optionally recursive) and unexpanded glob patterns into their matches.
+// Plain file arguments are passed through as given.
+func collectPDFs(args []string, recursive bool) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		st, err := os.Stat(arg)
+		if os.IsNotExist(err) && strings.ContainsAny(arg, "*?[") {
+			// A quoted glob the shell did not expand.
+			matches, gerr := filepath.Glob(arg)
+			if gerr != nil {
+				return nil, fmt.Errorf("invalid pattern %s: %w", arg, gerr)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %s", arg)
+			}
+			var keep []string
+			for _, m := range matches {
+				if st, err := os.Stat(m); err == nil && (st.IsDir() || strings.HasSuffix(strings.ToLower(m), ".pdf")) {
+					keep = append(keep, m)
+				}
+			}
+			sub, err := collectPDFs(keep, recursive)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, sub...)
+			continue
+		}
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("input not found: %s", arg)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot access %s: %w", arg, err)
+		}
+		if !st.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != arg && !recursive {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(strings.ToLower(d.Name()), ".pdf") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read directory %s: %w", arg, err)
+		}
+	}
+	return files, nil
+}
+
+func emitInfoJSON(path string, d *pdfDetails) error {
+	return jsonResultOK("info", infoJSONFields(path, d))
+}
+
+// infoJSONFields builds the document/metadata/features object shared by
+// single-file --json output and the multi-file formats.
+func infoJSONFields(path string, d *pdfDetails) map[string]interface{} {
+	info, fi := d.info, d.fi
 	doc := map[string]interface{}{
 		"file":        path,
 		"size_bytes":  fi.Size(),
@@ -141,12 +583,50 @@ func emitInfoJSON(path string, fi os.FileInfo, info *pdfcpu.PDFInfo) error {
 		"signatures":      info.Signatures,
 		"has_attachments": len(info.Attachments) > 0,
 	}
-	return jsonResultOK("info", map[string]interface{}{
+	if d.lin != nil {
+		if d.lin.Problems == nil {
+			d.lin.Problems = []string{}
+		}
+		features["linearization"] = d.lin
+	}
+	m := map[string]interface{}{
 		"input":    path,
 		"document": doc,
 		"metadata": meta,
 		"features": features,
-	})
+	}
+	if d.xmp != nil {
+		props := make(map[string][]string, len(d.xmp.Properties))
+		for _, p := range d.xmp.Properties {
+			props[p.Key] = p.Values
+		}
+		conflicts := d.conflicts
+		if conflicts == nil {
+			conflicts = []metaConflict{}
+		}
+		m["xmp"] = map[string]interface{}{
+			"size_bytes": d.xmp.Size,
+			"pdfa":       d.xmp.pdfA(),
+			"properties": props,
+			"conflicts":  conflicts,
+		}
+	}
+	if d.xmpErr != nil {
+		m["xmp_error"] = d.xmpErr.Error()
+	}
+	if d.enc != nil {
+		m["encryption"] = d.enc
+	}
+	if d.kinds != nil {
+		m["page_content"] = map[string]interface{}{
+			"counts":           countPageKinds(d.kinds),
+			"pages":            d.kinds,
+			"image_only_pages": pagesOfKind(d.kinds, kindImage),
+		}
+	} else if d.kindsErr != nil {
+		m["page_content_error"] = d.kindsErr.Error()
+	}
+	return m
 }
 
 func humanSize(b int64) string {
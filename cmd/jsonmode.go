@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 )
 
@@ -28,3 +29,28 @@ func jsonResultOK(command string, fields map[string]interface{}) error {
 	}
 	return jsonEmit(m)
 }
+
+// jsonFailure is an error that, in --json mode, is reported together with the
+// command's result fields, so a failed check still emits its full report.
+type jsonFailure struct {
+	command string
+	fields  map[string]interface{}
+	err     error
+}
+
+func (e *jsonFailure) Error() string { return e.err.Error() }
+
+// jsonFailureFields returns the object Execute prints for a failed command.
+func jsonFailureFields(err error) map[string]interface{} {
+	m := map[string]interface{}{}
+	var jf *jsonFailure
+	if errors.As(err, &jf) {
+		for k, v := range jf.fields {
+			m[k] = v
+		}
+		m["command"] = jf.command
+	}
+	m["ok"] = false
+	m["error"] = err.Error()
+	return m
+}
@@ -0,0 +1,319 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// headerFooterLines is how many text lines at each end of a page are
+// searched for a printed page number.
+const headerFooterLines = 2
+
+// minLabelSupport is how many pages must print a consistent sequence before
+// it becomes a label range; a single number is as likely to be body text.
+const minLabelSupport = 2
+
+// printedNumber is a page number found in a page's header or footer.
+type printedNumber struct {
+	value int
+	style string // "D", "r" or "R"
+	text  string
+}
+
+// labelSequence is a numbering style with a fixed distance between printed
+// and physical page numbers: page p prints value p+offset.
+type labelSequence struct {
+	style  string
+	offset int
+}
+
+// inferredRange is one proposed label range with the pages that back it.
+type inferredRange struct {
+	entry   pageLabelEntry
+	end     int // last physical page (1-based)
+	matched int // pages in the range whose printed number agrees
+}
+
+// labelMismatch is a page whose printed number disagrees with its proposed label.
+type labelMismatch struct {
+	Page    int    `json:"page"`
+	Printed string `json:"printed"`
+	Label   string `json:"label"`
+}
+
+type labelInference struct {
+	ranges     []inferredRange
+	mismatches []labelMismatch
+	numbered   int // pages with at least one number candidate
+}
+
+func (inf *labelInference) entries() []pageLabelEntry {
+	out := make([]pageLabelEntry, len(inf.ranges))
+	for i, r := range inf.ranges {
+		out[i] = r.entry
+	}
+	return out
+}
+
+// pageNumberWords introduce a number, as in "Page 12" or "p. 12".
+var pageNumberWords = map[string]bool{"page": true, "p": true, "pg": true, "seite": true, "pag": true}
+
+// printedNumbers returns the page number candidates in the first and last
+// lines of text: a line's first or last word, or the word after "Page".
+func printedNumbers(text string) []printedNumber {
+	var lines []string
+	for _, l := range strings.Split(text, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	var edge []string
+	for i, l := range lines {
+		if i < headerFooterLines || i >= len(lines)-headerFooterLines {
+			edge = append(edge, l)
+		}
+	}
+
+	var out []printedNumber
+	seen := map[printedNumber]bool{}
+	for _, l := range edge {
+		words := strings.FieldsFunc(l, func(r rune) bool {
+			return unicode.IsSpace(r) || strings.ContainsRune("|•·–—/", r)
+		})
+		if len(words) == 0 {
+			continue
+		}
+		var picks []string
+		picks = append(picks, words[0], words[len(words)-1])
+		for i := 0; i+1 < len(words); i++ {
+			if pageNumberWords[strings.ToLower(strings.Trim(words[i], ".:"))] {
+				picks = append(picks, words[i+1])
+			}
+		}
+		for _, w := range picks {
+			if n, ok := parsePrintedNumber(strings.Trim(w, "-–—[](){}.,:;*")); ok && !seen[n] {
+				seen[n] = true
+				out = append(out, n)
+			}
+		}
+	}
+	return out
+}
+
+// parsePrintedNumber accepts a decimal number or a roman numeral in its
+// canonical spelling, so words like "mix" or "dim" are not numbers.
+func parsePrintedNumber(w string) (printedNumber, bool) {
+	if w == "" || len(w) > 12 {
+		return printedNumber{}, false
+	}
+	if n, err := strconv.Atoi(w); err == nil {
+		if n < 1 || n > 9999 || w[0] == '0' {
+			return printedNumber{}, false
+		}
+		return printedNumber{value: n, style: "D", text: w}, true
+	}
+	lower := strings.ToLower(w)
+	if strings.Trim(lower, "ivxlcdm") != "" || (w != lower && w != strings.ToUpper(w)) {
+		return printedNumber{}, false
+	}
+	n := romanValue(lower)
+	if n < 1 || n > 3999 || toRoman(n, false) != lower {
+		return printedNumber{}, false
+	}
+	style := "r"
+	if w != lower {
+		style = "R"
+	}
+	return printedNumber{value: n, style: style, text: w}, true
+}
+
+func romanValue(s string) int {
+	vals := map[byte]int{'i': 1, 'v': 5, 'x': 10, 'l': 50, 'c': 100, 'd': 500, 'm': 1000}
+	total := 0
+	for i := 0; i < len(s); i++ {
+		v := vals[s[i]]
+		if i+1 < len(s) && vals[s[i+1]] > v {
+			total -= v
+		} else {
+			total += v
+		}
+	}
+	return total
+}
+
+// inferPageLabels proposes label ranges from the page numbers printed in
+// texts, the output of extractTextByPage. Every sequence printed on at least
+// minLabelSupport pages claims the pages from its first to its last
+// occurrence, strongest first; a range then starts as early as its
+// numbering allows (an unnumbered chapter opener before page 2 becomes
+// page 1). Pages before the first range get an empty label.
+func inferPageLabels(texts map[int]string, pageCount int) (*labelInference, error) {
+	inf := &labelInference{}
+	cands := make([][]printedNumber, pageCount+1)
+	support := map[labelSequence][]int{}
+	for p := 1; p <= pageCount; p++ {
+		cands[p] = printedNumbers(texts[p])
+		if len(cands[p]) > 0 {
+			inf.numbered++
+		}
+		for _, c := range cands[p] {
+			seq := labelSequence{c.style, c.value - p}
+			support[seq] = append(support[seq], p)
+		}
+	}
+
+	seqs := make([]labelSequence, 0, len(support))
+	for s, pages := range support {
+		if len(pages) >= minLabelSupport {
+			seqs = append(seqs, s)
+		}
+	}
+	sort.Slice(seqs, func(i, j int) bool {
+		a, b := support[seqs[i]], support[seqs[j]]
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return a[0] < b[0]
+	})
+
+	type segment struct {
+		seq         labelSequence
+		first, last int
+	}
+	claimed := make([]bool, pageCount+1)
+	var segs []segment
+	for _, s := range seqs {
+		pages := support[s]
+		// Split the sequence wherever a stronger one already holds a page.
+		run := []int{}
+		flush := func() {
+			if len(run) >= minLabelSupport {
+				for p := run[0]; p <= run[len(run)-1]; p++ {
+					claimed[p] = true
+				}
+				segs = append(segs, segment{s, run[0], run[len(run)-1]})
+			}
+			run = run[:0]
+		}
+		for _, p := range pages {
+			if claimed[p] {
+				flush()
+				continue
+			}
+			if len(run) > 0 {
+				for q := run[len(run)-1] + 1; q < p; q++ {
+					if claimed[q] {
+						flush()
+						break
+					}
+				}
+			}
+			run = append(run, p)
+		}
+		flush()
+	}
+	if len(segs) == 0 {
+		if inf.numbered == 0 {
+			return nil, fmt.Errorf("no printed page numbers found in the text layer (scanned pages need OCR first)")
+		}
+		return nil, fmt.Errorf("no consistent page numbering found (%d page(s) print a number)", inf.numbered)
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i].first < segs[j].first })
+
+	prevEnd := 0
+	for i, s := range segs {
+		if i > 0 && s.seq == segs[i-1].seq {
+			inf.ranges[len(inf.ranges)-1].end = s.last
+			prevEnd = s.last
+			continue
+		}
+		start := max(prevEnd+1, max(1-s.seq.offset, 1))
+		if start > s.first {
+			start = s.first
+		}
+		if len(inf.ranges) == 0 && start > 1 {
+			inf.ranges = append(inf.ranges, inferredRange{entry: pageLabelEntry{startIndex: 0, startValue: 1}, end: start - 1})
+		} else if len(inf.ranges) > 0 {
+			inf.ranges[len(inf.ranges)-1].end = start - 1
+		}
+		inf.ranges = append(inf.ranges, inferredRange{
+			entry: pageLabelEntry{startIndex: start - 1, style: s.seq.style, startValue: start + s.seq.offset},
+			end:   s.last,
+		})
+		prevEnd = s.last
+	}
+	inf.ranges[len(inf.ranges)-1].end = pageCount
+
+	labels := generateLabels(inf.entries(), pageCount)
+	for i := range inf.ranges {
+		r := &inf.ranges[i]
+		for p := r.entry.startIndex + 1; p <= r.end; p++ {
+			if len(cands[p]) == 0 || r.entry.style == "" {
+				continue
+			}
+			var printed []string
+			agree := false
+			for _, c := range cands[p] {
+				if c.style == r.entry.style && c.value == p-r.entry.startIndex-1+r.entry.startValue {
+					agree = true
+				}
+				printed = append(printed, c.text)
+			}
+			if agree {
+				r.matched++
+			} else {
+				inf.mismatches = append(inf.mismatches, labelMismatch{Page: p, Printed: strings.Join(printed, " "), Label: labels[p-1]})
+			}
+		}
+	}
+	return inf, nil
+}
+
+// inferredRangeJSON is one proposed range in --json output.
+type inferredRangeJSON struct {
+	labelRangeJSON
+	End     int `json:"end"`
+	Matched int `json:"matched"`
+}
+
+func inferredRangesJSON(inf *labelInference) []inferredRangeJSON {
+	out := make([]inferredRangeJSON, len(inf.ranges))
+	for i, r := range inf.ranges {
+		out[i] = inferredRangeJSON{labelRangesJSON([]pageLabelEntry{r.entry})[0], r.end, r.matched}
+	}
+	return out
+}
+
+func printLabelInference(inFile string, inf *labelInference, pageCount int) {
+	if quiet {
+		return
+	}
+	labels := generateLabels(inf.entries(), pageCount)
+	fmt.Println()
+	fmt.Println(bold(" Inferred page labels") + "  " + dimStyle.Render(inFile))
+	fmt.Println(strings.Repeat("─", 50))
+	fmt.Printf("  %s\n", bold(fmt.Sprintf("%-9s  %-8s  %-5s  %-14s  %s", "PAGES", "STYLE", "START", "LABELS", "EVIDENCE")))
+	for _, r := range inf.ranges {
+		first := r.entry.startIndex + 1
+		pages, span := strconv.Itoa(first), labels[first-1]
+		if r.end > first {
+			pages += "-" + strconv.Itoa(r.end)
+			span += " … " + labels[r.end-1]
+		}
+		evidence := fmt.Sprintf("printed on %d of %d pages", r.matched, r.end-first+1)
+		if r.entry.style == "" {
+			span, evidence = "(empty)", "no printed number"
+		}
+		fmt.Printf("  %-9s  %-8s  %-5d  %-14s  %s\n", pages, labelStyleNames[r.entry.style], r.entry.startValue, span, dimStyle.Render(evidence))
+	}
+	if len(inf.mismatches) > 0 {
+		fmt.Println()
+		for _, m := range inf.mismatches {
+			fmt.Printf("  %s page %d prints %q but would be labelled %q\n", yellow("!"), m.Page, m.Printed, m.Label)
+		}
+	}
+	fmt.Println()
+}
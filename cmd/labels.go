@@ -0,0 +1,618 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	labelsRanges   []string
+	labelsUnset    []int
+	labelsRemove   bool
+	labelsFromJSON string
+	labelsOutput   string
+	labelsDryRun   bool
+	labelsFind     []string
+	labelsPage     []int
+	labelsInfer    bool
+	labelsYes      bool
+)
+
+var labelsCmd = &cobra.Command{
+	Use:   "labels <input.pdf>",
+	Short: "Show, translate, set or remove page labels (printed page numbers)",
+	Long: fmt.Sprintf(`Show the label ranges and the label of every page, translate between
+labels and physical page numbers, or set and remove label ranges.
+Without -o, the file is updated in-place.
+
+%s
+  pdfed labels book.pdf
+  pdfed labels book.pdf --label xii --label 12
+  pdfed labels book.pdf --page 20 --json
+  pdfed labels book.pdf --range 1:r --range 9:D
+  pdfed labels book.pdf --range 1:none:Cover --range 2:r --range 10:D
+  pdfed labels book.pdf --range 301:D:A-:1 -o fixed.pdf
+  pdfed labels book.pdf --unset 301
+  pdfed labels book.pdf --from-json labels.json --dry-run
+  pdfed labels scan.pdf --infer
+  pdfed labels scan.pdf --infer --range 1:none:Cover --yes
+  pdfed labels book.pdf --remove
+
+%s
+  A range is FIRST:STYLE[:PREFIX[:START]] and runs from physical page FIRST
+  up to the next range. STYLE is D (decimal), r / R (roman), a / A (letters)
+  or none (prefix only); decimal, roman, ROMAN, letters and LETTERS work too.
+  START is the number of the first page in the range (default 1).
+
+  --range adds or replaces the range starting at FIRST and keeps the others;
+  combine it with --remove to start from scratch. --from-json replaces all
+  labels with an array like [{"page":1,"style":"r"},{"page":9,"style":"D"}]
+  (fields page, style, prefix, start). The first page always gets a range.
+  Changes are appended as an incremental update.
+
+  --label prints the physical page(s) carrying a label and --page prints the
+  label of a physical page. Without page labels, every page's label is its
+  physical number. Labels must match exactly; a label that is not found
+  fails with a list of similar labels.
+
+  --infer reads the page numbers printed in the first and last lines of
+  each page's text and proposes roman and decimal ranges. --range and
+  --unset adjust the proposal. It is written after confirmation, or straight
+  away with --yes; without a terminal and without --yes it is only shown.`,
+		bold("Examples:"), bold("Notes:")),
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return runLabels(args[0])
+	},
+}
+
+func init() {
+	labelsCmd.Flags().StringArrayVarP(&labelsRanges, "range", "r", nil, "Set a label range FIRST:STYLE[:PREFIX[:START]] (repeatable)")
+	labelsCmd.Flags().IntSliceVar(&labelsUnset, "unset", nil, "Remove the range starting at this physical page (repeatable)")
+	labelsCmd.Flags().BoolVar(&labelsRemove, "remove", false, "Remove all page labels")
+	labelsCmd.Flags().StringVar(&labelsFromJSON, "from-json", "", "Replace all labels with ranges from a JSON file (- for stdin)")
+	labelsCmd.Flags().StringVarP(&labelsOutput, "output", "o", "", "Output file (default: in-place)")
+	labelsCmd.Flags().BoolVarP(&labelsDryRun, "dry-run", "n", false, "Preview without writing")
+	labelsCmd.Flags().StringArrayVar(&labelsFind, "label", nil, "Print the physical page(s) with this label (repeatable)")
+	labelsCmd.Flags().IntSliceVar(&labelsPage, "page", nil, "Print the label of this physical page (repeatable)")
+	labelsCmd.Flags().BoolVar(&labelsInfer, "infer", false, "Propose labels from the page numbers printed on the pages")
+	labelsCmd.Flags().BoolVarP(&labelsYes, "yes", "y", false, "Write inferred labels without asking")
+	rootCmd.AddCommand(labelsCmd)
+}
+
+// labelStyleAliases maps the accepted style spellings to PDF style codes.
+// Lookups are exact first, so "roman" and "ROMAN" stay distinct.
+var labelStyleAliases = map[string]string{
+	"D": "D", "decimal": "D", "arabic": "D",
+	"r": "r", "roman": "r",
+	"R": "R", "ROMAN": "R",
+	"a": "a", "letters": "a",
+	"A": "A", "LETTERS": "A",
+	"none": "", "": "",
+}
+
+var labelStyleNames = map[string]string{
+	"D": "decimal", "r": "roman", "R": "ROMAN", "a": "letters", "A": "LETTERS", "": "none",
+}
+
+func parseLabelStyle(s string) (string, error) {
+	if code, ok := labelStyleAliases[strings.TrimSpace(s)]; ok {
+		return code, nil
+	}
+	if code, ok := labelStyleAliases[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return code, nil
+	}
+	return "", fmt.Errorf("unknown label style %q (use D, r, R, a, A or none)", s)
+}
+
+// labelRangeJSON is one range in --from-json input and --json output.
+type labelRangeJSON struct {
+	Page   int    `json:"page"`
+	Style  string `json:"style"`
+	Prefix string `json:"prefix,omitempty"`
+	Start  int    `json:"start,omitempty"`
+}
+
+func (r labelRangeJSON) entry(pageCount int) (pageLabelEntry, error) {
+	style, err := parseLabelStyle(r.Style)
+	if err != nil {
+		return pageLabelEntry{}, err
+	}
+	if r.Page < 1 || r.Page > pageCount {
+		return pageLabelEntry{}, fmt.Errorf("label range page %d is out of range (document has %d pages)", r.Page, pageCount)
+	}
+	start := r.Start
+	if start == 0 {
+		start = 1
+	}
+	if start < 1 {
+		return pageLabelEntry{}, fmt.Errorf("label range at page %d: start must be at least 1", r.Page)
+	}
+	return pageLabelEntry{startIndex: r.Page - 1, style: style, prefix: r.Prefix, startValue: start}, nil
+}
+
+func labelRangesJSON(entries []pageLabelEntry) []labelRangeJSON {
+	out := make([]labelRangeJSON, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, labelRangeJSON{Page: e.startIndex + 1, Style: e.style, Prefix: e.prefix, Start: e.startValue})
+	}
+	return out
+}
+
+// parseLabelRange parses FIRST:STYLE[:PREFIX[:START]].
+func parseLabelRange(spec string, pageCount int) (pageLabelEntry, error) {
+	parts := strings.SplitN(spec, ":", 4)
+	if len(parts) < 2 {
+		return pageLabelEntry{}, fmt.Errorf("--range expects FIRST:STYLE[:PREFIX[:START]], got %q", spec)
+	}
+	page, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return pageLabelEntry{}, fmt.Errorf("invalid first page in --range %q", spec)
+	}
+	r := labelRangeJSON{Page: page, Style: parts[1]}
+	if len(parts) > 2 {
+		r.Prefix = parts[2]
+	}
+	if len(parts) > 3 {
+		if r.Start, err = strconv.Atoi(strings.TrimSpace(parts[3])); err != nil || r.Start < 1 {
+			return pageLabelEntry{}, fmt.Errorf("invalid start value in --range %q", spec)
+		}
+	}
+	return r.entry(pageCount)
+}
+
+// pageLabelsTree builds a PageLabels number tree for entries, which must be
+// sorted by start page.
+func pageLabelsTree(entries []pageLabelEntry) (types.Dict, error) {
+	nums := types.Array{}
+	for _, e := range entries {
+		d := types.NewDict()
+		if e.style != "" {
+			d.Insert("S", types.Name(e.style))
+		}
+		if e.prefix != "" {
+			p, err := encodeInfoString(e.prefix)
+			if err != nil {
+				return nil, err
+			}
+			d.Insert("P", types.StringLiteral(p))
+		}
+		if e.startValue != 1 {
+			d.Insert("St", types.Integer(e.startValue))
+		}
+		nums = append(nums, types.Integer(e.startIndex), d)
+	}
+	return types.Dict{"Nums": nums}, nil
+}
+
+func runLabels(inFile string) error {
+	ctx, err := readContext(inFile, pdfConfig())
+	if err != nil {
+		return err
+	}
+	current, err := extractLabelEntries(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to parse page labels: %w", err)
+	}
+
+	editing := len(labelsRanges) > 0 || len(labelsUnset) > 0 || labelsRemove || labelsFromJSON != "" || labelsInfer
+	if len(labelsFind) > 0 || len(labelsPage) > 0 {
+		if editing {
+			return fmt.Errorf("--label and --page cannot be combined with --range, --unset, --remove, --from-json or --infer")
+		}
+		return translateLabels(inFile, current, ctx.PageCount)
+	}
+	if !editing {
+		return showLabels(inFile, current, ctx.PageCount)
+	}
+
+	base := current
+	var inf *labelInference
+	if labelsInfer {
+		if labelsRemove || labelsFromJSON != "" {
+			return fmt.Errorf("--infer cannot be combined with --remove or --from-json")
+		}
+		printInfo(fmt.Sprintf("Reading printed page numbers in %s…", inFile))
+		texts, err := pageTexts(ctx, inFile)
+		if err != nil {
+			return err
+		}
+		if inf, err = inferPageLabels(texts, ctx.PageCount); err != nil {
+			return err
+		}
+		printLabelInference(inFile, inf, ctx.PageCount)
+		base = inf.entries()
+	}
+	entries, err := editLabelEntries(base, ctx.PageCount)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 && entries[0].startIndex != 0 {
+		// The number tree must cover the first page.
+		entries = append([]pageLabelEntry{{startIndex: 0, style: "D", startValue: 1}}, entries...)
+		printWarning("No range starts at page 1; pages before the first range are numbered 1, 2, …")
+	}
+
+	out := labelsOutput
+	if out == "" {
+		out = inFile
+	}
+	labels := pageLabelStrings(entries, ctx.PageCount)
+	fields := map[string]interface{}{
+		"input":    inFile,
+		"output":   out,
+		"in_place": out == inFile,
+		"ranges":   labelRangesJSON(entries),
+		"labels":   labels,
+	}
+	if inf != nil {
+		fields["inferred"] = inferredRangesJSON(inf)
+		fields["mismatches"] = append([]labelMismatch{}, inf.mismatches...)
+	}
+	if sameLabelEntries(current, entries) {
+		printInfo("Page labels already up to date — nothing to change")
+		if jsonOut {
+			fields["changed"] = false
+			fields["dry_run"] = labelsDryRun
+			return jsonResultOK("labels", fields)
+		}
+		return nil
+	}
+	fields["changed"] = true
+
+	printInfo(fmt.Sprintf("Setting page labels in %s…", inFile))
+	printPageLabels(labels)
+	if labelsDryRun {
+		printInfo(fmt.Sprintf("[dry-run] would write %d label range(s) to %s", len(entries), out))
+		if jsonOut {
+			fields["dry_run"] = true
+			return jsonResultOK("labels", fields)
+		}
+		return nil
+	}
+	if inf != nil && !labelsYes {
+		ok, asked := confirm(fmt.Sprintf("Write these page labels to %s?", out))
+		if !ok {
+			if !asked {
+				printInfo("Proposal only; re-run with --yes to write it")
+			}
+			if jsonOut {
+				fields["dry_run"] = true
+				return jsonResultOK("labels", fields)
+			}
+			return nil
+		}
+	}
+
+	data, err := writePageLabels(ctx, inFile, entries)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(out, data); err != nil {
+		return err
+	}
+	// Re-read the result so a broken update never goes unnoticed.
+	check, err := readContext(out, pdfConfig())
+	if err != nil {
+		return fmt.Errorf("written file does not read back: %w", err)
+	}
+	if got, err := extractLabelEntries(check); err != nil || !sameLabelEntries(got, entries) {
+		return fmt.Errorf("written page labels do not read back as expected")
+	}
+
+	if len(entries) == 0 {
+		printSuccess(fmt.Sprintf("Removed page labels: %s", out))
+	} else {
+		printSuccess(fmt.Sprintf("Wrote %d label range(s): %s", len(entries), out))
+	}
+	if jsonOut {
+		fields["size_bytes"] = len(data)
+		return jsonResultOK("labels", fields)
+	}
+	return nil
+}
+
+// editLabelEntries applies --from-json, --remove, --unset and --range, in that order.
+func editLabelEntries(current []pageLabelEntry, pageCount int) ([]pageLabelEntry, error) {
+	byStart := map[int]pageLabelEntry{}
+	for _, e := range current {
+		byStart[e.startIndex] = e
+	}
+
+	if labelsFromJSON != "" {
+		var r io.Reader = os.Stdin
+		if labelsFromJSON != "-" {
+			f, err := os.Open(labelsFromJSON)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			r = f
+		}
+		var spec []labelRangeJSON
+		if err := json.NewDecoder(r).Decode(&spec); err != nil {
+			return nil, fmt.Errorf("invalid label JSON (expected an array of {page, style, prefix, start}): %w", err)
+		}
+		byStart = map[int]pageLabelEntry{}
+		for _, s := range spec {
+			e, err := s.entry(pageCount)
+			if err != nil {
+				return nil, err
+			}
+			byStart[e.startIndex] = e
+		}
+	}
+	if labelsRemove {
+		byStart = map[int]pageLabelEntry{}
+	}
+	for _, p := range labelsUnset {
+		if _, ok := byStart[p-1]; !ok {
+			return nil, fmt.Errorf("no label range starts at page %d", p)
+		}
+		delete(byStart, p-1)
+	}
+	for _, spec := range labelsRanges {
+		e, err := parseLabelRange(spec, pageCount)
+		if err != nil {
+			return nil, err
+		}
+		byStart[e.startIndex] = e
+	}
+
+	entries := make([]pageLabelEntry, 0, len(byStart))
+	for _, e := range byStart {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].startIndex < entries[j].startIndex })
+	return entries, nil
+}
+
+func sameLabelEntries(a, b []pageLabelEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pageLabelStrings is generateLabels, except that a document without
+// labels gets an empty list rather than the physical numbers.
+func pageLabelStrings(entries []pageLabelEntry, pageCount int) []string {
+	if len(entries) == 0 {
+		return []string{}
+	}
+	return generateLabels(entries, pageCount)
+}
+
+// writePageLabels returns inFile with an incremental update that replaces
+// the catalog's PageLabels tree (or removes it when entries is empty).
+func writePageLabels(ctx *model.Context, inFile string, entries []pageLabelEntry) ([]byte, error) {
+	root := ctx.RootDict.Clone().(types.Dict)
+	delete(root, "PageLabels")
+	if len(entries) > 0 {
+		tree, err := pageLabelsTree(entries)
+		if err != nil {
+			return nil, err
+		}
+		root["PageLabels"] = tree
+	}
+	src, err := os.ReadFile(inFile)
+	if err != nil {
+		return nil, err
+	}
+	return appendIncrementalUpdate(ctx, src, []pdfObjectUpdate{{
+		nr:   ctx.Root.ObjectNumber.Value(),
+		body: []byte(root.PDFString()),
+	}}, nil)
+}
+
+func showLabels(inFile string, entries []pageLabelEntry, pageCount int) error {
+	labels := pageLabelStrings(entries, pageCount)
+	if jsonOut {
+		return jsonResultOK("labels", map[string]interface{}{
+			"input":  inFile,
+			"pages":  pageCount,
+			"ranges": labelRangesJSON(entries),
+			"labels": labels,
+		})
+	}
+	fmt.Println()
+	fmt.Println(bold(" Page labels") + "  " + dimStyle.Render(inFile))
+	fmt.Println(strings.Repeat("─", 50))
+	if len(entries) == 0 {
+		fmt.Println("  " + dimStyle.Render("No page labels; pages are numbered 1 to "+strconv.Itoa(pageCount)+"."))
+		fmt.Println()
+		return nil
+	}
+	printLabelRanges(entries, labels)
+	printPageLabels(labels)
+	return nil
+}
+
+// printLabelRanges lists each range with the physical pages it covers and
+// its first and last label.
+func printLabelRanges(entries []pageLabelEntry, labels []string) {
+	fmt.Printf("  %s\n", bold(fmt.Sprintf("%-9s  %-8s  %-10s  %-5s  %s", "PAGES", "STYLE", "PREFIX", "START", "LABELS")))
+	for i, e := range entries {
+		end := len(labels)
+		if i+1 < len(entries) {
+			end = entries[i+1].startIndex
+		}
+		if e.startIndex >= end {
+			continue
+		}
+		pages := strconv.Itoa(e.startIndex + 1)
+		span := labels[e.startIndex]
+		if end-1 > e.startIndex {
+			pages += "-" + strconv.Itoa(end)
+			span += " … " + labels[end-1]
+		}
+		prefix := e.prefix
+		if prefix == "" {
+			prefix = "-"
+		}
+		fmt.Printf("  %-9s  %-8s  %-10s  %-5d  %s\n", pages, labelStyleNames[e.style], prefix, e.startValue, span)
+	}
+	fmt.Println()
+}
+
+// labelTranslation is the answer to one --label or --page query.
+type labelTranslation struct {
+	Label   string   `json:"label"`
+	Page    int      `json:"page,omitempty"`
+	Pages   []int    `json:"pages,omitempty"`
+	Similar []string `json:"similar,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// translateLabels answers --label and --page queries in either direction,
+// failing if any label or page does not exist.
+func translateLabels(inFile string, entries []pageLabelEntry, pageCount int) error {
+	labels := generateLabels(entries, pageCount)
+	byLabel := map[string][]int{}
+	for i, l := range labels {
+		byLabel[l] = append(byLabel[l], i+1)
+	}
+
+	var results []labelTranslation
+	missing := 0
+	for _, l := range labelsFind {
+		t := labelTranslation{Label: l, Pages: byLabel[l]}
+		if len(t.Pages) == 0 {
+			t.Similar = similarLabels(l, labels)
+			t.Error = "label not found"
+			missing++
+		}
+		results = append(results, t)
+	}
+	for _, p := range labelsPage {
+		t := labelTranslation{Page: p}
+		if p < 1 || p > pageCount {
+			t.Error = fmt.Sprintf("page out of range (document has %d pages)", pageCount)
+			missing++
+		} else {
+			t.Label = labels[p-1]
+		}
+		results = append(results, t)
+	}
+
+	if !jsonOut && !quiet {
+		fmt.Println()
+		for _, t := range results {
+			switch {
+			case t.Page > 0 && t.Error != "":
+				fmt.Printf("  page %-6d %s\n", t.Page, red(t.Error))
+			case t.Page > 0:
+				fmt.Printf("  page %-6d → label %q\n", t.Page, t.Label)
+			case t.Error != "":
+				msg := t.Error
+				if len(t.Similar) > 0 {
+					msg += "; similar: " + strings.Join(t.Similar, ", ")
+				}
+				fmt.Printf("  label %-5q %s\n", t.Label, red(msg))
+			case len(t.Pages) == 1:
+				fmt.Printf("  label %-5q → page %d\n", t.Label, t.Pages[0])
+			default:
+				fmt.Printf("  label %-5q → pages %s %s\n", t.Label, joinInts(t.Pages),
+					yellow(fmt.Sprintf("(label used %d times)", len(t.Pages))))
+			}
+		}
+		fmt.Println()
+	}
+
+	fields := map[string]interface{}{
+		"input":        inFile,
+		"pages":        pageCount,
+		"labelled":     len(entries) > 0,
+		"translations": results,
+	}
+	if missing > 0 {
+		return &jsonFailure{"labels", fields,
+			fmt.Errorf("%d label(s) or page(s) not found; run 'pdfed labels %s' to list all labels", missing, inFile)}
+	}
+	if jsonOut {
+		return jsonResultOK("labels", fields)
+	}
+	return nil
+}
+
+// similarLabels suggests labels that differ from l only in case or
+// surrounding text, such as "iv" for "IV" or "A-4" for "4".
+func similarLabels(l string, labels []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	lower := strings.ToLower(strings.TrimSpace(l))
+	for _, c := range labels {
+		if seen[c] || c == "" {
+			continue
+		}
+		lc := strings.ToLower(c)
+		if lc == lower || (lower != "" && (strings.HasSuffix(lc, lower) || strings.HasPrefix(lc, lower))) {
+			seen[c] = true
+			out = append(out, c)
+			if len(out) == 5 {
+				break
+			}
+		}
+	}
+	return out
+}
+
+func joinInts(ns []int) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ", ")
+}
+
+// printPageLabels lists the label of every physical page.
+func printPageLabels(labels []string) {
+	if quiet {
+		return
+	}
+	if len(labels) == 0 {
+		fmt.Println("  " + dimStyle.Render("No page labels."))
+		fmt.Println()
+		return
+	}
+	w := len(strconv.Itoa(len(labels)))
+	for i, l := range labels {
+		if l == "" {
+			l = dimStyle.Render("(empty)")
+		}
+		fmt.Printf("  %s  %s\n", dimStyle.Render(fmt.Sprintf("%*d", w, i+1)), l)
+	}
+	fmt.Println()
+}
+
+// confirm asks a yes/no question on the terminal. asked is false when there
+// is no terminal to ask on (or in --json mode), in which case the answer is no.
+func confirm(question string) (ok, asked bool) {
+	if jsonOut {
+		return false, false
+	}
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		return false, false
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	var answer string
+	fmt.Scanln(&answer)
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", true
+}
@@ -0,0 +1,807 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Linearized ("fast web view") files, PDF 1.7 annex F. The file starts with
+// everything needed to show the first page, followed by the other pages in
+// order, the objects pages share, and everything else. A hint stream tells
+// viewers where each page's objects are, so they can fetch pages by byte
+// range before the whole file has arrived:
+//
+//	header
+//	linearization dictionary
+//	first-page cross-reference table and trailer
+//	catalog
+//	primary hint stream
+//	first page and the objects it uses
+//	each further page with the objects only it uses
+//	objects used by several pages
+//	other objects (page tree, outlines, info, …)
+//	main cross-reference table and trailer
+//
+// Offsets in the hint tables are given as if the hint stream were absent.
+
+// linearLayout assigns the objects of a document to the parts of a
+// linearized file.
+type linearLayout struct {
+	pages     []int   // page object numbers in page order
+	firstPage []int   // page 1 and every object it uses
+	pageObjs  [][]int // pages 2… : the page and the objects only it uses
+	shared    []int   // objects used by several pages, none of them page 1
+	other     []int   // everything else reachable from the trailer
+	sharedRef [][]int // per page, indexes into the shared object hint table
+}
+
+// objectRefs returns the object numbers o refers to directly.
+func objectRefs(o types.Object) []int {
+	var out []int
+	var walk func(o types.Object)
+	walk = func(o types.Object) {
+		switch v := o.(type) {
+		case types.IndirectRef:
+			out = append(out, v.ObjectNumber.Value())
+		case types.Dict:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(v[k])
+			}
+		case types.Array:
+			for _, e := range v {
+				walk(e)
+			}
+		case types.StreamDict:
+			walk(v.Dict)
+		}
+	}
+	walk(o)
+	return out
+}
+
+func tableObject(ctx *model.Context, nr int) (types.Object, bool) {
+	e, ok := ctx.Table[nr]
+	if !ok || e == nil || e.Free {
+		return nil, false
+	}
+	// Objects from object streams are parsed on first use.
+	o, err := ctx.Dereference(*types.NewIndirectRef(nr, *e.Generation))
+	if err != nil {
+		return nil, false
+	}
+	return o, true
+}
+
+// isPageTreeNode reports whether o is a page or an intermediate page tree node.
+func isPageTreeNode(o types.Object) bool {
+	d, ok := o.(types.Dict)
+	if !ok {
+		return false
+	}
+	t := d.Type()
+	return t != nil && (*t == "Page" || *t == "Pages")
+}
+
+// reachableFrom lists, in depth-first order, the objects reachable from
+// start without passing through the objects for which stop returns true.
+func reachableFrom(ctx *model.Context, start int, stop func(nr int, o types.Object) bool) []int {
+	seen := map[int]bool{}
+	var out []int
+	var visit func(nr int)
+	visit = func(nr int) {
+		if seen[nr] {
+			return
+		}
+		seen[nr] = true
+		o, ok := tableObject(ctx, nr)
+		if !ok || (nr != start && stop(nr, o)) {
+			return
+		}
+		out = append(out, nr)
+		for _, r := range objectRefs(o) {
+			visit(r)
+		}
+	}
+	visit(start)
+	return out
+}
+
+func newLinearLayout(ctx *model.Context) (*linearLayout, error) {
+	if ctx.Root == nil {
+		return nil, fmt.Errorf("document has no catalog")
+	}
+	root := ctx.Root.ObjectNumber.Value()
+	l := &linearLayout{}
+	for nr := 1; nr <= ctx.PageCount; nr++ {
+		_, ref, _, err := ctx.PageDict(nr, false)
+		if err != nil {
+			return nil, err
+		}
+		if ref == nil {
+			return nil, fmt.Errorf("page %d is not an indirect object", nr)
+		}
+		l.pages = append(l.pages, ref.ObjectNumber.Value())
+	}
+	if len(l.pages) == 0 {
+		return nil, fmt.Errorf("document has no pages")
+	}
+
+	// What each page uses, not crossing into other pages, the page tree
+	// or the catalog (annotations and links point back at them).
+	stop := func(nr int, o types.Object) bool { return nr == root || isPageTreeNode(o) }
+	users := map[int][]int{}
+	uses := make([][]int, len(l.pages))
+	for i, p := range l.pages {
+		uses[i] = reachableFrom(ctx, p, stop)
+		for _, nr := range uses[i] {
+			users[nr] = append(users[nr], i)
+		}
+	}
+
+	placed := map[int]bool{root: true}
+	l.firstPage = uses[0]
+	for _, nr := range l.firstPage {
+		placed[nr] = true
+	}
+	l.pageObjs = make([][]int, len(l.pages)-1)
+	for i := 1; i < len(l.pages); i++ {
+		for _, nr := range uses[i] {
+			if !placed[nr] && len(users[nr]) == 1 {
+				l.pageObjs[i-1] = append(l.pageObjs[i-1], nr)
+				placed[nr] = true
+			}
+		}
+	}
+	for i := 1; i < len(l.pages); i++ {
+		for _, nr := range uses[i] {
+			if !placed[nr] {
+				l.shared = append(l.shared, nr)
+				placed[nr] = true
+			}
+		}
+	}
+
+	starts := []int{root}
+	if ctx.Info != nil {
+		starts = append(starts, ctx.Info.ObjectNumber.Value())
+	}
+	for _, s := range starts {
+		for _, nr := range reachableFrom(ctx, s, func(int, types.Object) bool { return false }) {
+			if !placed[nr] {
+				l.other = append(l.other, nr)
+				placed[nr] = true
+			}
+		}
+	}
+
+	// Shared object hint table: every object of the first page, then the
+	// shared objects section.
+	index := map[int]int{}
+	for i, nr := range l.firstPage {
+		index[nr] = i
+	}
+	for i, nr := range l.shared {
+		index[nr] = len(l.firstPage) + i
+	}
+	l.sharedRef = make([][]int, len(l.pages))
+	for i := 1; i < len(l.pages); i++ {
+		for _, nr := range uses[i] {
+			if len(users[nr]) > 1 {
+				l.sharedRef[i] = append(l.sharedRef[i], index[nr])
+			}
+		}
+	}
+	return l, nil
+}
+
+// renumberObject returns a copy of o with its references renumbered.
+// References to objects that are not written become null.
+func renumberObject(o types.Object, nums map[int]int) types.Object {
+	switch v := o.(type) {
+	case types.IndirectRef:
+		if n, ok := nums[v.ObjectNumber.Value()]; ok {
+			return *types.NewIndirectRef(n, 0)
+		}
+		return nil
+	case types.Dict:
+		d := types.Dict{}
+		for k, e := range v {
+			d[k] = renumberObject(e, nums)
+		}
+		return d
+	case types.Array:
+		a := make(types.Array, len(v))
+		for i, e := range v {
+			a[i] = renumberObject(e, nums)
+		}
+		return a
+	}
+	return o
+}
+
+func pdfString(o types.Object) string {
+	if o == nil {
+		return "null"
+	}
+	return o.PDFString()
+}
+
+// serializeObject writes object nr with its references renumbered.
+func serializeObject(ctx *model.Context, old, nr int, nums map[int]int) ([]byte, error) {
+	o, _ := tableObject(ctx, old)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d 0 obj\n", nr)
+	if sd, ok := o.(types.StreamDict); ok {
+		if sd.Raw == nil && sd.Content != nil {
+			if err := sd.Encode(); err != nil {
+				return nil, fmt.Errorf("object %d: %w", old, err)
+			}
+		}
+		d := renumberObject(sd.Dict, nums).(types.Dict)
+		d["Length"] = types.Integer(len(sd.Raw))
+		b.WriteString(d.PDFString())
+		b.WriteString("\nstream\n")
+		b.Write(sd.Raw)
+		b.WriteString("\nendstream")
+	} else {
+		b.WriteString(pdfString(renumberObject(o, nums)))
+	}
+	b.WriteString("\nendobj\n")
+	return b.Bytes(), nil
+}
+
+// bitsFor returns how many bits it takes to store values up to v.
+func bitsFor(v int) int {
+	n := 0
+	for ; v > 0; v >>= 1 {
+		n++
+	}
+	return n
+}
+
+func minMax(vals []int) (int, int) {
+	lo, hi := vals[0], vals[0]
+	for _, v := range vals[1:] {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	return lo, hi
+}
+
+// linearHints holds the per-page and per-shared-object values the hint
+// tables describe, with offsets as if the hint stream were absent.
+type linearHints struct {
+	firstPageOffset int
+	pageObjects     []int
+	pageLengths     []int
+	sharedRefs      [][]int
+	firstShared     int // object number of the first object in the shared section, or 0
+	firstSharedOff  int
+	firstPageShared int // shared table entries for the first page's objects
+	groupLengths    []int
+}
+
+// hintStream encodes the page offset and shared object hint tables. It
+// returns the stream data and the offset of the shared object table in it.
+func (h *linearHints) hintStream() ([]byte, int) {
+	var w bitWriter
+	column := func(vals []int, base, bits int) {
+		for _, v := range vals {
+			w.writeBits(uint64(v-base), bits)
+		}
+		w.bytes()
+	}
+
+	// Page offset hint table (tables F.3 and F.4).
+	minObjs, maxObjs := minMax(h.pageObjects)
+	minLen, maxLen := minMax(h.pageLengths)
+	maxShared, maxID := 0, 0
+	for _, refs := range h.sharedRefs {
+		maxShared = max(maxShared, len(refs))
+		for _, id := range refs {
+			maxID = max(maxID, id)
+		}
+	}
+	objBits, lenBits := bitsFor(maxObjs-minObjs), bitsFor(maxLen-minLen)
+	sharedBits, idBits := bitsFor(maxShared), bitsFor(maxID)
+	w.writeBits(uint64(minObjs), 32)
+	w.writeBits(uint64(h.firstPageOffset), 32)
+	w.writeBits(uint64(objBits), 16)
+	w.writeBits(uint64(minLen), 32)
+	w.writeBits(uint64(lenBits), 16)
+	w.writeBits(0, 32) // least content stream offset: pages are described as a whole
+	w.writeBits(0, 16)
+	w.writeBits(uint64(minLen), 32) // least content stream length
+	w.writeBits(uint64(lenBits), 16)
+	w.writeBits(uint64(sharedBits), 16)
+	w.writeBits(uint64(idBits), 16)
+	w.writeBits(0, 16) // bits for the fractional position numerator
+	w.writeBits(1, 16) // its denominator
+	column(h.pageObjects, minObjs, objBits)
+	column(h.pageLengths, minLen, lenBits)
+	counts := make([]int, len(h.sharedRefs))
+	var ids []int
+	for i, refs := range h.sharedRefs {
+		counts[i] = len(refs)
+		ids = append(ids, refs...)
+	}
+	column(counts, 0, sharedBits)
+	column(ids, 0, idBits)
+	column(nil, 0, 0) // numerators
+	column(make([]int, len(h.pageLengths)), 0, 0)
+	column(h.pageLengths, minLen, lenBits)
+	sharedAt := len(w.bytes())
+
+	// Shared object hint table (tables F.5 and F.6), one object per group.
+	minGroup, maxGroup := 0, 0
+	if len(h.groupLengths) > 0 {
+		minGroup, maxGroup = minMax(h.groupLengths)
+	}
+	groupBits := bitsFor(maxGroup - minGroup)
+	w.writeBits(uint64(h.firstShared), 32)
+	w.writeBits(uint64(h.firstSharedOff), 32)
+	w.writeBits(uint64(h.firstPageShared), 32)
+	w.writeBits(uint64(len(h.groupLengths)), 32)
+	w.writeBits(0, 16) // bits for objects per group - 1
+	w.writeBits(uint64(minGroup), 32)
+	w.writeBits(uint64(groupBits), 16)
+	column(h.groupLengths, minGroup, groupBits)
+	column(make([]int, len(h.groupLengths)), 0, 1) // no MD5 signatures
+	return w.bytes(), sharedAt
+}
+
+// linearize writes ctx as a linearized file.
+func linearize(ctx *model.Context) ([]byte, error) {
+	if ctx.Encrypt != nil {
+		return nil, fmt.Errorf("linearizing encrypted files is not supported; decrypt first")
+	}
+	l, err := newLinearLayout(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Number the main section 1…m-1 in file order; the first-page section
+	// (linearization dict, catalog, hint stream, first page) follows.
+	nums := map[int]int{}
+	next := 1
+	var mainOrder []int
+	add := func(nrs ...int) {
+		for _, nr := range nrs {
+			nums[nr] = next
+			next++
+			mainOrder = append(mainOrder, nr)
+		}
+	}
+	for _, objs := range l.pageObjs {
+		add(objs...)
+	}
+	add(l.shared...)
+	add(l.other...)
+	m := next
+	linNr, rootNr, hintNr := m, m+1, m+2
+	nums[ctx.Root.ObjectNumber.Value()] = rootNr
+	for i, nr := range l.firstPage {
+		nums[nr] = hintNr + 1 + i
+	}
+	size := hintNr + 1 + len(l.firstPage)
+
+	ser := map[int][]byte{}
+	for old := range nums {
+		if ser[old], err = serializeObject(ctx, old, nums[old], nums); err != nil {
+			return nil, err
+		}
+	}
+
+	// Fixed-width placeholders keep the sizes of the front matter known
+	// before the offsets that go into it.
+	header := fmt.Sprintf("%%PDF-%s\n%%\xe2\xe3\xcf\xd3\n", ctx.XRefTable.Version())
+	linDict := func(L, h0, h1, E, T int) string {
+		return fmt.Sprintf("%d 0 obj\n<< /Linearized 1 /L %10d /H [ %10d %10d ] /O %d /E %10d /N %d /T %10d >>\nendobj\n",
+			linNr, L, h0, h1, nums[l.pages[0]], E, len(l.pages), T)
+	}
+	id := pdfString(ctx.ID)
+	if len(ctx.ID) == 0 {
+		h := md5.New()
+		for _, nr := range l.firstPage {
+			h.Write(ser[nr])
+		}
+		sum := h.Sum(nil)
+		id = fmt.Sprintf("[<%x> <%x>]", sum, sum)
+	}
+	info := ""
+	if ctx.Info != nil {
+		if n, ok := nums[ctx.Info.ObjectNumber.Value()]; ok {
+			info = fmt.Sprintf(" /Info %d 0 R", n)
+		}
+	}
+	firstXref := func(offsets []int, prev int) string {
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "xref\n%d %d\n", linNr, size-linNr)
+		for _, off := range offsets {
+			fmt.Fprintf(&b, "%010d 00000 n \n", off)
+		}
+		fmt.Fprintf(&b, "trailer\n<< /Size %d /Root %d 0 R%s /ID %s /Prev %10d >>\nstartxref\n0\n%%%%EOF\n", size, rootNr, info, id, prev)
+		return b.String()
+	}
+
+	// Lay everything out with the hint stream left out, as hint offsets
+	// are measured.
+	frontLen := len(header) + len(linDict(0, 0, 0, 0, 0)) + len(firstXref(make([]int, size-linNr), 0))
+	pos := frontLen
+	offset := map[int]int{} // adjusted offsets by old object number
+	place := func(nrs ...int) {
+		for _, nr := range nrs {
+			offset[nr] = pos
+			pos += len(ser[nr])
+		}
+	}
+	place(ctx.Root.ObjectNumber.Value())
+	hintAt := pos
+	place(l.firstPage...)
+	endFirst := pos
+	for _, objs := range l.pageObjs {
+		place(objs...)
+	}
+	place(l.shared...)
+	place(l.other...)
+	mainXrefAt := pos
+
+	h := &linearHints{firstPageOffset: offset[l.pages[0]], firstPageShared: len(l.firstPage)}
+	pageLen := func(objs []int) int {
+		n := 0
+		for _, nr := range objs {
+			n += len(ser[nr])
+		}
+		return n
+	}
+	h.pageObjects = append(h.pageObjects, len(l.firstPage))
+	h.pageLengths = append(h.pageLengths, pageLen(l.firstPage))
+	for _, objs := range l.pageObjs {
+		h.pageObjects = append(h.pageObjects, len(objs))
+		h.pageLengths = append(h.pageLengths, pageLen(objs))
+	}
+	h.sharedRefs = l.sharedRef
+	if len(l.shared) > 0 {
+		h.firstShared, h.firstSharedOff = nums[l.shared[0]], offset[l.shared[0]]
+	}
+	for _, nr := range append(append([]int{}, l.firstPage...), l.shared...) {
+		h.groupLengths = append(h.groupLengths, len(ser[nr]))
+	}
+	data, sharedAt := h.hintStream()
+	hint := fmt.Sprintf("%d 0 obj\n<< /Length %d /S %d >>\nstream\n", hintNr, len(data), sharedAt)
+	hintObj := append(append([]byte(hint), data...), "\nendstream\nendobj\n"...)
+	hintLen := len(hintObj)
+
+	// Real offsets.
+	actual := func(off int) int {
+		if off >= hintAt {
+			return off + hintLen
+		}
+		return off
+	}
+	mainXrefAt = actual(mainXrefAt)
+	var main bytes.Buffer
+	fmt.Fprintf(&main, "xref\n0 %d\n", m)
+	tOffset := mainXrefAt + main.Len() - 1
+	main.WriteString("0000000000 65535 f \n")
+	for _, nr := range mainOrder {
+		fmt.Fprintf(&main, "%010d 00000 n \n", actual(offset[nr]))
+	}
+	firstXrefAt := len(header) + len(linDict(0, 0, 0, 0, 0))
+	fmt.Fprintf(&main, "trailer\n<< /Size %d >>\nstartxref\n%d\n%%%%EOF\n", m, firstXrefAt)
+	total := mainXrefAt + main.Len()
+
+	firstOffsets := []int{len(header), actual(offset[ctx.Root.ObjectNumber.Value()]), hintAt}
+	for _, nr := range l.firstPage {
+		firstOffsets = append(firstOffsets, actual(offset[nr]))
+	}
+
+	var out bytes.Buffer
+	out.Grow(total)
+	out.WriteString(header)
+	out.WriteString(linDict(total, hintAt, hintLen, actual(endFirst), tOffset))
+	out.WriteString(firstXref(firstOffsets, mainXrefAt))
+	out.Write(ser[ctx.Root.ObjectNumber.Value()])
+	out.Write(hintObj)
+	for _, nr := range l.firstPage {
+		out.Write(ser[nr])
+	}
+	for _, nr := range mainOrder {
+		out.Write(ser[nr])
+	}
+	out.Write(main.Bytes())
+	if out.Len() != total {
+		return nil, fmt.Errorf("internal error: linearized layout is %d bytes, expected %d", out.Len(), total)
+	}
+	return out.Bytes(), nil
+}
+
+// linearizeBytes rewrites a complete PDF as a linearized file.
+func linearizeBytes(data []byte) ([]byte, error) {
+	ctx, err := readContextFrom(bytes.NewReader(data), pdfConfig())
+	if err != nil {
+		return nil, err
+	}
+	return linearize(ctx)
+}
+
+// ── verification ──────────────────────────────────────────────────────────────
+
+// linearizationCheck is what info found when checking a linearized file
+// against its hint tables.
+type linearizationCheck struct {
+	Valid         bool     `json:"valid"`
+	Pages         int      `json:"pages"`
+	SharedObjects int      `json:"shared_objects"`
+	HintBytes     int      `json:"hint_stream_bytes"`
+	Problems      []string `json:"problems"`
+}
+
+var (
+	linDictRe = regexp.MustCompile(`(?s)^\s*\d+\s+\d+\s+obj\s*<<(.*?)>>`)
+	linKeyRe  = regexp.MustCompile(`/(L|O|E|N|T)\s+(\d+)`)
+	linHintRe = regexp.MustCompile(`/H\s*\[\s*(\d+)\s+(\d+)`)
+	// What /T points just before: a table's first entry or an xref stream.
+	mainXrefRe = regexp.MustCompile(`^\s*(0000000000 65535 f|\d+\s+\d+\s+obj\s*<<[^>]*/Type\s*/XRef)`)
+)
+
+type bitReader struct {
+	data []byte
+	pos  int // in bits
+	err  bool
+}
+
+func (r *bitReader) read(n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		if r.pos/8 >= len(r.data) {
+			r.err = true
+			return 0
+		}
+		v = v<<1 | int(r.data[r.pos/8]>>(7-uint(r.pos%8))&1)
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) align() { r.pos = (r.pos + 7) / 8 * 8 }
+
+func (r *bitReader) column(n, base, bits int) []int {
+	out := make([]int, n)
+	for i := range out {
+		out[i] = base + r.read(bits)
+	}
+	r.align()
+	return out
+}
+
+// checkLinearization verifies the linearization dictionary and hint tables
+// of the file in raw against the objects ctx read from it. It returns nil if
+// the file is not linearized.
+func checkLinearization(ctx *model.Context, raw []byte) *linearizationCheck {
+	head := raw[min(len(raw), bytes.Index(raw, []byte("\n"))+1):]
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	for len(head) > 0 && head[0] == '%' {
+		head = head[min(len(head), bytes.IndexByte(head, '\n')+1):]
+	}
+	dm := linDictRe.FindSubmatch(head)
+	if dm == nil || !bytes.Contains(dm[1], []byte("/Linearized")) {
+		return nil
+	}
+	c := &linearizationCheck{Pages: ctx.PageCount}
+	problem := func(format string, args ...interface{}) {
+		c.Problems = append(c.Problems, fmt.Sprintf(format, args...))
+	}
+	vals := map[string]int{}
+	for _, km := range linKeyRe.FindAllSubmatch(dm[1], -1) {
+		vals[string(km[1])], _ = strconv.Atoi(string(km[2]))
+	}
+	hm := linHintRe.FindSubmatch(dm[1])
+	if hm == nil {
+		problem("linearization dictionary has no /H entry")
+		return c
+	}
+	hintOff, _ := strconv.Atoi(string(hm[1]))
+	hintLen, _ := strconv.Atoi(string(hm[2]))
+	c.HintBytes = hintLen
+
+	if vals["L"] != len(raw) {
+		problem("/L says %d bytes but the file has %d (changed after linearizing?)", vals["L"], len(raw))
+	}
+	if vals["N"] != ctx.PageCount {
+		problem("/N says %d pages but the document has %d", vals["N"], ctx.PageCount)
+	}
+
+	// Object offsets and lengths. An object runs to the next object or
+	// cross-reference table.
+	offsets := map[int]int{}
+	var bounds []int
+	for nr, e := range ctx.Table {
+		if e != nil && !e.Free && !e.Compressed && e.Offset != nil && *e.Offset > 0 {
+			offsets[nr] = int(*e.Offset)
+			bounds = append(bounds, int(*e.Offset))
+		}
+	}
+	for i := 0; ; {
+		j := bytes.Index(raw[i:], []byte("xref"))
+		if j < 0 {
+			break
+		}
+		if p := i + j; p == 0 || raw[p-1] != 't' {
+			bounds = append(bounds, p)
+		}
+		i += j + 4
+	}
+	bounds = append(bounds, len(raw))
+	sort.Ints(bounds)
+	objLen := func(nr int) int {
+		off, ok := offsets[nr]
+		if !ok {
+			return -1
+		}
+		k := sort.SearchInts(bounds, off+1)
+		return bounds[k] - off
+	}
+	lengthOf := func(first, n int) int {
+		total := 0
+		for nr := first; nr < first+n; nr++ {
+			l := objLen(nr)
+			if l < 0 {
+				return -1
+			}
+			total += l
+		}
+		return total
+	}
+	adjust := func(off int) int {
+		if off >= hintOff {
+			return off - hintLen
+		}
+		return off
+	}
+
+	pages := make([]int, ctx.PageCount)
+	for i := range pages {
+		if _, ref, _, err := ctx.PageDict(i+1, false); err == nil && ref != nil {
+			pages[i] = ref.ObjectNumber.Value()
+		}
+	}
+	if len(pages) > 0 && vals["O"] != pages[0] {
+		problem("/O names object %d but the first page is object %d", vals["O"], pages[0])
+	}
+	if t := vals["T"]; t >= len(raw) || !mainXrefRe.Match(raw[t:min(len(raw), t+512)]) {
+		problem("/T (%d) does not point at the main cross-reference table", t)
+	}
+
+	// The hint stream.
+	var hintNr int
+	for nr, off := range offsets {
+		if off == hintOff {
+			hintNr = nr
+		}
+	}
+	o, _ := tableObject(ctx, hintNr)
+	sd, ok := o.(types.StreamDict)
+	if hintNr == 0 || !ok {
+		problem("no hint stream at offset %d", hintOff)
+		return c
+	}
+	if err := sd.Decode(); err != nil {
+		problem("hint stream cannot be decoded: %v", err)
+		return c
+	}
+	if objLen(hintNr) != hintLen {
+		problem("/H gives the hint stream %d bytes but it has %d", hintLen, objLen(hintNr))
+	}
+	sAt := sd.IntEntry("S")
+	if sAt == nil || *sAt > len(sd.Content) {
+		problem("hint stream has no valid /S entry")
+		return c
+	}
+
+	// Page offset hint table.
+	r := &bitReader{data: sd.Content}
+	minObjs, firstLoc, objBits := r.read(32), r.read(32), r.read(16)
+	minLen, lenBits := r.read(32), r.read(16)
+	r.read(32)
+	offBits := r.read(16)
+	r.read(32)
+	contentBits := r.read(16)
+	sharedBits, idBits, numBits := r.read(16), r.read(16), r.read(16)
+	r.read(16)
+	n := ctx.PageCount
+	nobjs := r.column(n, minObjs, objBits)
+	lens := r.column(n, minLen, lenBits)
+	nshared := r.column(n, 0, sharedBits)
+	var ids [][]int
+	for _, k := range nshared {
+		ids = append(ids, make([]int, k))
+		for j := range ids[len(ids)-1] {
+			ids[len(ids)-1][j] = r.read(idBits)
+		}
+	}
+	r.align()
+	for _, k := range nshared {
+		r.read(k * numBits)
+	}
+	r.align()
+	r.column(n, 0, offBits)
+	r.column(n, 0, contentBits)
+	if r.err {
+		problem("page offset hint table is truncated")
+		return c
+	}
+	if len(pages) > 0 && adjust(offsets[pages[0]]) != firstLoc {
+		problem("hint table puts page 1 at offset %d, but it is at %d", firstLoc, adjust(offsets[pages[0]]))
+	}
+	for i, p := range pages {
+		if got := lengthOf(p, nobjs[i]); got != lens[i] {
+			problem("page %d: hint table gives %d bytes in %d object(s), the file has %d", i+1, lens[i], nobjs[i], got)
+		}
+	}
+	if end := vals["E"]; len(pages) > 0 && end != offsets[pages[0]]+lengthOf(pages[0], nobjs[0]) {
+		problem("/E (%d) is not the end of the first page", end)
+	}
+
+	// Shared object hint table.
+	r = &bitReader{data: sd.Content, pos: *sAt * 8}
+	firstShared, firstSharedLoc := r.read(32), r.read(32)
+	firstPageEntries, totalEntries := r.read(32), r.read(32)
+	perGroupBits, minGroup, groupBits := r.read(16), r.read(32), r.read(16)
+	groupLens := r.column(totalEntries, minGroup, groupBits)
+	sigs := r.column(totalEntries, 0, 1)
+	for _, s := range sigs {
+		if s == 1 {
+			r.read(128)
+		}
+	}
+	groupObjs := r.column(totalEntries, 1, perGroupBits)
+	if r.err {
+		problem("shared object hint table is truncated")
+		return c
+	}
+	c.SharedObjects = totalEntries - firstPageEntries
+	if c.SharedObjects > 0 && adjust(offsets[firstShared]) != firstSharedLoc {
+		problem("hint table puts shared object %d at offset %d, but it is at %d", firstShared, firstSharedLoc, adjust(offsets[firstShared]))
+	}
+	cur := 0
+	if len(pages) > 0 {
+		cur = pages[0]
+	}
+	for i := 0; i < totalEntries; i++ {
+		if i == firstPageEntries {
+			cur = firstShared
+		}
+		if got := lengthOf(cur, groupObjs[i]); got != groupLens[i] {
+			problem("shared object group %d (object %d): hint table gives %d bytes, the file has %d", i, cur, groupLens[i], got)
+		}
+		cur += groupObjs[i]
+	}
+	for i, refs := range ids {
+		for _, id := range refs {
+			if id >= totalEntries {
+				problem("page %d refers to shared object group %d of %d", i+1, id, totalEntries)
+			}
+		}
+	}
+
+	if len(c.Problems) > 8 {
+		more := len(c.Problems) - 8
+		c.Problems = append(c.Problems[:8], fmt.Sprintf("… and %d more", more))
+	}
+	c.Valid = len(c.Problems) == 0
+	return c
+}
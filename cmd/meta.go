@@ -0,0 +1,536 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	metaSet      []string
+	metaClear    []string
+	metaFromJSON string
+	metaCopyFrom string
+	metaFields   string
+	metaOutput   string
+	metaDryRun   bool
+)
+
+var metaCmd = &cobra.Command{
+	Use:   "meta <input.pdf>",
+	Short: "Show or edit document metadata (Info dictionary and XMP)",
+	Long: fmt.Sprintf(`Show, set, clear or copy Info dictionary fields and custom keys.
+Without -o, the file is updated in-place. With no changes requested the
+current Info dictionary is printed.
+
+%s
+  pdfed meta report.pdf
+  pdfed meta report.pdf --set Title="Annual Report" --set Author="Jane Doe"
+  pdfed meta report.pdf --clear Keywords --clear Creator
+  pdfed meta report.pdf --set Course=CS101 -o tagged.pdf
+  pdfed meta report.pdf --from-json meta.json --dry-run
+  pdfed meta report.pdf --copy-from original.pdf --fields Title,Author
+
+%s
+  Standard keys: Title, Author, Subject, Keywords, Creator, Producer,
+  CreationDate, ModDate, Trapped. Any other key is stored as a custom key.
+  Dates accept PDF (D:20240131120000Z) or ISO (2024-01-31, 2024-01-31T12:00:00Z) form.
+  The JSON file is an object of key → string, or null to clear a key.
+  Sources apply in order --copy-from, --from-json, --set, --clear.
+
+  A present XMP packet is kept in sync (dc:title, dc:creator, xmp:CreateDate, …).
+  Changes are appended as an incremental update, so the rest of the file is
+  left byte-for-byte intact. ModDate is set to now unless given explicitly.`,
+		bold("Examples:"), bold("Notes:")),
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMeta(args[0])
+	},
+}
+
+func init() {
+	metaCmd.Flags().StringArrayVarP(&metaSet, "set", "s", nil, "Set a field: Key=Value (repeatable)")
+	metaCmd.Flags().StringArrayVarP(&metaClear, "clear", "c", nil, "Remove a field (repeatable)")
+	metaCmd.Flags().StringVar(&metaFromJSON, "from-json", "", "Read fields from a JSON object file (- for stdin)")
+	metaCmd.Flags().StringVar(&metaCopyFrom, "copy-from", "", "Copy Info fields from another PDF")
+	metaCmd.Flags().StringVar(&metaFields, "fields", "", "Comma-separated keys to copy with --copy-from (default: all)")
+	metaCmd.Flags().StringVarP(&metaOutput, "output", "o", "", "Output file (default: in-place)")
+	metaCmd.Flags().BoolVarP(&metaDryRun, "dry-run", "n", false, "Preview without writing")
+	rootCmd.AddCommand(metaCmd)
+}
+
+// infoKeys are the standard Info dictionary keys, in display order.
+var infoKeys = []string{"Title", "Author", "Subject", "Keywords", "Creator", "Producer", "CreationDate", "ModDate", "Trapped"}
+
+var infoKeyAliases = map[string]string{"created": "CreationDate", "modified": "ModDate"}
+
+// metaChange is one Info dictionary edit. A nil New removes the key.
+type metaChange struct {
+	Key string  `json:"key"`
+	Old *string `json:"old"`
+	New *string `json:"new"`
+}
+
+// canonicalInfoKey maps standard keys case-insensitively to their proper
+// spelling and validates custom keys.
+func canonicalInfoKey(k string) (string, error) {
+	k = strings.TrimSpace(k)
+	if a, ok := infoKeyAliases[strings.ToLower(k)]; ok {
+		return a, nil
+	}
+	for _, std := range infoKeys {
+		if strings.EqualFold(k, std) {
+			return std, nil
+		}
+	}
+	if k == "" || strings.ContainsAny(k, " \t\r\n()<>[]{}/%#") {
+		return "", fmt.Errorf("invalid metadata key %q", k)
+	}
+	return k, nil
+}
+
+// normalizeInfoValue validates a value for key and puts dates into PDF form.
+func normalizeInfoValue(key, v string) (string, error) {
+	switch key {
+	case "CreationDate", "ModDate":
+		if t, ok := types.DateTime(v, true); ok {
+			return types.DateString(t), nil
+		}
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+				return types.DateString(t), nil
+			}
+		}
+		return "", fmt.Errorf("invalid date for %s: %q", key, v)
+	case "Trapped":
+		for _, t := range []string{"True", "False", "Unknown"} {
+			if strings.EqualFold(v, t) {
+				return t, nil
+			}
+		}
+		return "", fmt.Errorf("Trapped must be True, False or Unknown, got %q", v)
+	}
+	return v, nil
+}
+
+// infoDictStrings decodes an Info dictionary's text and name entries.
+func infoDictStrings(d types.Dict) map[string]string {
+	m := make(map[string]string, len(d))
+	for k, v := range d {
+		if s, ok := decodePDFText(v); ok {
+			m[k] = s
+		}
+	}
+	return m
+}
+
+// decodePDFText decodes a text string (literal or hex) or a name.
+func decodePDFText(o types.Object) (string, bool) {
+	switch v := o.(type) {
+	case types.StringLiteral:
+		s, err := types.StringLiteralToString(v)
+		return s, err == nil
+	case types.HexLiteral:
+		s, err := types.HexLiteralToString(v)
+		return s, err == nil
+	case types.Name:
+		return v.Value(), true
+	}
+	return "", false
+}
+
+// encodeInfoString escapes a text string for the Info dictionary, using
+// UTF-16 only when the value is not plain ASCII.
+func encodeInfoString(v string) (string, error) {
+	for _, r := range v {
+		if r > 0x7e {
+			s, err := types.EscapedUTF16String(v)
+			if err != nil {
+				return "", err
+			}
+			return *s, nil
+		}
+	}
+	s, err := types.Escape(v)
+	if err != nil {
+		return "", err
+	}
+	return *s, nil
+}
+
+// readInfoDict returns the document's Info dictionary, or nil.
+func readInfoDict(ctx *model.Context) (types.Dict, error) {
+	if ctx.Info == nil {
+		return nil, nil
+	}
+	return ctx.DereferenceDict(*ctx.Info)
+}
+
+// sortedInfoKeys lists standard keys in their usual order, then custom keys alphabetically.
+func sortedInfoKeys(m map[string]string) []string {
+	var keys, custom []string
+	for _, k := range infoKeys {
+		if _, ok := m[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	for k := range m {
+		if _, err := canonicalInfoKey(k); err == nil && !isStandardInfoKey(k) {
+			custom = append(custom, k)
+		}
+	}
+	sort.Strings(custom)
+	return append(keys, custom...)
+}
+
+func isStandardInfoKey(k string) bool {
+	for _, std := range infoKeys {
+		if k == std {
+			return true
+		}
+	}
+	return false
+}
+
+func runMeta(inFile string) error {
+	ctx, err := readContext(inFile, pdfConfig())
+	if err != nil {
+		return err
+	}
+	infoDict, err := readInfoDict(ctx)
+	if err != nil {
+		return err
+	}
+	current := infoDictStrings(infoDict)
+
+	if len(metaSet) == 0 && len(metaClear) == 0 && metaFromJSON == "" && metaCopyFrom == "" {
+		return showMeta(inFile, current)
+	}
+
+	requested, err := collectMetaEdits()
+	if err != nil {
+		return err
+	}
+
+	// Drop edits that would not change anything.
+	var changes []metaChange
+	touched := map[string]bool{}
+	for _, e := range requested {
+		old, had := current[e.Key]
+		touched[e.Key] = true
+		if e.New == nil && !had || e.New != nil && had && *e.New == old {
+			continue
+		}
+		c := metaChange{Key: e.Key, New: e.New}
+		if had {
+			c.Old = &old
+		}
+		changes = append(changes, c)
+	}
+
+	out := metaOutput
+	if out == "" {
+		out = inFile
+	}
+	if len(changes) == 0 {
+		printInfo("Metadata already up to date — nothing to change")
+		if jsonOut {
+			return jsonResultOK("meta", map[string]interface{}{
+				"input": inFile, "output": out, "dry_run": metaDryRun, "changes": []metaChange{}, "info": current,
+			})
+		}
+		return nil
+	}
+
+	now := time.Now()
+	if !touched["ModDate"] {
+		mod := types.DateString(now)
+		c := metaChange{Key: "ModDate", New: &mod}
+		if old, ok := current["ModDate"]; ok {
+			c.Old = &old
+		}
+		changes = append(changes, c)
+	}
+
+	// Build the new Info dictionary from the old one so untouched entries keep their encoding.
+	newInfo := types.NewDict()
+	for k, v := range infoDict {
+		newInfo[k] = v
+	}
+	final := make(map[string]string, len(current))
+	for k, v := range current {
+		final[k] = v
+	}
+	xmpChanges := map[string]*string{}
+	for _, c := range changes {
+		if c.New == nil {
+			delete(newInfo, c.Key)
+			delete(final, c.Key)
+		} else if c.Key == "Trapped" {
+			newInfo[c.Key] = types.Name(*c.New)
+			final[c.Key] = *c.New
+		} else {
+			s, err := encodeInfoString(*c.New)
+			if err != nil {
+				return err
+			}
+			newInfo[c.Key] = types.StringLiteral(s)
+			final[c.Key] = *c.New
+		}
+		xmpChanges[c.Key] = c.New
+	}
+
+	infoNr := nextObjectNumber(ctx)
+	if ctx.Info != nil {
+		infoNr = ctx.Info.ObjectNumber.Value()
+	}
+	updates := []pdfObjectUpdate{{nr: infoNr, body: []byte(newInfo.PDFString())}}
+
+	xmpUpdated := false
+	raw, err := readCatalogXMP(ctx)
+	if err != nil {
+		return err
+	}
+	if raw != nil {
+		ref := ctx.RootDict.IndirectRefEntry("Metadata")
+		if ref == nil {
+			return fmt.Errorf("catalog Metadata is not an indirect stream")
+		}
+		packet, err := syncXMP(raw, xmpChanges, now)
+		if err != nil {
+			return err
+		}
+		body := fmt.Sprintf("<</Type/Metadata/Subtype/XML/Length %d>>\nstream\n", len(packet))
+		updates = append(updates, pdfObjectUpdate{
+			nr:   ref.ObjectNumber.Value(),
+			body: append(append([]byte(body), packet...), []byte("\nendstream")...),
+		})
+		xmpUpdated = true
+	}
+
+	printInfo(fmt.Sprintf("Updating metadata in %s…", inFile))
+	if !quiet {
+		for _, c := range changes {
+			from, to := dimStyle.Render("(unset)"), red("(removed)")
+			if c.Old != nil {
+				from = fmt.Sprintf("%q", *c.Old)
+			}
+			if c.New != nil {
+				to = green(fmt.Sprintf("%q", *c.New))
+			}
+			fmt.Printf("  %s%s%s → %s\n", cyan(c.Key+":"), strings.Repeat(" ", max(1, 15-len(c.Key))), from, to)
+		}
+		if xmpUpdated {
+			fmt.Println("  " + dimStyle.Render("XMP packet updated to match"))
+		}
+	}
+
+	fields := map[string]interface{}{
+		"input":       inFile,
+		"output":      out,
+		"in_place":    out == inFile,
+		"changes":     changes,
+		"xmp_updated": xmpUpdated,
+		"info":        final,
+	}
+	if metaDryRun {
+		printInfo(fmt.Sprintf("[dry-run] would write %d change(s) to %s", len(changes), out))
+		if jsonOut {
+			fields["dry_run"] = true
+			return jsonResultOK("meta", fields)
+		}
+		return nil
+	}
+
+	src, err := os.ReadFile(inFile)
+	if err != nil {
+		return err
+	}
+	data, err := appendIncrementalUpdate(ctx, src, updates, types.NewIndirectRef(infoNr, 0))
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(out, data); err != nil {
+		return err
+	}
+	// Re-read the result so a broken update never goes unnoticed.
+	if _, err := readContext(out, pdfConfig()); err != nil {
+		return fmt.Errorf("written file does not read back: %w", err)
+	}
+
+	if out == inFile {
+		printSuccess(fmt.Sprintf("Updated in-place: %s (%s)", out, humanSize(int64(len(data)))))
+	} else {
+		printSuccess(fmt.Sprintf("Created: %s (%s)", out, humanSize(int64(len(data)))))
+	}
+	if jsonOut {
+		fields["size_bytes"] = len(data)
+		fields["size_human"] = humanSize(int64(len(data)))
+		return jsonResultOK("meta", fields)
+	}
+	return nil
+}
+
+// collectMetaEdits gathers the requested edits from every source, in precedence order.
+func collectMetaEdits() ([]metaChange, error) {
+	var edits []metaChange
+	add := func(key string, v *string) error {
+		k, err := canonicalInfoKey(key)
+		if err != nil {
+			return err
+		}
+		if v != nil {
+			nv, err := normalizeInfoValue(k, *v)
+			if err != nil {
+				return err
+			}
+			v = &nv
+		}
+		// A later source overrides an earlier one for the same key.
+		for i := range edits {
+			if edits[i].Key == k {
+				edits = append(edits[:i], edits[i+1:]...)
+				break
+			}
+		}
+		edits = append(edits, metaChange{Key: k, New: v})
+		return nil
+	}
+
+	if metaCopyFrom != "" {
+		ctx, err := readContext(metaCopyFrom, pdfConfig())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", metaCopyFrom, err)
+		}
+		d, err := readInfoDict(ctx)
+		if err != nil {
+			return nil, err
+		}
+		src := infoDictStrings(d)
+		keys := sortedInfoKeys(src)
+		if metaFields != "" {
+			keys = nil
+			for _, f := range strings.Split(metaFields, ",") {
+				k, err := canonicalInfoKey(f)
+				if err != nil {
+					return nil, err
+				}
+				// Custom keys are case-sensitive in the file but not on the command line.
+				for sk := range src {
+					if strings.EqualFold(sk, k) {
+						k = sk
+						break
+					}
+				}
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			v, ok := src[k]
+			if !ok {
+				continue
+			}
+			if err := add(k, &v); err != nil {
+				return nil, err
+			}
+		}
+	} else if metaFields != "" {
+		return nil, fmt.Errorf("--fields only applies to --copy-from")
+	}
+
+	if metaFromJSON != "" {
+		var r io.Reader = os.Stdin
+		if metaFromJSON != "-" {
+			f, err := os.Open(metaFromJSON)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			r = f
+		}
+		var m map[string]*string
+		if err := json.NewDecoder(r).Decode(&m); err != nil {
+			return nil, fmt.Errorf("invalid metadata JSON (expected an object of key → string or null): %w", err)
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := add(k, m[k]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, kv := range metaSet {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("--set expects Key=Value, got %q", kv)
+		}
+		if err := add(k, &v); err != nil {
+			return nil, err
+		}
+	}
+	for _, k := range metaClear {
+		if err := add(k, nil); err != nil {
+			return nil, err
+		}
+	}
+	return edits, nil
+}
+
+func showMeta(inFile string, info map[string]string) error {
+	if jsonOut {
+		return jsonResultOK("meta", map[string]interface{}{"input": inFile, "info": info})
+	}
+	fmt.Println()
+	fmt.Println(bold(" Info dictionary") + "  " + dimStyle.Render(inFile))
+	fmt.Println(strings.Repeat("─", 50))
+	keys := sortedInfoKeys(info)
+	if len(keys) == 0 {
+		fmt.Println("  " + dimStyle.Render("No Info dictionary entries."))
+	}
+	for _, k := range keys {
+		mark := " "
+		if !isStandardInfoKey(k) {
+			mark = dimStyle.Render("*")
+		}
+		fmt.Printf("  %s%s%s%s\n", cyan(k+":"), mark, strings.Repeat(" ", max(1, 14-len(k))), info[k])
+	}
+	fmt.Println()
+	return nil
+}
+
+// writeFileAtomic writes data next to path and renames it into place, so an
+// in-place update never leaves a half-written file behind.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".pdfed-*.pdf")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if fi, err := os.Stat(path); err == nil {
+		_ = os.Chmod(tmp.Name(), fi.Mode().Perm())
+	} else {
+		_ = os.Chmod(tmp.Name(), 0644)
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -1,101 +1,613 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
 	"github.com/spf13/cobra"
 )
 
 var (
-	optimizeOutput string
-	optimizeDryRun bool
+	optimizeOutput   string
+	optimizeDryRun   bool
+	optimizePreset   string
+	optimizeDPI      int
+	optimizeQuality  int
+	optimizeLossless bool
+	optimizeTarget   string
+	optimizeLinear   bool
+	optimizeMin      string
+	optimizeJobs     int
+	optimizeRecurse  bool
+	optimizePrune    bool
+	optimizeSubset   bool
 )
 
 var optimizeCmd = &cobra.Command{
-	Use:   "optimize <input.pdf>",
+	Use:   "optimize <input.pdf|dir|glob> [more...]",
 	Short: "Compress and optimize a PDF to reduce file size",
 	Long: `Removes redundant objects, compresses streams, and deduplicates resources.
-Without -o, the optimized file replaces the original.`,
-	Args: cobra.ExactArgs(1),
+Without -o, the optimized file replaces the original.
+
+Images are left alone unless asked for:
+  --dpi N            downsample images drawn above N DPI
+  --jpeg-quality Q   recompress JPEG images at quality Q (1-100)
+  --lossless         re-encode other images with Flate and PNG predictors
+  --preset NAME      screen (72 DPI, quality 50), ebook (150 DPI, quality 70)
+                     or print (300 DPI, quality 85), all with --lossless
+
+Flags given with --preset override the preset's value; 0 turns a setting off.
+An image is only replaced when the result is smaller.
+
+--target-size (e.g. 5MB, 800KB) starts from these settings and tries
+progressively lower resolutions and JPEG qualities until the file fits.
+The smallest result is kept if the target cannot be reached. The input is
+never replaced by a larger file.
+
+--linearize writes the result for fast web view: the first page comes first
+and hint tables let viewers fetch further pages on demand. "pdfed info"
+checks the hint tables of a linearized file. In place, a linearized result
+that is larger than the input is not written; use -o to keep it.
+
+--subset-fonts cuts embedded TrueType and CFF fonts down to the glyphs the
+pages draw. Fonts that form fields or unreadable content may also draw with
+are left whole, as are Type 1 fonts.
+
+--prune-resources removes fonts, images and graphics states that a page's
+resources list but nothing on the page draws, and reports what went.
+
+--min-savings (e.g. 5% or 100KB) keeps the input unless the result is at
+least that much smaller.
+
+With several files, a directory or a glob, files are optimized in parallel
+(--jobs) and a summary table is printed; with --json, one JSON object per
+file is written as each finishes. -o then names a directory.`,
+	Example: `  pdfed optimize scan.pdf --preset ebook
+  pdfed optimize report.pdf --dpi 200 -o smaller.pdf
+  pdfed optimize photos.pdf --preset screen --jpeg-quality 60 --dry-run
+  pdfed optimize handout.pdf --target-size 5MB
+  pdfed optimize manual.pdf --linearize -o web.pdf
+  pdfed optimize merged.pdf --subset-fonts --prune-resources
+  pdfed optimize ./scans -r --preset ebook --min-savings 10%
+  pdfed optimize 'reports/*.pdf' -o ./small --json`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runOptimize(args[0])
+		settings, err := optimizeImageSettings(cmd)
+		if err != nil {
+			return err
+		}
+		var target int64
+		if optimizeTarget != "" {
+			if target, err = parseSize(optimizeTarget); err != nil {
+				return fmt.Errorf("--target-size: %w", err)
+			}
+		}
+		threshold, err := parseThreshold(optimizeMin)
+		if err != nil {
+			return fmt.Errorf("--min-savings: %w", err)
+		}
+		if optimizeJobs < 0 {
+			return fmt.Errorf("--jobs must be 1 or more")
+		}
+		files, err := collectPDFs(args, optimizeRecurse)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("no PDF files found")
+		}
+		cmd.SilenceUsage = true
+		if len(args) == 1 && len(files) == 1 && files[0] == args[0] {
+			return runOptimize(args[0], settings, target, threshold)
+		}
+		return runOptimizeBatch(files, settings, target, threshold)
 	},
 }
 
 func init() {
-	optimizeCmd.Flags().StringVarP(&optimizeOutput, "output", "o", "", "Output file (default: in-place)")
+	optimizeCmd.Flags().StringVarP(&optimizeOutput, "output", "o", "", "Output file, or directory with several inputs (default: in-place)")
 	optimizeCmd.Flags().BoolVarP(&optimizeDryRun, "dry-run", "n", false, "Preview without writing")
+	optimizeCmd.Flags().StringVar(&optimizePreset, "preset", "", "Image settings: screen, ebook or print")
+	optimizeCmd.Flags().IntVar(&optimizeDPI, "dpi", 0, "Downsample images drawn above this resolution")
+	optimizeCmd.Flags().IntVar(&optimizeQuality, "jpeg-quality", 0, "Recompress JPEG images at this quality (1-100)")
+	optimizeCmd.Flags().BoolVar(&optimizeLossless, "lossless", false, "Re-encode lossless images with Flate and PNG predictors")
+	optimizeCmd.Flags().StringVar(&optimizeTarget, "target-size", "", "Lower image settings step by step until the file fits (e.g. 5MB)")
+	optimizeCmd.Flags().BoolVar(&optimizeLinear, "linearize", false, "Write a linearized file for fast web view")
+	optimizeCmd.Flags().BoolVar(&optimizePrune, "prune-resources", false, "Remove fonts, images and graphics states that pages list but never use")
+	optimizeCmd.Flags().BoolVar(&optimizeSubset, "subset-fonts", false, "Cut embedded TrueType and CFF fonts down to the glyphs the document uses")
+	optimizeCmd.Flags().StringVar(&optimizeMin, "min-savings", "", "Keep the input unless the result is this much smaller (e.g. 5% or 100KB)")
+	optimizeCmd.Flags().IntVarP(&optimizeJobs, "jobs", "j", 0, "Files to optimize in parallel (default: number of CPUs)")
+	optimizeCmd.Flags().BoolVarP(&optimizeRecurse, "recursive", "r", false, "Descend into subdirectories")
 	rootCmd.AddCommand(optimizeCmd)
 }
 
-func runOptimize(inFile string) error {
-	fi, err := os.Stat(inFile)
-	if err != nil {
-		return err
+// optimizeImageSettings combines --preset with the individual image flags.
+func optimizeImageSettings(cmd *cobra.Command) (imageSettings, error) {
+	var s imageSettings
+	if optimizePreset != "" {
+		p, ok := imagePresets[strings.ToLower(optimizePreset)]
+		if !ok {
+			return s, fmt.Errorf("unknown preset %q (use %s)", optimizePreset, strings.Join(imagePresetNames, ", "))
+		}
+		s = p
 	}
-	origSize := fi.Size()
+	flags := cmd.Flags()
+	if flags.Changed("dpi") {
+		if optimizeDPI < 0 {
+			return s, fmt.Errorf("--dpi must be 0 or more")
+		}
+		s.DPI = optimizeDPI
+	}
+	if flags.Changed("jpeg-quality") {
+		if optimizeQuality < 0 || optimizeQuality > 100 {
+			return s, fmt.Errorf("--jpeg-quality must be between 1 and 100 (0 keeps JPEGs as they are)")
+		}
+		s.Quality = optimizeQuality
+	}
+	if flags.Changed("lossless") {
+		s.Lossless = optimizeLossless
+	}
+	return s, nil
+}
 
-	printInfo(fmt.Sprintf("Optimizing %s (%s)…", inFile, humanSize(origSize)))
+// categorySaving compares one size category before and after optimizing.
+type categorySaving struct {
+	Category sizeCategory `json:"category"`
+	Label    string       `json:"label"`
+	Before   int64        `json:"before_bytes"`
+	After    int64        `json:"after_bytes"`
+	Saved    int64        `json:"saved_bytes"`
+}
 
-	if optimizeDryRun {
-		printInfo("[dry-run] would optimize (no output written)")
-		if jsonOut {
-			out := optimizeOutput
-			if out == "" {
-				out = inFile
-			}
-			return jsonResultOK("optimize", map[string]interface{}{
-				"dry_run":           true,
-				"input":             inFile,
-				"output":            out,
-				"input_size_bytes":  origSize,
-				"input_size_human":  humanSize(origSize),
-			})
+// categorySavings attributes the input and output to size categories. It
+// returns nil if either file cannot be analyzed; the report is a bonus.
+func categorySavings(before, after []byte) []categorySaving {
+	analyze := func(b []byte) *sizeReport {
+		ctx, err := readContextFrom(bytes.NewReader(b), pdfConfig())
+		if err != nil {
+			return nil
+		}
+		rep, err := analyzeSizes(ctx)
+		if err != nil {
+			return nil
 		}
+		return rep
+	}
+	a, b := analyze(before), analyze(after)
+	if a == nil || b == nil {
 		return nil
 	}
+	var out []categorySaving
+	for _, c := range sizeCategories {
+		x, y := a.byCategory[c], b.byCategory[c]
+		if x == 0 && y == 0 {
+			continue
+		}
+		out = append(out, categorySaving{c, sizeCategoryLabels[c], x, y, x - y})
+	}
+	return out
+}
+
+func printCategorySavings(savings []categorySaving) {
+	if quiet || len(savings) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println("  " + bold(fmt.Sprintf("%-16s %10s %10s  %s", "CATEGORY", "BEFORE", "AFTER", "SAVED")))
+	for _, s := range savings {
+		saved := "-"
+		if s.Saved != 0 {
+			saved = humanSize(abs64(s.Saved))
+			if s.Saved < 0 {
+				saved = "+" + saved
+			}
+			if s.Before > 0 {
+				saved = fmt.Sprintf("%s (%.0f%%)", saved, percentOf(abs64(s.Saved), s.Before))
+			}
+		}
+		fmt.Printf("  %-16s %10s %10s  %s\n", s.Label, humanSize(s.Before), humanSize(s.After), saved)
+	}
+	fmt.Println()
+}
+
+func describeImageStats(st imageStats) string {
+	var parts []string
+	add := func(n int, what string) {
+		if n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, what))
+		}
+	}
+	add(st.Downsampled, "downsampled")
+	add(st.Recompressed, "JPEG recompressed")
+	add(st.Reencoded, "re-encoded losslessly")
+	add(st.Unchanged, "kept (no gain)")
+	add(st.Skipped, "skipped (unsupported encoding)")
+	if len(parts) == 0 {
+		return "no images"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// targetSteps are the settings --target-size tries, from mildest to
+// strongest, after the settings given on the command line.
+var targetSteps = []imageSettings{
+	{DPI: 300, Quality: 85, Lossless: true},
+	{DPI: 200, Quality: 75, Lossless: true},
+	{DPI: 150, Quality: 70, Lossless: true},
+	{DPI: 110, Quality: 60, Lossless: true},
+	{DPI: 72, Quality: 50, Lossless: true},
+	{DPI: 72, Quality: 35, Lossless: true},
+	{DPI: 50, Quality: 25, Lossless: true},
+}
+
+// strongerThan reports whether s compresses images harder than base in at
+// least one respect.
+func (s imageSettings) strongerThan(base imageSettings) bool {
+	return (s.DPI > 0 && (base.DPI == 0 || s.DPI < base.DPI)) ||
+		(s.Quality > 0 && (base.Quality == 0 || s.Quality < base.Quality)) ||
+		(s.Lossless && !base.Lossless)
+}
+
+// parseSize reads a size such as 5MB, 800K or 1.5 GB; units are powers of
+// 1024 as in humanSize, and a bare number is bytes.
+func parseSize(v string) (int64, error) {
+	t := strings.ToUpper(strings.TrimSpace(v))
+	i := strings.IndexFunc(t, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' })
+	num, unit := t, ""
+	if i >= 0 {
+		num, unit = t[:i], strings.TrimSpace(t[i:])
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 5MB or 800KB)", v)
+	}
+	mult := map[string]float64{"": 1, "B": 1, "K": 1 << 10, "KB": 1 << 10, "M": 1 << 20, "MB": 1 << 20, "G": 1 << 30, "GB": 1 << 30}
+	m, ok := mult[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 5MB or 800KB)", v)
+	}
+	return int64(n * m), nil
+}
+
+// passStats reports what the optional optimize passes did; a nil field
+// means the pass did not run.
+type passStats struct {
+	images *imageStats
+	pruned *pruneStats
+	fonts  *fontStats
+}
+
+// optimizeBytes optimizes the PDF in src, prunes its resources and subsets
+// its fonts if asked to, and applies s to its images.
+func optimizeBytes(src []byte, s imageSettings) ([]byte, passStats, error) {
+	var stats passStats
+	conf := pdfConfig()
+	conf.Cmd = model.OPTIMIZE
+	if optimizePrune {
+		// pdfcpu trims page resources on its own, silently and without
+		// following forms that borrow the page's resources; prune instead.
+		conf.OptimizeResourceDicts = false
+	}
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(src), conf)
+	if err != nil {
+		return nil, stats, err
+	}
+	if optimizePrune {
+		st := pruneResources(ctx)
+		stats.pruned = &st
+	}
+	if optimizeSubset {
+		st := subsetFonts(ctx)
+		stats.fonts = &st
+	}
+	if s.active() {
+		st := optimizeImages(ctx, s, imageResolutions(ctx))
+		stats.images = &st
+	}
+	data, err := writeContextBytes(ctx)
+	if err != nil {
+		return nil, stats, err
+	}
+	return data, stats, nil
+}
+
+// targetAttempt is one try at reaching --target-size.
+type targetAttempt struct {
+	Settings imageSettings `json:"settings"`
+	Bytes    int64         `json:"size_bytes"`
+	Fits     bool          `json:"fits"`
+}
+
+// optimizeToTarget tries settings, then every stronger targetSteps entry,
+// until the result is at most target bytes. It returns the smallest result
+// and the settings that produced it; progress, if set, sees every attempt.
+func optimizeToTarget(src []byte, settings imageSettings, target int64, progress func(targetAttempt)) ([]byte, passStats, imageSettings, []targetAttempt, error) {
+	steps := []imageSettings{settings}
+	for _, s := range targetSteps {
+		if s.strongerThan(settings) {
+			steps = append(steps, s)
+		}
+	}
+	var (
+		best      []byte
+		bestStats passStats
+		bestSet   imageSettings
+		attempts  []targetAttempt
+	)
+	for _, s := range steps {
+		data, stats, err := optimizeBytes(src, s)
+		if err != nil {
+			return nil, stats, s, attempts, err
+		}
+		a := targetAttempt{s, int64(len(data)), int64(len(data)) <= target}
+		attempts = append(attempts, a)
+		if progress != nil {
+			progress(a)
+		}
+		if best == nil || len(data) < len(best) {
+			best, bestStats, bestSet = data, stats, s
+		}
+		if a.Fits {
+			break
+		}
+	}
+	return best, bestStats, bestSet, attempts, nil
+}
+
+// savingsThreshold is the --min-savings value: the smallest saving, as a
+// share of the input or in bytes, worth replacing a file for.
+type savingsThreshold struct {
+	Percent float64
+	Bytes   int64
+}
+
+// parseThreshold reads a --min-savings value such as 5% or 100KB.
+func parseThreshold(v string) (savingsThreshold, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return savingsThreshold{}, nil
+	}
+	if p, ok := strings.CutSuffix(v, "%"); ok {
+		n, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || n < 0 || n >= 100 {
+			return savingsThreshold{}, fmt.Errorf("invalid percentage %q (use e.g. 5%%)", v)
+		}
+		return savingsThreshold{Percent: n}, nil
+	}
+	n, err := parseSize(v)
+	if err != nil {
+		return savingsThreshold{}, err
+	}
+	return savingsThreshold{Bytes: n}, nil
+}
+
+func (t savingsThreshold) set() bool { return t.Percent > 0 || t.Bytes > 0 }
+
+// met reports whether saving saved bytes of an orig-byte file is enough.
+func (t savingsThreshold) met(saved, orig int64) bool {
+	return saved > 0 && saved >= t.Bytes && percentOf(saved, orig) >= t.Percent
+}
+
+func (t savingsThreshold) String() string {
+	if t.Percent > 0 {
+		return strconv.FormatFloat(t.Percent, 'f', -1, 64) + "%"
+	}
+	return humanSize(t.Bytes)
+}
+
+// optimizeResult is what optimizing one file produced.
+type optimizeResult struct {
+	data     []byte
+	origSize int64
+	outSize  int64
+	stats    passStats
+	settings imageSettings
+	attempts []targetAttempt
+	target   int64
+}
+
+func (r *optimizeResult) saved() int64 { return r.origSize - r.outSize }
+
+// optimizeSource runs the whole optimize pipeline on src: rewriting, image
+// settings or --target-size, then linearizing. progress, if set, is told
+// about each --target-size attempt.
+func optimizeSource(src []byte, settings imageSettings, target int64, progress func(targetAttempt)) (*optimizeResult, error) {
+	r := &optimizeResult{origSize: int64(len(src)), settings: settings, target: target}
+	var err error
+	if target > 0 {
+		r.data, r.stats, r.settings, r.attempts, err = optimizeToTarget(src, settings, target, progress)
+	} else {
+		r.data, r.stats, err = optimizeBytes(src, settings)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if optimizeLinear {
+		if r.data, err = linearizeBytes(r.data); err != nil {
+			return nil, fmt.Errorf("linearize: %w", err)
+		}
+	}
+	r.outSize = int64(len(r.data))
+	return r, nil
+}
+
+// keepOriginal reports whether the input should be kept instead of the
+// result. An in-place run never replaces the input with a file that is not
+// smaller, and --min-savings raises that bar for every output. --linearize
+// writes even when nothing was saved, as linearizing is the point of the
+// run, but an in-place run still never replaces the input with a larger file.
+func keepOriginal(r *optimizeResult, inPlace bool, t savingsThreshold) bool {
+	if optimizeLinear {
+		return inPlace && r.saved() < 0
+	}
+	if !inPlace && !t.set() {
+		return false
+	}
+	return !t.met(r.saved(), r.origSize)
+}
+
+// keepReason explains keepOriginal's decision for r.
+func keepReason(r *optimizeResult, t savingsThreshold) string {
+	if optimizeLinear {
+		return "larger; write the linearized file with -o"
+	}
+	if r.saved() <= 0 {
+		return "not smaller"
+	}
+	return fmt.Sprintf("saves less than %s", t)
+}
+
+// optimizeJSONFields is the --json report for one file, shared by single-file
+// and batch runs.
+func optimizeJSONFields(inFile, outFile string, r *optimizeResult, keep bool) map[string]interface{} {
+	saved := r.saved()
+	fields := map[string]interface{}{
+		"input":             inFile,
+		"output":            outFile,
+		"input_size_bytes":  r.origSize,
+		"output_size_bytes": r.outSize,
+		"saved_bytes":       saved,
+		"saved_percent":     percentOf(saved, r.origSize),
+		"input_size_human":  humanSize(r.origSize),
+		"output_size_human": humanSize(r.outSize),
+		"saved_human":       humanSize(abs64(saved)),
+		"linearized":        optimizeLinear,
+		"kept_original":     keep,
+	}
+	if r.stats.images != nil {
+		fields["images"] = r.settings
+		fields["image_stats"] = r.stats.images
+	}
+	if r.stats.pruned != nil {
+		fields["pruned"] = r.stats.pruned
+	}
+	if r.stats.fonts != nil {
+		fields["fonts"] = r.stats.fonts
+	}
+	if r.target > 0 {
+		fields["target_bytes"] = r.target
+		fields["target_human"] = humanSize(r.target)
+		fields["target_reached"] = r.outSize <= r.target
+		fields["attempts"] = r.attempts
+		fields["images"] = r.settings
+	}
+	if optimizeDryRun {
+		fields["dry_run"] = true
+	}
+	return fields
+}
+
+// runOptimize optimizes inFile. With target > 0 it lowers the image
+// settings until the output is at most target bytes.
+func runOptimize(inFile string, settings imageSettings, target int64, threshold savingsThreshold) error {
+	src, err := os.ReadFile(inFile)
+	if err != nil {
+		return err
+	}
+	origSize := int64(len(src))
 
 	outFile := optimizeOutput
 	if outFile == "" {
 		outFile = inFile // in-place
 	}
 
-	if err := api.OptimizeFile(inFile, outFile, pdfConfig()); err != nil {
+	printInfo(fmt.Sprintf("Optimizing %s (%s)…", inFile, humanSize(origSize)))
+
+	var progress func(targetAttempt)
+	if target > 0 {
+		printInfo(fmt.Sprintf("Target size %s; trying image settings:", humanSize(target)))
+		progress = func(a targetAttempt) {
+			mark := dimStyle.Render("too big")
+			if a.Fits {
+				mark = green("fits")
+			}
+			printInfo(fmt.Sprintf("  %-40s %10s  %s", a.Settings, humanSize(a.Bytes), mark))
+		}
+	} else if settings.active() {
+		printInfo(fmt.Sprintf("Images: %s", settings))
+	}
+	r, err := optimizeSource(src, settings, target, progress)
+	if err != nil {
 		return err
 	}
+	if optimizeLinear {
+		printInfo("Linearized for fast web view")
+	}
+	outSize := r.outSize
+	savings := categorySavings(src, r.data)
 
-	fi2, err := os.Stat(outFile)
-	if err != nil {
+	printCategorySavings(savings)
+	if r.stats.images != nil {
+		printInfo(fmt.Sprintf("Images: %s", describeImageStats(*r.stats.images)))
+	}
+	if p := r.stats.pruned; p != nil {
+		printPruneStats(*p)
+	}
+	if f := r.stats.fonts; f != nil {
+		printFontStats(*f)
+	}
+
+	saved := r.saved()
+	pct := percentOf(saved, origSize)
+	if target > 0 {
+		if outSize <= target {
+			printSuccess(fmt.Sprintf("Fits in %s with %s", humanSize(target), r.settings))
+		} else {
+			printWarning(fmt.Sprintf("Could not get under %s; the smallest result is %s (%s)", humanSize(target), humanSize(outSize), r.settings))
+		}
+	}
+	keep := keepOriginal(r, outFile == inFile, threshold)
+	fields := optimizeJSONFields(inFile, outFile, r, keep)
+	fields["categories"] = savings
+
+	if optimizeDryRun {
+		switch {
+		case keep && outFile == inFile:
+			printInfo(fmt.Sprintf("[dry-run] %s → %s (%s); %s would be left unchanged", humanSize(origSize), humanSize(outSize), keepReason(r, threshold), inFile))
+		case keep:
+			printInfo(fmt.Sprintf("[dry-run] %s → %s (%s); %s would be copied unchanged", humanSize(origSize), humanSize(outSize), keepReason(r, threshold), inFile))
+		default:
+			printInfo(fmt.Sprintf("[dry-run] would write %s → %s (no output written)", humanSize(origSize), humanSize(outSize)))
+		}
 		if jsonOut {
-			return fmt.Errorf("stat output after optimize: %w", err)
+			return jsonResultOK("optimize", fields)
 		}
 		return nil
 	}
-	saved := origSize - fi2.Size()
-	pct := float64(0)
-	if origSize > 0 {
-		pct = float64(saved) / float64(origSize) * 100
+
+	if keep {
+		if outFile != inFile {
+			if err := writeFileAtomic(outFile, src); err != nil {
+				return err
+			}
+			printInfo(fmt.Sprintf("%s → %s (%s); copied %s to %s unchanged", humanSize(origSize), humanSize(outSize), keepReason(r, threshold), inFile, outFile))
+		} else {
+			printInfo(fmt.Sprintf("%s → %s (%s); %s is left unchanged", humanSize(origSize), humanSize(outSize), keepReason(r, threshold), inFile))
+		}
+		if jsonOut {
+			return jsonResultOK("optimize", fields)
+		}
+		return nil
+	}
+	if err := writeFileAtomic(outFile, r.data); err != nil {
+		return err
 	}
+
 	if saved > 0 {
-		printSuccess(fmt.Sprintf("%s → %s (saved %s, %.1f%%)", humanSize(origSize), humanSize(fi2.Size()), humanSize(saved), pct))
+		printSuccess(fmt.Sprintf("%s → %s (saved %s, %.1f%%)", humanSize(origSize), humanSize(outSize), humanSize(saved), pct))
+	} else if saved < 0 {
+		printSuccess(fmt.Sprintf("%s → %s (%s larger)", humanSize(origSize), humanSize(outSize), humanSize(-saved)))
 	} else {
-		printSuccess(fmt.Sprintf("%s → %s (already optimal)", humanSize(origSize), humanSize(fi2.Size())))
+		printSuccess(fmt.Sprintf("%s → %s (already optimal)", humanSize(origSize), humanSize(outSize)))
 	}
 	if jsonOut {
-		outSz := fi2.Size()
-		return jsonResultOK("optimize", map[string]interface{}{
-			"input":             inFile,
-			"output":            outFile,
-			"input_size_bytes":  origSize,
-			"output_size_bytes": outSz,
-			"saved_bytes":       saved,
-			"saved_percent":     pct,
-			"input_size_human":  humanSize(origSize),
-			"output_size_human": humanSize(outSz),
-			"saved_human":       humanSize(abs64(saved)),
-		})
+		return jsonResultOK("optimize", fields)
 	}
 	return nil
 }
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"text/tabwriter"
+)
+
+// batchResult is one file's outcome in a batch optimize.
+type batchResult struct {
+	input  string
+	output string
+	r      *optimizeResult
+	keep   bool
+	err    error
+}
+
+// batchOutputs maps each input to where its result goes: the input itself,
+// or outDir/<name> with -o. Two inputs with the same name cannot share outDir.
+func batchOutputs(files []string, outDir string) ([]string, error) {
+	outs := make([]string, len(files))
+	if outDir == "" {
+		copy(outs, files)
+		return outs, nil
+	}
+	if st, err := os.Stat(outDir); err == nil && !st.IsDir() {
+		return nil, fmt.Errorf("-o %s: with several inputs, -o must be a directory", outDir)
+	}
+	seen := map[string]string{}
+	for i, f := range files {
+		name := filepath.Base(f)
+		if prev, ok := seen[name]; ok {
+			return nil, fmt.Errorf("%s and %s would both be written to %s", prev, f, filepath.Join(outDir, name))
+		}
+		seen[name] = f
+		outs[i] = filepath.Join(outDir, name)
+	}
+	return outs, nil
+}
+
+// optimizeOne optimizes one file of a batch and writes the result.
+func optimizeOne(in, out string, settings imageSettings, target int64, threshold savingsThreshold) batchResult {
+	res := batchResult{input: in, output: out}
+	src, err := os.ReadFile(in)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	if res.r, err = optimizeSource(src, settings, target, nil); err != nil {
+		res.err = err
+		return res
+	}
+	res.keep = keepOriginal(res.r, in == out, threshold)
+	switch {
+	case optimizeDryRun:
+	case !res.keep:
+		res.err = writeFileAtomic(out, res.r.data)
+	case in != out:
+		res.err = writeFileAtomic(out, src)
+	}
+	res.r.data = nil // results are kept until the summary; the bytes are not needed
+	return res
+}
+
+// runOptimizeBatch optimizes files with a pool of --jobs workers, then prints
+// a summary table, or one JSON object per file as it finishes with --json.
+func runOptimizeBatch(files []string, settings imageSettings, target int64, threshold savingsThreshold) error {
+	outs, err := batchOutputs(files, optimizeOutput)
+	if err != nil {
+		return err
+	}
+	if optimizeOutput != "" && !optimizeDryRun {
+		if err := os.MkdirAll(optimizeOutput, 0o755); err != nil {
+			return err
+		}
+	}
+	jobs := optimizeJobs
+	if jobs == 0 {
+		jobs = runtime.NumCPU()
+	}
+	jobs = min(jobs, len(files))
+
+	printInfo(fmt.Sprintf("Optimizing %d PDF(s) with %d worker(s)…", len(files), jobs))
+	if settings.active() && target == 0 {
+		printInfo(fmt.Sprintf("Images: %s", settings))
+	}
+	if threshold.set() {
+		printInfo(fmt.Sprintf("Keeping files that shrink by less than %s", threshold))
+	}
+
+	// pdfcpu sets up its global configuration on first use; do that before
+	// the workers race for it.
+	pdfConfig()
+
+	results := make([]batchResult, len(files))
+	work := make(chan int)
+	var mu sync.Mutex // serializes NDJSON lines
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				res := optimizeOne(files[i], outs[i], settings, target, threshold)
+				results[i] = res
+				if jsonOut {
+					mu.Lock()
+					_ = jsonEmit(batchResultJSON(res))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range files {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	failed := 0
+	for _, res := range results {
+		if res.err != nil {
+			failed++
+		}
+	}
+	if !jsonOut {
+		if err := writeBatchTable(results, threshold); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d file(s) could not be optimized", failed, len(results))
+	}
+	return nil
+}
+
+func batchResultJSON(res batchResult) map[string]interface{} {
+	if res.err != nil {
+		return map[string]interface{}{"ok": false, "command": "optimize", "input": res.input, "output": res.output, "error": res.err.Error()}
+	}
+	m := optimizeJSONFields(res.input, res.output, res.r, res.keep)
+	m["ok"] = true
+	m["command"] = "optimize"
+	return m
+}
+
+func writeBatchTable(results []batchResult, threshold savingsThreshold) error {
+	if quiet {
+		return nil
+	}
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tBEFORE\tAFTER\tSAVED\tRESULT")
+	var before, after int64
+	var written, kept, failed int
+	for _, res := range results {
+		if res.err != nil {
+			failed++
+			fmt.Fprintf(tw, "%s\t\t\t\t%s\n", res.input, red("error: "+res.err.Error()))
+			continue
+		}
+		r := res.r
+		saved := "-"
+		if s := r.saved(); s != 0 {
+			saved = fmt.Sprintf("%s (%.1f%%)", humanSize(abs64(s)), percentOf(abs64(s), r.origSize))
+			if s < 0 {
+				saved = "+" + saved
+			}
+		}
+		var result string
+		before += r.origSize
+		if res.keep {
+			kept++
+			after += r.origSize
+			result = "kept, " + keepReason(r, threshold)
+			if optimizeDryRun {
+				result = "would keep, " + keepReason(r, threshold)
+			}
+			result = dimStyle.Render(result)
+		} else {
+			written++
+			after += r.outSize
+			result = green("written")
+			if optimizeDryRun {
+				result = "would write"
+			}
+			if res.output != res.input {
+				result += " → " + res.output
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", res.input, humanSize(r.origSize), humanSize(r.outSize), saved, result)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	verb := "written"
+	if optimizeDryRun {
+		verb = "to write"
+	}
+	summary := fmt.Sprintf("%d file(s): %d %s, %d kept", len(results), written, verb, kept)
+	if failed > 0 {
+		summary += fmt.Sprintf(", %d failed", failed)
+	}
+	change := "saved"
+	if after > before {
+		change = "grew"
+	}
+	fmt.Printf("\n%s %s; %s → %s (%s %s, %.1f%%)\n", cyan("Σ"), summary,
+		humanSize(before), humanSize(after), change, humanSize(abs64(before-after)), percentOf(abs64(before-after), before))
+	return nil
+}
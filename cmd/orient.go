@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+const (
+	// minOrientGlyphs is how many glyphs a page needs before its text
+	// direction is trusted.
+	minOrientGlyphs = 10
+	// minOrientShare is the share of glyphs that must agree on one direction.
+	minOrientShare = 0.6
+	// maxOrientSkew is how far, in degrees, a baseline may be off an axis
+	// and still vote; skewed and diagonal text does not.
+	maxOrientSkew = 20.0
+)
+
+// pageOrientation is the detected text direction of one page and the
+// rotation that makes it read upright.
+type pageOrientation struct {
+	Page       int     `json:"page"`
+	Direction  int     `json:"direction"` // degrees counterclockwise in unrotated page space; -1 if unknown
+	Share      float64 `json:"share"`
+	Glyphs     int     `json:"glyphs"`
+	Current    int     `json:"current_rotation"`
+	Rotation   int     `json:"rotation"`
+	Status     string  `json:"status"` // upright, rotated, no text or unclear
+	Changed    bool    `json:"changed"`
+	directions [4]int
+}
+
+// textDirections votes on the reading direction of each page: every glyph
+// shown counts for right (0°), up (90°), left (180°) or down (270°) in the
+// page's own coordinates, by the baseline of its text and transformation
+// matrices.
+func textDirections(ctx *model.Context) ([]pageOrientation, error) {
+	out := make([]pageOrientation, ctx.PageCount)
+	for i := 1; i <= ctx.PageCount; i++ {
+		o := &out[i-1]
+		o.Page, o.Direction = i, -1
+		d, _, inh, err := ctx.PageDict(i, false)
+		if err != nil {
+			return nil, err
+		}
+		if d == nil {
+			continue
+		}
+		content, err := ctx.PageContent(d, i)
+		if err == model.ErrNoContent {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", i, err)
+		}
+		res, _ := ctx.DereferenceDict(d["Resources"])
+		if res == nil && inh != nil {
+			res = inh.Resources
+		}
+		voteTextDirections(ctx, content, res, identityMatrix, 0, &o.directions)
+
+		best := 0
+		for d := range o.directions {
+			o.Glyphs += o.directions[d]
+			if o.directions[d] > o.directions[best] {
+				best = d
+			}
+		}
+		if o.Glyphs > 0 {
+			o.Share = float64(o.directions[best]) / float64(o.Glyphs)
+		}
+		if o.Glyphs >= minOrientGlyphs && o.Share >= minOrientShare {
+			o.Direction = best * 90
+		}
+	}
+	return out, nil
+}
+
+// voteTextDirections adds the glyphs content shows to votes, following form
+// XObjects. Only the linear part of the matrices matters, so text
+// positioning operators are ignored; a negative font size or horizontal
+// scaling turns the baseline around.
+func voteTextDirections(ctx *model.Context, content []byte, res types.Dict, ctm matrix, depth int, votes *[4]int) {
+	if depth > 8 {
+		return
+	}
+	xobjs, _ := ctx.DereferenceDict(res["XObject"])
+	type state struct {
+		ctm         matrix
+		size, scale float64
+	}
+	st := state{ctm: ctm, size: 1, scale: 100}
+	var stack []state
+	tm := identityMatrix
+	scanContentOps(content, func(op string, args []string, start, end int) {
+		switch op {
+		case "q":
+			stack = append(stack, st)
+		case "Q":
+			if len(stack) > 0 {
+				st, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			}
+		case "cm":
+			if m, ok := parseMatrix(args); ok {
+				st.ctm = m.times(st.ctm)
+			}
+		case "BT":
+			tm = identityMatrix
+		case "Tm":
+			if m, ok := parseMatrix(args); ok {
+				tm = m
+			}
+		case "Tf":
+			if len(args) == 2 {
+				if v, err := strconv.ParseFloat(args[1], 64); err == nil {
+					st.size = v
+				}
+			}
+		case "Tz":
+			if len(args) == 1 {
+				if v, err := strconv.ParseFloat(args[0], 64); err == nil {
+					st.scale = v
+				}
+			}
+		case "Tj", "'", "\"", "TJ":
+			glyphs := 0
+			for _, s := range textStrings(content[start:end]) {
+				glyphs += len(s) - bytes.Count(s, []byte(" "))
+			}
+			m := tm.times(st.ctm)
+			dx, dy := m[0], m[1]
+			if st.size*st.scale < 0 {
+				dx, dy = -dx, -dy
+			}
+			if glyphs == 0 || (dx == 0 && dy == 0) {
+				return
+			}
+			deg := math.Atan2(dy, dx) * 180 / math.Pi
+			q := int(math.Round(deg/90)+4) % 4
+			if math.Abs(math.Remainder(deg, 90)) <= maxOrientSkew {
+				votes[q] += glyphs
+			}
+		case "Do":
+			if len(args) != 1 || xobjs == nil || len(args[0]) < 2 {
+				return
+			}
+			ir, ok := xobjs[args[0][1:]].(types.IndirectRef)
+			if !ok {
+				return
+			}
+			sd, _, err := ctx.DereferenceStreamDict(ir)
+			if err != nil || sd == nil || sd.Subtype() == nil || *sd.Subtype() != "Form" {
+				return
+			}
+			if err := sd.Decode(); err != nil {
+				return
+			}
+			m := identityMatrix
+			if a := sd.ArrayEntry("Matrix"); len(a) == 6 {
+				var s []string
+				for _, v := range a {
+					s = append(s, v.PDFString())
+				}
+				if fm, ok := parseMatrix(s); ok {
+					m = fm
+				}
+			}
+			formRes, _ := ctx.DereferenceDict(sd.Dict["Resources"])
+			if formRes == nil {
+				formRes = res
+			}
+			voteTextDirections(ctx, sd.Content, formRes, m.times(st.ctm), depth+1, votes)
+		}
+	})
+}
+
+// orientPages fills in the current rotation of each selected page and the
+// rotation that shows its text upright. A page is displayed turned clockwise
+// by /Rotate, so text running at d° counterclockwise needs /Rotate d.
+func orientPages(ctx *model.Context, orients []pageOrientation, selected types.IntSet) ([]pageOrientation, error) {
+	var out []pageOrientation
+	for _, o := range orients {
+		if selected != nil && !selected[o.Page] {
+			continue
+		}
+		_, _, inh, err := ctx.PageDict(o.Page, false)
+		if err != nil {
+			return nil, err
+		}
+		o.Current = normalizeRotation(inh.Rotate)
+		o.Rotation = o.Current
+		switch {
+		case o.Glyphs == 0:
+			o.Status = "no text"
+		case o.Direction < 0:
+			o.Status = "unclear"
+		case o.Direction == o.Current:
+			o.Status = "upright"
+		default:
+			o.Status = "rotated"
+			o.Rotation = o.Direction
+			o.Changed = true
+		}
+		out = append(out, o)
+	}
+	return out, nil
+}
+
+func normalizeRotation(r int) int {
+	return ((r % 360) + 360) % 360
+}
+
+// writeContextBytes serializes ctx as a complete new file.
+func writeContextBytes(ctx *model.Context) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := api.WriteContext(ctx, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func describeDirection(o pageOrientation) string {
+	if o.Direction < 0 {
+		return "-"
+	}
+	// How the text currently appears on screen.
+	switch normalizeRotation(o.Direction - o.Current) {
+	case 0:
+		return "upright"
+	case 90:
+		return "turned left"
+	case 180:
+		return "upside down"
+	default:
+		return "turned right"
+	}
+}
+
+func printOrientReport(report []pageOrientation) {
+	if quiet {
+		return
+	}
+	fmt.Println()
+	fmt.Println("  " + bold(fmt.Sprintf("%-5s  %-13s  %-6s  %-10s  %s", "PAGE", "TEXT", "VOTES", "ROTATE", "ACTION")))
+	for _, o := range report {
+		votes := "-"
+		if o.Glyphs > 0 {
+			votes = fmt.Sprintf("%.0f%%", o.Share*100)
+		}
+		rot := fmt.Sprintf("%d", o.Current)
+		action := dimStyle.Render(o.Status)
+		if o.Changed {
+			rot = fmt.Sprintf("%d → %d", o.Current, o.Rotation)
+			action = green("rotate")
+		} else if o.Status == "unclear" {
+			action = yellow("unclear, kept")
+		}
+		fmt.Printf("  %-5d  %-13s  %-6s  %-10s  %s\n", o.Page, describeDirection(o), votes, rot, action)
+	}
+	fmt.Println()
+}
+
+// runAutoRotate turns every selected page whose text does not read upright.
+func runAutoRotate(inFile string) error {
+	ctx, err := readValidatedContext(inFile)
+	if err != nil {
+		return err
+	}
+	selected, err := selectedRotatePages(ctx, inFile)
+	if err != nil {
+		return err
+	}
+	printInfo(fmt.Sprintf("Detecting text orientation in %s…", inFile))
+	dirs, err := textDirections(ctx)
+	if err != nil {
+		return err
+	}
+	report, err := orientPages(ctx, dirs, selected)
+	if err != nil {
+		return err
+	}
+	printOrientReport(report)
+
+	rotations := map[int]int{}
+	var unclear []int
+	for _, o := range report {
+		rotations[o.Page] = o.Rotation
+		if o.Status == "unclear" {
+			unclear = append(unclear, o.Page)
+		}
+	}
+	if len(unclear) > 0 {
+		printWarning(fmt.Sprintf("Could not tell the orientation of page(s) %s; left as they are", compactPageList(unclear)))
+	}
+
+	fields := map[string]interface{}{
+		"input":    inFile,
+		"mode":     "auto",
+		"report":   report,
+		"selected": selectedPageList(selected, ctx.PageCount),
+	}
+	if rotatePages != "" {
+		fields["pages"] = rotatePages
+	}
+	return writeRotations(ctx, inFile, rotations, fields)
+}
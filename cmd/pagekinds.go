@@ -0,0 +1,366 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// pageKind classifies a page by what it draws: extractable text, images, both, or nothing.
+type pageKind string
+
+const (
+	kindText  pageKind = "text"
+	kindImage pageKind = "image"
+	kindMixed pageKind = "mixed"
+	kindBlank pageKind = "blank"
+)
+
+var pageKinds = []pageKind{kindText, kindImage, kindMixed, kindBlank}
+
+var pageKindLabels = map[pageKind]string{
+	kindText:  "text",
+	kindImage: "image-only",
+	kindMixed: "text + images",
+	kindBlank: "blank",
+}
+
+// minTextChars is how many letters or digits a page needs before it counts as
+// having a text layer; a stray page number stamped on a scan does not.
+const minTextChars = 10
+
+// searchable reports whether search can find anything on a page of this kind.
+func (k pageKind) searchable() bool {
+	return k == kindText || k == kindMixed
+}
+
+// classifyPages classifies every page of inFile. texts is the output of
+// extractTextByPage; pass nil to have it extracted here.
+func classifyPages(inFile string, texts map[int]string) ([]pageKind, error) {
+	ctx, err := readContext(inFile, pdfConfig())
+	if err != nil {
+		return nil, err
+	}
+	if texts == nil {
+		if texts, err = pageTexts(ctx, inFile); err != nil {
+			return nil, err
+		}
+	}
+	return classifyContextPages(ctx, texts)
+}
+
+// pageTexts is extractTextByPage for a file already read as ctx. The text
+// library cannot decrypt reliably and panics on some encrypted files, so
+// those are refused, and any other panic is returned as an error.
+func pageTexts(ctx *model.Context, inFile string) (texts map[int]string, err error) {
+	if ctx.Encrypt != nil {
+		return nil, fmt.Errorf("cannot read the text of an encrypted file; decrypt it first")
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			texts, err = nil, fmt.Errorf("failed to extract text: %v", r)
+		}
+	}()
+	if texts, err = extractTextByPage(inFile); err != nil {
+		return nil, fmt.Errorf("failed to extract text: %w", err)
+	}
+	return texts, nil
+}
+
+func classifyContextPages(ctx *model.Context, texts map[int]string) ([]pageKind, error) {
+	kinds := make([]pageKind, ctx.PageCount)
+	for i := 1; i <= ctx.PageCount; i++ {
+		hasImages, err := pageDrawsImages(ctx, i)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", i, err)
+		}
+		hasText := countTextChars(texts[i]) >= minTextChars
+		switch {
+		case hasText && hasImages:
+			kinds[i-1] = kindMixed
+		case hasText:
+			kinds[i-1] = kindText
+		case hasImages:
+			kinds[i-1] = kindImage
+		default:
+			kinds[i-1] = kindBlank
+		}
+	}
+	return kinds, nil
+}
+
+func countTextChars(s string) int {
+	n := 0
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			n++
+		}
+	}
+	return n
+}
+
+var (
+	doOperator  = regexp.MustCompile(`/([^\s/\[\]()<>{}%]+)\s*Do\b`)
+	inlineImage = regexp.MustCompile(`(?:^|\s)BI\s`)
+)
+
+// pageDrawsImages reports whether the page's content actually paints an image,
+// either inline or through an image XObject, possibly nested in form XObjects.
+// Resources that are merely listed but never used do not count.
+func pageDrawsImages(ctx *model.Context, pageNr int) (bool, error) {
+	d, _, inh, err := ctx.PageDict(pageNr, false)
+	if err != nil {
+		return false, err
+	}
+	if d == nil {
+		return false, nil
+	}
+	content, err := ctx.PageContent(d, pageNr)
+	if err == model.ErrNoContent {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	res, err := ctx.DereferenceDict(d["Resources"])
+	if err != nil {
+		return false, err
+	}
+	if res == nil && inh != nil {
+		res = inh.Resources
+	}
+	return contentDrawsImages(ctx, content, res, 0)
+}
+
+func contentDrawsImages(ctx *model.Context, content []byte, res types.Dict, depth int) (bool, error) {
+	if inlineImage.Match(content) {
+		return true, nil
+	}
+	if res == nil || depth > 8 {
+		return false, nil
+	}
+	xobjs, err := ctx.DereferenceDict(res["XObject"])
+	if err != nil || xobjs == nil {
+		return false, err
+	}
+	for _, m := range doOperator.FindAllSubmatch(content, -1) {
+		sd, _, err := ctx.DereferenceStreamDict(xobjs[string(m[1])])
+		if err != nil || sd == nil {
+			continue
+		}
+		st := sd.Subtype()
+		if st == nil {
+			continue
+		}
+		switch *st {
+		case "Image":
+			return true, nil
+		case "Form":
+			if err := sd.Decode(); err != nil {
+				continue
+			}
+			formRes, _ := ctx.DereferenceDict(sd.Dict["Resources"])
+			if formRes == nil {
+				formRes = res
+			}
+			ok, err := contentDrawsImages(ctx, sd.Content, formRes, depth+1)
+			if ok || err != nil {
+				return ok, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// pagesOfKind returns the 1-based page numbers classified as k.
+func pagesOfKind(kinds []pageKind, k pageKind) []int {
+	pages := []int{}
+	for i, pk := range kinds {
+		if pk == k {
+			pages = append(pages, i+1)
+		}
+	}
+	return pages
+}
+
+// countPageKinds tallies kinds, with every kind present in the result.
+func countPageKinds(kinds []pageKind) map[pageKind]int {
+	counts := make(map[pageKind]int, len(pageKinds))
+	for _, k := range pageKinds {
+		counts[k] = 0
+	}
+	for _, k := range kinds {
+		counts[k]++
+	}
+	return counts
+}
+
+// pageKindSummary renders counts like "10 text, 3 image-only, 1 blank".
+func pageKindSummary(kinds []pageKind) string {
+	counts := countPageKinds(kinds)
+	var parts []string
+	for _, k := range pageKinds {
+		if counts[k] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[k], pageKindLabels[k]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// compactPageList renders sorted page numbers as ranges, e.g. "1-3,7,9-10".
+func compactPageList(pages []int) string {
+	pages = append([]int(nil), pages...)
+	sort.Ints(pages)
+	var parts []string
+	for i := 0; i < len(pages); {
+		j := i
+		for j+1 < len(pages) && pages[j+1] == pages[j]+1 {
+			j++
+		}
+		if j == i {
+			parts = append(parts, strconv.Itoa(pages[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", pages[i], pages[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// pagePredicates are the selection keywords answered from page numbers and
+// dimensions rather than from content.
+var pagePredicates = map[string]func(nr int, w, h float64) bool{
+	"odd":       func(nr int, w, h float64) bool { return nr%2 == 1 },
+	"even":      func(nr int, w, h float64) bool { return nr%2 == 0 },
+	"landscape": func(nr int, w, h float64) bool { return w > h },
+	"portrait":  func(nr int, w, h float64) bool { return h > w },
+}
+
+// noPagesMatchError is returned when a page-kind or predicate keyword selects
+// nothing, so callers for whom an empty selection is fine can tell it apart.
+type noPagesMatchError string
+
+func (e noPagesMatchError) Error() string { return string(e) }
+
+// displayedPageSize returns the size of page nr as shown: its crop box,
+// turned by its rotation.
+func displayedPageSize(ctx *model.Context, nr int) (w, h float64, err error) {
+	_, _, inh, err := ctx.PageDict(nr, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	box := inh.CropBox
+	if box == nil {
+		box = inh.MediaBox
+	}
+	if box == nil {
+		return 0, 0, fmt.Errorf("page %d has no MediaBox", nr)
+	}
+	w, h = box.Width(), box.Height()
+	if r := normalizeRotation(inh.Rotate); r == 90 || r == 270 {
+		w, h = h, w
+	}
+	return w, h, nil
+}
+
+// expandPageKinds replaces the page-kind keywords (text, image, mixed, blank),
+// and with predicates also odd, even, landscape and portrait, in a
+// comma-separated page selection with the matching page numbers, leaving
+// every other token untouched. The file is only classified when a keyword is used.
+// A keyword that matches no page is dropped with a note; if that leaves
+// nothing, a noPagesMatchError is returned.
+func expandPageKinds(inFile, sel string, predicates bool) (string, error) {
+	tokens := strings.Split(sel, ",")
+	var kinds []pageKind
+	var ctx *model.Context
+	var unmatched []string
+	for i, tok := range tokens {
+		word := strings.ToLower(strings.TrimSpace(tok))
+		if pred, ok := pagePredicates[word]; ok && predicates {
+			if ctx == nil {
+				var err error
+				if ctx, err = readContext(inFile, pdfConfig()); err != nil {
+					return "", err
+				}
+			}
+			var pages []int
+			for nr := 1; nr <= ctx.PageCount; nr++ {
+				w, h, err := displayedPageSize(ctx, nr)
+				if err != nil {
+					return "", err
+				}
+				if pred(nr, w, h) {
+					pages = append(pages, nr)
+				}
+			}
+			if len(pages) == 0 {
+				unmatched = append(unmatched, fmt.Sprintf("no %s pages in %s", word, inFile))
+				tokens[i] = ""
+				continue
+			}
+			tokens[i] = compactPageList(pages)
+			continue
+		}
+		k := pageKind(word)
+		if _, ok := pageKindLabels[k]; !ok {
+			continue
+		}
+		if kinds == nil {
+			var err error
+			if kinds, err = classifyPages(inFile, nil); err != nil {
+				return "", err
+			}
+		}
+		pages := pagesOfKind(kinds, k)
+		if len(pages) == 0 {
+			unmatched = append(unmatched, fmt.Sprintf("no pages in %s are classified as %s", inFile, pageKindLabels[k]))
+			tokens[i] = ""
+			continue
+		}
+		tokens[i] = compactPageList(pages)
+	}
+	if len(unmatched) == 0 {
+		return strings.Join(tokens, ","), nil
+	}
+	var kept []string
+	for _, tok := range tokens {
+		if strings.TrimSpace(tok) != "" {
+			kept = append(kept, tok)
+		}
+	}
+	if len(kept) == 0 {
+		return "", noPagesMatchError(strings.Join(unmatched, "; "))
+	}
+	for _, m := range unmatched {
+		printInfo(m)
+	}
+	return strings.Join(kept, ","), nil
+}
+
+// selectedPages resolves a -p value, which may use page kinds and
+// predicates, against ctx; nil means every page. A selection whose keywords
+// match no page selects nothing rather than failing, so "rotate every
+// landscape page" can safely run twice.
+func selectedPages(ctx *model.Context, inFile, spec string) (types.IntSet, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	sel, err := expandPageKinds(inFile, spec, true)
+	var none noPagesMatchError
+	if errors.As(err, &none) {
+		printInfo(none.Error())
+		return types.IntSet{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return api.PagesForPageSelection(ctx.PageCount, strings.Split(sel, ","), true, true)
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/pdfcpu/pdfcpu/pkg/api"
 	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
@@ -116,8 +118,8 @@ func walkNumberTree(ctx *model.Context, node types.Dict, entries *[]pageLabelEnt
 				if s := valDict.NameEntry("S"); s != nil {
 					entry.style = *s
 				}
-				if p := valDict.StringEntry("P"); p != nil {
-					entry.prefix = *p
+				if p, ok := decodePDFText(valDict["P"]); ok {
+					entry.prefix = p
 				}
 				if st := valDict.IntEntry("St"); st != nil {
 					entry.startValue = *st
@@ -242,57 +244,276 @@ func reverseString(s string) string {
 	return string(runes)
 }
 
-// resolveRealPages takes a comma-separated range string of real page numbers and
-// resolves them to physical page indices (1-based) using the PDF's page labels.
-func resolveRealPages(rangeStr string, labelsMap pageLabelsMap, pageCount int) ([]int, error) {
-	var result []int
-	seen := make(map[int]bool)
+// labelToken is one lexical piece of a printed-page range string: literal
+// label text, or one of the separators ',', '-' and '@'.
+type labelToken struct {
+	text   string
+	sep    byte
+	quoted bool // text came (at least partly) from quotes or escapes
+}
 
-	parts := strings.Split(rangeStr, ",")
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if part == "" {
-			continue
+// tokenizeLabelRange splits a range string into text and separators. Text in
+// single or double quotes, and any character after a backslash, is literal.
+func tokenizeLabelRange(s string) ([]labelToken, error) {
+	var toks []labelToken
+	var cur strings.Builder
+	quoted, inText := false, false
+	flush := func() {
+		if inText {
+			toks = append(toks, labelToken{text: cur.String(), quoted: quoted})
 		}
+		cur.Reset()
+		quoted, inText = false, false
+	}
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case ',', '-', '@':
+			flush()
+			toks = append(toks, labelToken{sep: byte(r)})
+		case '\\':
+			if i+1 == len(runes) {
+				return nil, fmt.Errorf("trailing backslash in %q", s)
+			}
+			i++
+			cur.WriteRune(runes[i])
+			quoted, inText = true, true
+		case '"', '\'':
+			end := -1
+			for j := i + 1; j < len(runes); j++ {
+				if runes[j] == r {
+					end = j
+					break
+				}
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote in %q", s)
+			}
+			cur.WriteString(string(runes[i+1 : end]))
+			quoted, inText = true, true
+			i = end
+		default:
+			cur.WriteRune(r)
+			inText = true
+		}
+	}
+	flush()
+	return toks, nil
+}
 
-		if strings.Contains(part, "-") {
-			rangeParts := strings.SplitN(part, "-", 2)
-			startLabel := strings.TrimSpace(rangeParts[0])
-			endLabel := strings.TrimSpace(rangeParts[1])
+// labelRef names the pages carrying one label. occurrence picks the n-th page
+// with that label (1-based); 0 means "the only one" and -1 means all of them.
+type labelRef struct {
+	label      string
+	occurrence int
+}
 
-			startPages, ok := labelsMap[startLabel]
-			if !ok {
-				return nil, fmt.Errorf("page label %q not found in PDF", startLabel)
+// String writes r the way it is typed in a range string, always quoted.
+func (r labelRef) String() string {
+	q := `"` + r.label + `"`
+	switch {
+	case r.occurrence > 0:
+		return fmt.Sprintf("%s@%d", q, r.occurrence)
+	case r.occurrence < 0:
+		return q + "@*"
+	}
+	return q
+}
+
+// labelRefFromTokens joins toks into a label with an optional trailing @N or
+// @* qualifier. Unquoted whitespace at either end is dropped.
+func labelRefFromTokens(toks []labelToken) (labelRef, bool) {
+	ref := labelRef{}
+	if n := len(toks); n >= 2 && toks[n-2].sep == '@' && toks[n-1].sep == 0 && !toks[n-1].quoted {
+		q := strings.TrimSpace(toks[n-1].text)
+		if q == "*" {
+			ref.occurrence = -1
+			toks = toks[:n-2]
+		} else if v, err := strconv.Atoi(q); err == nil && v > 0 {
+			ref.occurrence = v
+			toks = toks[:n-2]
+		}
+	}
+	var b strings.Builder
+	for i, t := range toks {
+		text := t.text
+		if t.sep != 0 {
+			text = string(t.sep)
+		} else if !t.quoted {
+			if i == 0 {
+				text = strings.TrimLeftFunc(text, unicode.IsSpace)
 			}
-			endPages, ok := labelsMap[endLabel]
-			if !ok {
-				return nil, fmt.Errorf("page label %q not found in PDF", endLabel)
+			if i == len(toks)-1 {
+				text = strings.TrimRightFunc(text, unicode.IsSpace)
+			}
+		}
+		b.WriteString(text)
+	}
+	ref.label = b.String()
+	if ref.label == "" {
+		for _, t := range toks {
+			if t.quoted {
+				return ref, true // "" names pages with an empty label
 			}
+		}
+		return ref, false
+	}
+	return ref, true
+}
 
-			startPhys := startPages[0]
-			endPhys := endPages[len(endPages)-1]
+// labelReading is one way to read a comma-separated item: a single label,
+// or a range between two labels.
+type labelReading struct {
+	refs []labelRef
+}
 
-			if startPhys > endPhys {
-				return nil, fmt.Errorf("invalid range: page %q (PDF page %d) comes after page %q (PDF page %d)", startLabel, startPhys, endLabel, endPhys)
-			}
+func (r labelReading) String() string {
+	if len(r.refs) == 1 {
+		return r.refs[0].String() + " (one label)"
+	}
+	return r.refs[0].String() + "-" + r.refs[1].String() + " (a range)"
+}
 
-			for i := startPhys; i <= endPhys; i++ {
-				if !seen[i] {
-					result = append(result, i)
-					seen[i] = true
-				}
-			}
-		} else {
-			physPages, ok := labelsMap[part]
+// labelReadings lists every way to read item. A '-' that is neither quoted
+// nor escaped may separate a range or be part of a label such as "A-1";
+// only the readings whose labels all exist are returned.
+func labelReadings(item []labelToken, labelsMap pageLabelsMap) (valid []labelReading, first error) {
+	try := func(parts ...[]labelToken) {
+		var reading labelReading
+		for _, p := range parts {
+			ref, ok := labelRefFromTokens(p)
 			if !ok {
-				return nil, fmt.Errorf("page label %q not found in PDF", part)
+				return
 			}
-			for _, p := range physPages {
-				if !seen[p] {
-					result = append(result, p)
-					seen[p] = true
+			if _, found := labelsMap[ref.label]; !found {
+				if first == nil {
+					first = fmt.Errorf("page label %q not found in PDF", ref.label)
 				}
+				return
 			}
+			reading.refs = append(reading.refs, ref)
+		}
+		valid = append(valid, reading)
+	}
+	for i, t := range item {
+		if t.sep == '-' {
+			try(item[:i], item[i+1:])
+		}
+	}
+	try(item)
+	return valid, first
+}
+
+// pages returns the physical pages ref selects.
+func (r labelRef) pages(labelsMap pageLabelsMap) ([]int, error) {
+	pages := labelsMap[r.label]
+	switch {
+	case r.occurrence < 0:
+		return pages, nil
+	case r.occurrence > len(pages):
+		return nil, fmt.Errorf("page label %q occurs only %d time(s), so @%d does not exist", r.label, len(pages), r.occurrence)
+	case r.occurrence > 0:
+		return pages[r.occurrence-1 : r.occurrence], nil
+	case len(pages) > 1:
+		choices := make([]string, len(pages))
+		for i, p := range pages {
+			choices[i] = fmt.Sprintf("%s@%d (PDF page %d)", quoteLabel(r.label), i+1, p)
+		}
+		return nil, fmt.Errorf("page label %q is used by %d pages; pick one with %s, or all with %s@*",
+			r.label, len(pages), strings.Join(choices, ", "), quoteLabel(r.label))
+	}
+	return pages, nil
+}
+
+// quoteLabel writes label the way it can be typed in a range string.
+func quoteLabel(label string) string {
+	if label != "" && !strings.ContainsAny(label, ",-@\\\"' ") {
+		return label
+	}
+	if strings.Contains(label, `"`) {
+		return "'" + label + "'"
+	}
+	return `"` + label + `"`
+}
+
+// resolveRealPages takes a comma-separated range string of real page numbers and
+// resolves them to physical page indices (1-based) using the PDF's page labels.
+//
+// Labels containing ',', '-' or '@' can be quoted ("A-1") or escaped (A\-1);
+// an unquoted "A-1" also works when it cannot be read as a range. When a label
+// is used by several pages, LABEL@N picks the N-th of them and LABEL@* all.
+func resolveRealPages(rangeStr string, labelsMap pageLabelsMap, pageCount int) ([]int, error) {
+	var result []int
+	seen := make(map[int]bool)
+	add := func(p int) {
+		if !seen[p] {
+			result = append(result, p)
+			seen[p] = true
+		}
+	}
+
+	toks, err := tokenizeLabelRange(rangeStr)
+	if err != nil {
+		return nil, err
+	}
+	var items [][]labelToken
+	start := 0
+	for i := 0; i <= len(toks); i++ {
+		if i == len(toks) || toks[i].sep == ',' {
+			items = append(items, toks[start:i])
+			start = i + 1
+		}
+	}
+
+	for _, item := range items {
+		if _, ok := labelRefFromTokens(item); !ok && len(item) <= 1 {
+			continue // empty item, as in "1,,3"
+		}
+		readings, firstErr := labelReadings(item, labelsMap)
+		if len(readings) == 0 {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("invalid page range item %q", strings.TrimSpace(itemText(item)))
+			}
+			return nil, firstErr
+		}
+		if len(readings) > 1 {
+			alts := make([]string, len(readings))
+			for i, r := range readings {
+				alts[i] = r.String()
+			}
+			return nil, fmt.Errorf("%q is ambiguous: write %s",
+				strings.TrimSpace(itemText(item)), strings.Join(alts, " or "))
+		}
+
+		refs := readings[0].refs
+		if len(refs) == 1 {
+			pages, err := refs[0].pages(labelsMap)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range pages {
+				add(p)
+			}
+			continue
+		}
+
+		if refs[0].occurrence < 0 || refs[1].occurrence < 0 {
+			return nil, fmt.Errorf("@* cannot be used in a range (%s-%s)", refs[0], refs[1])
+		}
+		startPages, err := refs[0].pages(labelsMap)
+		if err != nil {
+			return nil, err
+		}
+		endPages, err := refs[1].pages(labelsMap)
+		if err != nil {
+			return nil, err
+		}
+		startPhys, endPhys := startPages[0], endPages[0]
+		if startPhys > endPhys {
+			return nil, fmt.Errorf("invalid range: page %q (PDF page %d) comes after page %q (PDF page %d)", refs[0].label, startPhys, refs[1].label, endPhys)
+		}
+		for i := startPhys; i <= endPhys && i <= pageCount; i++ {
+			add(i)
 		}
 	}
 
@@ -302,3 +523,16 @@ func resolveRealPages(rangeStr string, labelsMap pageLabelsMap, pageCount int) (
 
 	return result, nil
 }
+
+// itemText reassembles tokens for error messages.
+func itemText(item []labelToken) string {
+	var b strings.Builder
+	for _, t := range item {
+		if t.sep != 0 {
+			b.WriteByte(t.sep)
+		} else {
+			b.WriteString(t.text)
+		}
+	}
+	return b.String()
+}
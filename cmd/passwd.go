@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/spf13/cobra"
+)
+
+var (
+	passwdOutput       string
+	passwdUserPW       string
+	passwdUserFrom     string
+	passwdOwnerPW      string
+	passwdOwnerFrom    string
+	passwdNewUserPW    string
+	passwdNewUserFrom  string
+	passwdNewOwnerPW   string
+	passwdNewOwnerFrom string
+	passwdUnrestrict   bool
+)
+
+var passwdCmd = &cobra.Command{
+	Use:   "passwd <input.pdf>",
+	Short: "Change the passwords of an encrypted PDF",
+	Long: `Changes the user password (required to open), the owner password (required
+to edit), or both, without writing the document out unencrypted. The
+permissions and algorithm stay as they are. Without -o, changes in-place.
+
+Both current passwords are needed: --user-pw (leave it out if the file opens
+without one) and --owner-pw. --new-user-pw "" removes the open password
+while keeping the restrictions.
+
+--remove-restrictions lifts the owner restrictions of a file that opens
+without a password. Such a file protects nothing else, so it is written
+without encryption. Files with a user password are refused; use decrypt.
+
+Every password flag has a --…-from form that reads it from: prompt (new
+passwords are asked twice), stdin, env:NAME, file:PATH or fd:N. Passwords
+from one stream are read in the order current user, current owner, new
+user, new owner.`,
+	Example: `  pdfed passwd report.pdf --user-pw old --owner-pw edit --new-user-pw fresh
+  pdfed passwd report.pdf --owner-pw-from prompt --new-owner-pw-from prompt -o rotated.pdf
+  pdfed passwd form.pdf --owner-pw edit --remove-restrictions`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		changeUser := cmd.Flags().Changed("new-user-pw") || passwdNewUserFrom != ""
+		changeOwner := cmd.Flags().Changed("new-owner-pw") || passwdNewOwnerFrom != ""
+		if passwdUnrestrict && (changeUser || changeOwner) {
+			return fmt.Errorf("--remove-restrictions cannot be combined with new passwords")
+		}
+		if !passwdUnrestrict && !changeUser && !changeOwner {
+			return fmt.Errorf("provide --new-user-pw, --new-owner-pw or --remove-restrictions")
+		}
+		if passwdOwnerPW == "" && passwdOwnerFrom == "" {
+			return fmt.Errorf("provide the current owner password with --owner-pw")
+		}
+		cmd.SilenceUsage = true
+
+		// Read in the documented order, so one stream can carry them all.
+		sources := []struct {
+			from, what string
+			confirm    bool
+			pw         *string
+		}{
+			{passwdUserFrom, "user password", false, &passwdUserPW},
+			{passwdOwnerFrom, "owner password", false, &passwdOwnerPW},
+			{passwdNewUserFrom, "new user password", true, &passwdNewUserPW},
+			{passwdNewOwnerFrom, "new owner password", true, &passwdNewOwnerPW},
+		}
+		for _, s := range sources {
+			if s.from == "" {
+				continue
+			}
+			pw, err := readPassword(s.from, s.what, s.confirm)
+			if err != nil {
+				return err
+			}
+			*s.pw = pw
+		}
+		if passwdUnrestrict {
+			return runUnrestrict(args[0])
+		}
+		var newUser, newOwner *string
+		if changeUser {
+			newUser = &passwdNewUserPW
+		}
+		if changeOwner {
+			if passwdNewOwnerPW == "" {
+				return fmt.Errorf("the new owner password is empty")
+			}
+			newOwner = &passwdNewOwnerPW
+		}
+		return runPasswd(args[0], newUser, newOwner)
+	},
+}
+
+func init() {
+	passwdCmd.Flags().StringVarP(&passwdOutput, "output", "o", "", "Output file (default: in-place)")
+	passwdCmd.Flags().StringVar(&passwdUserPW, "user-pw", "", "Current user password (empty if the file opens without one)")
+	passwdCmd.Flags().StringVar(&passwdUserFrom, "user-pw-from", "", "Read the current user password from "+passwordSourceHelp)
+	passwdCmd.Flags().StringVar(&passwdOwnerPW, "owner-pw", "", "Current owner password")
+	passwdCmd.Flags().StringVar(&passwdOwnerFrom, "owner-pw-from", "", "Read the current owner password from "+passwordSourceHelp)
+	passwdCmd.Flags().StringVar(&passwdNewUserPW, "new-user-pw", "", "New user password (\"\" to open without one)")
+	passwdCmd.Flags().StringVar(&passwdNewUserFrom, "new-user-pw-from", "", "Read the new user password from "+passwordSourceHelp)
+	passwdCmd.Flags().StringVar(&passwdNewOwnerPW, "new-owner-pw", "", "New owner password")
+	passwdCmd.Flags().StringVar(&passwdNewOwnerFrom, "new-owner-pw-from", "", "Read the new owner password from "+passwordSourceHelp)
+	passwdCmd.Flags().BoolVar(&passwdUnrestrict, "remove-restrictions", false, "Lift the owner restrictions of a file that opens without a password")
+	for _, name := range []string{"user-pw", "owner-pw", "new-user-pw", "new-owner-pw"} {
+		passwdCmd.MarkFlagsMutuallyExclusive(name, name+"-from")
+	}
+	rootCmd.AddCommand(passwdCmd)
+}
+
+// passwordError puts pdfcpu's password errors in terms of passwd's flags.
+func passwordError(err error) error {
+	switch {
+	case errors.Is(err, pdfcpu.ErrWrongPassword):
+		return fmt.Errorf("wrong user password (--user-pw)")
+	case strings.Contains(err.Error(), "owner password"):
+		return fmt.Errorf("wrong owner password (--owner-pw)")
+	}
+	return err
+}
+
+// runPasswd rewrites inFile with new passwords; a nil password stays as it is.
+func runPasswd(inFile string, newUser, newOwner *string) error {
+	printInfo(fmt.Sprintf("Changing passwords of %s…", inFile))
+
+	src, err := os.ReadFile(inFile)
+	if err != nil {
+		return err
+	}
+	conf := pdfConfig()
+	conf.Cmd = model.CHANGEOPW // either change mode checks both current passwords
+	conf.UserPW = passwdUserPW
+	conf.OwnerPW = passwdOwnerPW
+	conf.UserPWNew = newUser
+	conf.OwnerPWNew = newOwner
+	var buf bytes.Buffer
+	if err := api.Optimize(bytes.NewReader(src), &buf, conf); err != nil {
+		return passwordError(err)
+	}
+
+	outFile := passwdOutput
+	if outFile == "" {
+		outFile = inFile
+	}
+	if err := writeFileAtomic(outFile, buf.Bytes()); err != nil {
+		return err
+	}
+
+	userPW, ownerPW := passwdUserPW, passwdOwnerPW
+	if newUser != nil {
+		userPW = *newUser
+	}
+	if newOwner != nil {
+		ownerPW = *newOwner
+	}
+	enc, err := readBackEncryption(outFile, userPW, ownerPW)
+	if err != nil {
+		return err
+	}
+
+	var changed []string
+	if newUser != nil {
+		changed = append(changed, "user")
+	}
+	if newOwner != nil {
+		changed = append(changed, "owner")
+	}
+	printSuccess(fmt.Sprintf("Changed %s password: %s (%s)", strings.Join(changed, " and "), outFile, humanSize(int64(buf.Len()))))
+	if userPW == "" {
+		printInfo("The file opens without a password")
+	}
+	printEncryptionSummary(enc, userPW, ownerPW)
+	if jsonOut {
+		return jsonResultOK("passwd", map[string]interface{}{
+			"input":         inFile,
+			"output":        outFile,
+			"in_place":      outFile == inFile,
+			"changed_user":  newUser != nil,
+			"changed_owner": newOwner != nil,
+			"user_password": userPW != "",
+			"encryption":    enc,
+			"size_bytes":    buf.Len(),
+			"size_human":    humanSize(int64(buf.Len())),
+		})
+	}
+	return nil
+}
+
+// runUnrestrict writes inFile without encryption, provided it opens without
+// a password, so that nothing but the owner restrictions is lost.
+func runUnrestrict(inFile string) error {
+	printInfo(fmt.Sprintf("Removing restrictions from %s…", inFile))
+
+	src, err := os.ReadFile(inFile)
+	if err != nil {
+		return err
+	}
+	// pdfcpu decrypts such a file without the owner password; check it, and
+	// that the user password is empty, by opening it as for a change.
+	open := pdfConfig()
+	open.Cmd = model.CHANGEOPW
+	open.OwnerPW = passwdOwnerPW
+	ctx, err := readContextFrom(bytes.NewReader(src), open)
+	if err != nil {
+		if errors.Is(err, pdfcpu.ErrWrongPassword) {
+			return fmt.Errorf("%s needs a password to open; use decrypt to remove it along with the restrictions", inFile)
+		}
+		return passwordError(err)
+	}
+	enc, err := describeEncryption(ctx)
+	if err != nil {
+		return err
+	}
+	if enc == nil {
+		return fmt.Errorf("%s is not encrypted", inFile)
+	}
+
+	conf := pdfConfig()
+	conf.Cmd = model.DECRYPT
+	conf.OwnerPW = passwdOwnerPW
+	var buf bytes.Buffer
+	if err := api.Optimize(bytes.NewReader(src), &buf, conf); err != nil {
+		return passwordError(err)
+	}
+
+	outFile := passwdOutput
+	if outFile == "" {
+		outFile = inFile
+	}
+	if err := writeFileAtomic(outFile, buf.Bytes()); err != nil {
+		return err
+	}
+
+	var lifted []string
+	for _, p := range enc.Permissions.rows() {
+		if !p.on {
+			lifted = append(lifted, p.label)
+		}
+	}
+	printSuccess(fmt.Sprintf("Restrictions removed: %s (%s)", outFile, humanSize(int64(buf.Len()))))
+	if len(lifted) > 0 {
+		printInfo("Now allowed: " + strings.Join(lifted, ", "))
+	} else {
+		printInfo("Nothing was restricted; the file is no longer encrypted")
+	}
+	if jsonOut {
+		return jsonResultOK("passwd", map[string]interface{}{
+			"input":                inFile,
+			"output":               outFile,
+			"in_place":             outFile == inFile,
+			"removed_restrictions": true,
+			"previous_encryption":  enc,
+			"size_bytes":           buf.Len(),
+			"size_human":           humanSize(int64(buf.Len())),
+		})
+	}
+	return nil
+}
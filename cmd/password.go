@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// passwordSourceHelp lists the forms a --…-from flag takes.
+const passwordSourceHelp = "prompt, stdin, env:NAME, file:PATH or fd:N"
+
+// passwordReaders keeps stdin, files and numbered descriptors open between
+// reads, so that one source can carry several passwords, one per line.
+var passwordReaders = map[string]*bufio.Reader{}
+
+// readPassword reads a password from source. what names the password in
+// prompts and errors; confirm asks for a prompted password twice.
+func readPassword(source, what string, confirm bool) (string, error) {
+	kind, arg, _ := strings.Cut(source, ":")
+	switch kind {
+	case "prompt":
+		return promptPassword(what, confirm)
+	case "stdin":
+		r := passwordReaders[kind]
+		if r == nil {
+			r = bufio.NewReader(os.Stdin)
+			passwordReaders[kind] = r
+		}
+		return readPasswordLine(r, what, "stdin")
+	case "env":
+		v, ok := os.LookupEnv(arg)
+		if arg == "" || !ok {
+			return "", fmt.Errorf("%s: environment variable %q is not set", what, arg)
+		}
+		return v, nil
+	case "file":
+		r := passwordReaders[source]
+		if r == nil {
+			data, err := os.ReadFile(arg)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", what, err)
+			}
+			r = bufio.NewReader(bytes.NewReader(data))
+			passwordReaders[source] = r
+		}
+		return readPasswordLine(r, what, arg)
+	case "fd":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("%s: bad file descriptor %q", what, arg)
+		}
+		r := passwordReaders[source]
+		if r == nil {
+			r = bufio.NewReader(os.NewFile(uintptr(n), "fd "+arg))
+			passwordReaders[source] = r
+		}
+		return readPasswordLine(r, what, "fd "+arg)
+	}
+	return "", fmt.Errorf("unknown password source %q (use %s)", source, passwordSourceHelp)
+}
+
+// readPasswordLine reads one line from r, without its line ending.
+func readPasswordLine(r *bufio.Reader, what, from string) (string, error) {
+	line, err := r.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return "", fmt.Errorf("%s: nothing to read from %s", what, from)
+	}
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("%s: reading %s: %w", what, from, err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// promptPassword asks for a password on the terminal without echoing it.
+func promptPassword(what string, confirm bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("%s: cannot prompt, stdin is not a terminal (use stdin, env:NAME, file:PATH or fd:N)", what)
+	}
+	ask := func(label string) (string, error) {
+		fmt.Fprintf(os.Stderr, "%s: ", label)
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(b), err
+	}
+	label := strings.ToUpper(what[:1]) + what[1:]
+	pw, err := ask(label)
+	if err != nil || !confirm {
+		return pw, err
+	}
+	again, err := ask("Repeat " + what)
+	if err != nil {
+		return "", err
+	}
+	if again != pw {
+		return "", fmt.Errorf("%s: the two entries do not match", what)
+	}
+	return pw, nil
+}
+
+// stdinIsTerminal reports whether a password can be prompted for.
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
@@ -0,0 +1,548 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// prunableKinds are the resource categories pruneResources cleans up.
+var prunableKinds = []string{"Font", "XObject", "ExtGState"}
+
+// dictID identifies a dictionary by the map behind it, so a dictionary
+// reached through different references is recognized as the same one.
+func dictID(d types.Dict) uintptr {
+	if d == nil {
+		return 0
+	}
+	return reflect.ValueOf(d).Pointer()
+}
+
+// drawnContent is a content stream as it is drawn: a page's contents, a
+// form, an annotation appearance or a Type 3 glyph procedure.
+type drawnContent struct {
+	content []byte     // decoded; nil if it could not be decoded
+	res     types.Dict // the resources its names refer to
+	page    int
+}
+
+// contentWalk is the result of walkDrawnContent: which dictionaries were
+// looked at on the way, and which objects something else refers to.
+type contentWalk struct {
+	owners    map[uintptr]bool       // dicts whose Resources were followed
+	resources map[uintptr]types.Dict // Resources dicts content was drawn with
+	resNrs    map[uintptr]int        // their object numbers, if indirect
+	foreign   map[int]bool           // see foreignRefs
+}
+
+// resourcesOwner returns the page or page tree node whose Resources apply
+// to page d.
+func resourcesOwner(ctx *model.Context, d types.Dict) types.Dict {
+	for i := 0; d != nil && i < 32; i++ {
+		if _, ok := d["Resources"]; ok {
+			return d
+		}
+		d, _ = ctx.DereferenceDict(d["Parent"])
+	}
+	return nil
+}
+
+func decodedContent(ctx *model.Context, o types.Object) []byte {
+	sd, _, err := ctx.DereferenceStreamDict(o)
+	if err != nil || sd == nil {
+		return nil
+	}
+	if err := sd.Decode(); err != nil {
+		return nil
+	}
+	if sd.Content == nil {
+		return []byte{}
+	}
+	return sd.Content
+}
+
+// walkDrawnContent calls fn for every content stream the pages draw, with
+// the resources that apply to it. A form is visited once for each set of
+// resources it is drawn with; forms without their own resources use the
+// resources of whatever draws them.
+func walkDrawnContent(ctx *model.Context, fn func(c drawnContent)) *contentWalk {
+	w := &contentWalk{owners: map[uintptr]bool{}, resources: map[uintptr]types.Dict{}}
+	type visitKey struct {
+		obj int
+		res uintptr
+	}
+	seen := map[visitKey]bool{}
+	var visit func(content []byte, res types.Dict, page, depth int)
+	visitStream := func(ir types.IndirectRef, res types.Dict, page, depth int) {
+		sd, _, err := ctx.DereferenceStreamDict(ir)
+		if err != nil || sd == nil {
+			return
+		}
+		own, _ := ctx.DereferenceDict(sd.Dict["Resources"])
+		if own != nil {
+			res = own
+			w.owners[dictID(sd.Dict)] = true
+		}
+		key := visitKey{ir.ObjectNumber.Value(), dictID(res)}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		visit(decodedContent(ctx, ir), res, page, depth+1)
+	}
+	visit = func(content []byte, res types.Dict, page, depth int) {
+		if res != nil {
+			w.resources[dictID(res)] = res
+		}
+		fn(drawnContent{content, res, page})
+		if content == nil || depth > 16 {
+			return
+		}
+		xobjs, _ := ctx.DereferenceDict(res["XObject"])
+		fonts, _ := ctx.DereferenceDict(res["Font"])
+		scanContentOps(content, func(op string, args []string, _, _ int) {
+			if len(args) == 0 || len(args[0]) < 2 || args[0][0] != '/' {
+				return
+			}
+			name := args[0][1:]
+			switch op {
+			case "Do":
+				ir, ok := xobjs[name].(types.IndirectRef)
+				if !ok {
+					return
+				}
+				if sd, _, err := ctx.DereferenceStreamDict(ir); err == nil && sd != nil && sd.Subtype() != nil && *sd.Subtype() == "Form" {
+					visitStream(ir, res, page, depth)
+				}
+			case "Tf":
+				font, _ := ctx.DereferenceDict(fonts[name])
+				if font == nil || font.Subtype() == nil || *font.Subtype() != "Type3" {
+					return
+				}
+				fontRes, _ := ctx.DereferenceDict(font["Resources"])
+				if fontRes != nil {
+					w.owners[dictID(font)] = true
+				} else {
+					fontRes = res
+				}
+				procs, _ := ctx.DereferenceDict(font["CharProcs"])
+				for _, o := range procs {
+					if ir, ok := o.(types.IndirectRef); ok {
+						visitStream(ir, fontRes, page, depth)
+					}
+				}
+			}
+		})
+	}
+
+	for nr := 1; nr <= ctx.PageCount; nr++ {
+		d, _, _, err := ctx.PageDict(nr, false)
+		if err != nil || d == nil {
+			continue
+		}
+		owner := resourcesOwner(ctx, d)
+		res, _ := ctx.DereferenceDict(owner["Resources"])
+		if owner != nil {
+			w.owners[dictID(owner)] = true
+		}
+
+		// A page's content streams are one stream split in parts.
+		var parts []types.Object
+		switch c := d["Contents"].(type) {
+		case types.IndirectRef:
+			if a, err := ctx.DereferenceArray(c); err == nil && a != nil {
+				parts = a
+			} else {
+				parts = []types.Object{c}
+			}
+		case types.Array:
+			parts = c
+		}
+		var content []byte
+		for _, o := range parts {
+			b := decodedContent(ctx, o)
+			if b == nil {
+				content = nil
+				break
+			}
+			content = append(append(content, b...), '\n')
+		}
+		if len(parts) == 0 {
+			content = []byte{}
+		}
+		visit(content, res, nr, 0)
+
+		annots, _ := ctx.DereferenceArray(d["Annots"])
+		for _, a := range annots {
+			ad, _ := ctx.DereferenceDict(a)
+			ap, _ := ctx.DereferenceDict(ad["AP"])
+			for _, k := range []string{"N", "R", "D"} {
+				var streams []types.IndirectRef
+				if ir, ok := ap[k].(types.IndirectRef); ok {
+					if states, err := ctx.DereferenceDict(ir); err == nil && states != nil {
+						for _, s := range states {
+							if sir, ok := s.(types.IndirectRef); ok {
+								streams = append(streams, sir)
+							}
+						}
+					} else {
+						streams = append(streams, ir)
+					}
+				} else if states, ok := ap[k].(types.Dict); ok {
+					for _, s := range states {
+						if sir, ok := s.(types.IndirectRef); ok {
+							streams = append(streams, sir)
+						}
+					}
+				}
+				for _, ir := range streams {
+					visitStream(ir, nil, nr, 0)
+				}
+			}
+		}
+	}
+
+	w.resNrs = map[uintptr]int{}
+	for nr := range ctx.Table {
+		if o, ok := tableObject(ctx, nr); ok {
+			if d, ok := o.(types.Dict); ok && w.resources[dictID(d)] != nil {
+				w.resNrs[dictID(d)] = nr
+			}
+		}
+	}
+	w.foreign = w.foreignRefs(ctx)
+	return w
+}
+
+// foreignRefs returns the objects referenced from anywhere other than the
+// resource entries walkDrawnContent followed. Resources referenced from
+// elsewhere (a pattern, a form nothing draws, the AcroForm defaults) may be
+// used by content that was not looked at.
+func (w *contentWalk) foreignRefs(ctx *model.Context) map[int]bool {
+	categories := map[uintptr]bool{}
+	for _, res := range w.resources {
+		for _, kind := range prunableKinds {
+			if d, _ := ctx.DereferenceDict(res[kind]); d != nil {
+				categories[dictID(d)] = true
+			}
+		}
+	}
+	// Everything the catalog reaches is looked at, but references along
+	// the followed resource entries do not count.
+	refs, visited := map[int]bool{}, map[int]bool{}
+	var walk func(o types.Object, count bool)
+	walkDict := func(d types.Dict) {
+		id := dictID(d)
+		// Every page was walked with the resources that apply to it, so
+		// page tree nodes' Resources nobody inherits are never drawn with.
+		node := d.Type() != nil && *d.Type() == "Pages"
+		for k, v := range d {
+			switch {
+			case categories[id]:
+				walk(v, false)
+			case k == "Resources" && w.owners[id]:
+				walk(v, false)
+			case k == "Resources" && node:
+			case w.resources[id] != nil && (k == "Font" || k == "XObject" || k == "ExtGState"):
+				walk(v, false)
+			default:
+				walk(v, true)
+			}
+		}
+	}
+	walk = func(o types.Object, count bool) {
+		switch v := o.(type) {
+		case types.IndirectRef:
+			nr := v.ObjectNumber.Value()
+			if count {
+				refs[nr] = true
+			}
+			if visited[nr] {
+				return
+			}
+			visited[nr] = true
+			if o, ok := tableObject(ctx, nr); ok {
+				walk(o, true)
+			}
+		case types.Dict:
+			walkDict(v)
+		case types.StreamDict:
+			walkDict(v.Dict)
+		case types.Array:
+			for _, e := range v {
+				walk(e, count)
+			}
+		}
+	}
+	// pdfcpu writes only what the catalog reaches; Resources dicts it
+	// replaced with page copies do not count.
+	if ctx.Root != nil {
+		walk(*ctx.Root, false)
+	}
+	return refs
+}
+
+// exposed reports whether content the walk did not see may draw with the
+// kind entries of res: the Resources dict or its kind dict is referenced
+// from elsewhere too.
+func (w *contentWalk) exposed(res types.Dict, kind string) bool {
+	if nr := w.resNrs[dictID(res)]; nr != 0 && w.foreign[nr] {
+		return true
+	}
+	ir, ok := res[kind].(types.IndirectRef)
+	return ok && w.foreign[ir.ObjectNumber.Value()]
+}
+
+// prunedResource is a resource entry that no content uses.
+type prunedResource struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Pages string `json:"pages"`
+}
+
+// pruneStats is what pruneResources removed.
+type pruneStats struct {
+	Fonts      int              `json:"fonts"`
+	XObjects   int              `json:"xobjects"`
+	ExtGStates int              `json:"extgstates"`
+	Removed    []prunedResource `json:"removed"`
+}
+
+func (s pruneStats) total() int { return s.Fonts + s.XObjects + s.ExtGStates }
+
+func printPruneStats(st pruneStats) {
+	if st.total() == 0 {
+		printInfo("Resources: nothing unused")
+		return
+	}
+	var parts []string
+	add := func(n int, what string) {
+		if n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, what))
+		}
+	}
+	add(st.Fonts, "font(s)")
+	add(st.XObjects, "XObject(s)")
+	add(st.ExtGStates, "graphics state(s)")
+	printInfo(fmt.Sprintf("Resources: removed %s no page uses", strings.Join(parts, ", ")))
+	const shown = 12
+	for i, r := range st.Removed {
+		if i == shown {
+			printf("    %s\n", dimStyle.Render(fmt.Sprintf("… and %d more", len(st.Removed)-shown)))
+			break
+		}
+		where := "page " + r.Pages
+		if strings.ContainsAny(r.Pages, ",-") {
+			where = "pages " + r.Pages
+		}
+		printf("    %s\n", dimStyle.Render(fmt.Sprintf("%-9s /%s  (%s)", r.Kind, r.Name, where)))
+	}
+}
+
+// resourceNames returns the names content uses from each prunable category.
+func resourceNames(content []byte) map[string]map[string]bool {
+	used := map[string]map[string]bool{"Font": {}, "XObject": {}, "ExtGState": {}}
+	scanContentOps(content, func(op string, args []string, _, _ int) {
+		if len(args) == 0 || len(args[0]) < 2 || args[0][0] != '/' {
+			return
+		}
+		switch op {
+		case "Tf":
+			used["Font"][args[0][1:]] = true
+		case "Do":
+			used["XObject"][args[0][1:]] = true
+		case "gs":
+			used["ExtGState"][args[0][1:]] = true
+		}
+	})
+	return used
+}
+
+// pruneResources removes fonts, XObjects and graphics states that no
+// content stream drawn with them refers to. Resource dictionaries that
+// could be used by content it cannot see, or by content it cannot decode,
+// are left alone.
+func pruneResources(ctx *model.Context) pruneStats {
+	type category struct {
+		kind   string
+		dict   types.Dict
+		res    []types.Dict // the Resources dicts it was reached from
+		used   map[string]bool
+		pages  map[int]bool
+		unsafe bool
+	}
+	cats := map[uintptr]*category{}
+	var order []uintptr
+	walk := walkDrawnContent(ctx, func(c drawnContent) {
+		var used map[string]map[string]bool
+		if c.content != nil {
+			used = resourceNames(c.content)
+		}
+		for _, kind := range prunableKinds {
+			d, _ := ctx.DereferenceDict(c.res[kind])
+			if d == nil {
+				continue
+			}
+			id := dictID(d)
+			cat := cats[id]
+			if cat == nil {
+				cat = &category{kind: kind, dict: d, used: map[string]bool{}, pages: map[int]bool{}}
+				cats[id] = cat
+				order = append(order, id)
+			}
+			cat.res = append(cat.res, c.res)
+			cat.pages[c.page] = true
+			if used == nil {
+				cat.unsafe = true
+				continue
+			}
+			for n := range used[kind] {
+				cat.used[n] = true
+			}
+		}
+	})
+	for _, cat := range cats {
+		for _, res := range cat.res {
+			if walk.exposed(res, cat.kind) {
+				cat.unsafe = true
+			}
+		}
+	}
+
+	var st pruneStats
+	for _, id := range order {
+		cat := cats[id]
+		if cat.unsafe {
+			continue
+		}
+		var names []string
+		for n := range cat.dict {
+			if !cat.used[n] {
+				names = append(names, n)
+			}
+		}
+		sort.Strings(names)
+		var pages []int
+		for p := range cat.pages {
+			pages = append(pages, p)
+		}
+		sort.Ints(pages)
+		for _, n := range names {
+			delete(cat.dict, n)
+			st.Removed = append(st.Removed, prunedResource{cat.kind, n, compactPageList(pages)})
+			switch cat.kind {
+			case "Font":
+				st.Fonts++
+			case "XObject":
+				st.XObjects++
+			case "ExtGState":
+				st.ExtGStates++
+			}
+		}
+	}
+	return st
+}
+
+// textStrings returns the strings among the operands in b, decoded.
+func textStrings(b []byte) [][]byte {
+	var out [][]byte
+	for i := 0; i < len(b); i++ {
+		switch b[i] {
+		case '(':
+			var s []byte
+			depth := 1
+			for i++; i < len(b); i++ {
+				c := b[i]
+				if c == '\\' && i+1 < len(b) {
+					i++
+					switch c = b[i]; c {
+					case 'n':
+						s = append(s, '\n')
+					case 'r':
+						s = append(s, '\r')
+					case 't':
+						s = append(s, '\t')
+					case 'b':
+						s = append(s, '\b')
+					case 'f':
+						s = append(s, '\f')
+					case '\r':
+						if i+1 < len(b) && b[i+1] == '\n' {
+							i++
+						}
+					case '\n':
+					default:
+						if c >= '0' && c <= '7' {
+							v := 0
+							for n := 0; n < 3 && i < len(b) && b[i] >= '0' && b[i] <= '7'; n++ {
+								v = v*8 + int(b[i]-'0')
+								i++
+							}
+							i--
+							s = append(s, byte(v))
+						} else {
+							s = append(s, c)
+						}
+					}
+					continue
+				}
+				if c == '(' {
+					depth++
+				} else if c == ')' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+				s = append(s, c)
+			}
+			out = append(out, s)
+		case '<':
+			if i+1 < len(b) && b[i+1] == '<' {
+				i++
+				continue
+			}
+			j := bytes.IndexByte(b[i:], '>')
+			if j < 0 {
+				return out
+			}
+			out = append(out, hexBytes(b[i+1:i+j]))
+			i += j
+		}
+	}
+	return out
+}
+
+// hexBytes decodes the digits of a hex string, ignoring whitespace; an odd
+// final digit is followed by 0.
+func hexBytes(h []byte) []byte {
+	var out []byte
+	var cur byte
+	n := 0
+	for _, c := range h {
+		var v byte
+		switch {
+		case c >= '0' && c <= '9':
+			v = c - '0'
+		case c >= 'a' && c <= 'f':
+			v = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			v = c - 'A' + 10
+		default:
+			continue
+		}
+		cur = cur<<4 | v
+		if n++; n == 2 {
+			out = append(out, cur)
+			cur, n = 0, 0
+		}
+	}
+	if n == 1 {
+		out = append(out, cur<<4)
+	}
+	return out
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/fatih/color"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
 	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
 	"github.com/spf13/cobra"
 )
@@ -43,6 +45,47 @@ func pdfConfig() *model.Configuration {
 	return model.NewDefaultConfiguration()
 }
 
+// readContext opens a PDF and parses it into a pdfcpu context (without validation).
+func readContext(inFile string, conf *model.Configuration) (*model.Context, error) {
+	f, err := os.Open(inFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readContextFrom(f, conf)
+}
+
+// readContextFrom is readContext for a file that is already open or in memory.
+func readContextFrom(rs io.ReadSeeker, conf *model.Configuration) (*model.Context, error) {
+	ctx, err := api.ReadContext(rs, conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF context: %w", err)
+	}
+	if err := ctx.EnsurePageCount(); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// readValidatedContext reads and validates a PDF, as commands that rewrite
+// the whole file need.
+func readValidatedContext(inFile string) (*model.Context, error) {
+	f, err := os.Open(inFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ctx, err := api.ReadAndValidate(f, pdfConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	if err := ctx.EnsurePageCount(); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
 var version = "0.2.0"
 
 var quiet bool
@@ -69,10 +112,18 @@ var rootCmd = &cobra.Command{
   • %s    Extract pages or page ranges from a PDF
   • %s    Combine multiple PDFs into one
   • %s     Show PDF metadata and properties
+  • %s     Edit title, author and other metadata
+  • %s    Remove authors, software fingerprints and comments
+  • %s Remove JavaScript, launch actions and embedded executables
+  • %s   Show, translate, set or remove page labels
   • %s    Rotate pages (90, 180, 270°)
   • %s  Compress and reduce file size
+  • %s Convert pages to grayscale or black and white
+  • %s   Show what is taking up space
   • %s   Password-protect a PDF
   • %s    Remove password protection
+  • %s     Change passwords or remove restrictions
+  • %s Verify digital signatures
   • %s    Fuzzy-search text across a PDF
   • %s Add images as new pages (or build a PDF from images)
 
@@ -81,9 +132,18 @@ var rootCmd = &cobra.Command{
   pdfed split input.pdf                   Interactive split TUI
   pdfed merge out.pdf a.pdf b.pdf         Merge PDFs
   pdfed info input.pdf                    Show metadata
+  pdfed meta input.pdf --set Title=Notes  Edit metadata
+  pdfed scrub input.pdf -o public.pdf     Strip identifying data
+  pdfed sanitize upload.pdf --report-only Check for active content
+  pdfed labels book.pdf --range 9:D       Set page labels
   pdfed rotate input.pdf 90 -p 1-3       Rotate pages 1-3
+  pdfed rotate scan.pdf auto              Turn pages upright by their text
   pdfed optimize input.pdf -o out.pdf     Compress PDF
+  pdfed optimize scan.pdf --preset ebook  Downsample images to 150 DPI
+  pdfed grayscale scan.pdf --mono         Black-and-white scan
+  pdfed analyze input.pdf                 Size breakdown
   pdfed encrypt input.pdf --user-pw pass  Password-protect
+  pdfed signatures signed.pdf             Verify signatures
   pdfed search input.pdf                  Fuzzy search TUI
   pdfed add-images doc.pdf scan.png       Append image as new page
 
@@ -93,10 +153,18 @@ var rootCmd = &cobra.Command{
 		cyan("split"),
 		cyan("merge"),
 		cyan("info"),
+		cyan("meta"),
+		cyan("scrub"),
+		cyan("sanitize"),
+		cyan("labels"),
 		cyan("rotate"),
 		cyan("optimize"),
+		cyan("grayscale"),
+		cyan("analyze"),
 		cyan("encrypt"),
 		cyan("decrypt"),
+		cyan("passwd"),
+		cyan("signatures"),
 		cyan("search"),
 		cyan("add-images"),
 		bold("Examples:"),
@@ -108,7 +176,7 @@ var rootCmd = &cobra.Command{
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		if jsonOut {
-			_ = jsonEmit(map[string]interface{}{"ok": false, "error": err.Error()})
+			_ = jsonEmit(jsonFailureFields(err))
 		} else {
 			fmt.Fprintln(os.Stderr, red("Error:"), err)
 		}
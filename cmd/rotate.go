@@ -2,31 +2,70 @@ package cmd
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
-	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
 	"github.com/spf13/cobra"
 )
 
 var (
-	rotateOutput string
-	rotateDryRun bool
+	rotateOutput   string
+	rotateDryRun   bool
+	rotateAbsolute bool
+	rotateBake     bool
 )
 
 var rotateCmd = &cobra.Command{
-	Use:   "rotate <input.pdf> <degrees>",
-	Short: "Rotate pages in a PDF (90, 180, 270)",
+	Use:   "rotate <input.pdf> <degrees|auto|reset>",
+	Short: "Rotate pages in a PDF (90, 180, 270, or auto-orient)",
 	Long: `Rotate pages clockwise by the specified degrees (must be a multiple of 90).
-Without -o, the file is rotated in-place.`,
+Without -o, the file is rotated in-place.
+
+-p also accepts the page kinds text, image, mixed and blank, so
+"pdfed rotate scan.pdf 90 -p image" turns only the scanned pages, and the
+predicates odd, even, landscape and portrait. Landscape and portrait go by
+each page's size as displayed, so "pdfed rotate scan.pdf 90 -p landscape"
+turns every landscape page to portrait, and "pdfed rotate scan.pdf 180 -p even"
+fixes the flip side of a duplex scan. The selected pages are listed.
+
+"auto" works out which way each page's text runs from the baseline every
+glyph is drawn on and sets the rotation that makes it read upright, e.g.
+"pdfed rotate scan.pdf auto --dry-run". Pages without text, or whose text
+runs in no clear direction, are left alone and reported.
+
+--absolute sets the rotation to exactly the given degrees instead of adding
+to it, so running the same command twice does no harm. "reset" sets every
+selected page back to 0.
+
+--bake moves the rotation into the page content: the page is drawn turned
+and its rotation becomes 0, so it looks the same in viewers and also in
+tools that ignore rotation. "pdfed rotate doc.pdf 0 --bake" bakes the
+existing rotation without changing how pages look.`,
 	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		switch strings.ToLower(args[1]) {
+		case "auto":
+			cmd.SilenceUsage = true
+			return runAutoRotate(args[0])
+		case "reset":
+			cmd.SilenceUsage = true
+			return runRotate(args[0], 0, "reset")
+		}
 		deg, err := strconv.Atoi(args[1])
 		if err != nil || deg%90 != 0 {
-			return fmt.Errorf("degrees must be a multiple of 90 (e.g. 90, 180, 270)")
+			return fmt.Errorf("degrees must be a multiple of 90 (e.g. 90, 180, 270), auto or reset")
+		}
+		cmd.SilenceUsage = true
+		mode := "relative"
+		if rotateAbsolute {
+			mode = "absolute"
 		}
-		return runRotate(args[0], deg)
+		return runRotate(args[0], deg, mode)
 	},
 }
 
@@ -36,78 +75,343 @@ func init() {
 	rotateCmd.Flags().StringVarP(&rotatePages, "pages", "p", "", "Page ranges to rotate (e.g. 1-3,5)")
 	rotateCmd.Flags().StringVarP(&rotateOutput, "output", "o", "", "Output file (default: in-place)")
 	rotateCmd.Flags().BoolVarP(&rotateDryRun, "dry-run", "n", false, "Preview without writing")
+	rotateCmd.Flags().BoolVarP(&rotateAbsolute, "absolute", "a", false, "Set the rotation to exactly <degrees> instead of adding to it")
+	rotateCmd.Flags().BoolVar(&rotateBake, "bake", false, "Move the rotation into the page content and set /Rotate to 0")
 	rootCmd.AddCommand(rotateCmd)
 }
 
-func runRotate(inFile string, degrees int) error {
-	var pages []string
-	if rotatePages != "" {
-		pages = strings.Split(rotatePages, ",")
+// rotateChange is one page whose rotation a rotate run changes.
+type rotateChange struct {
+	Page  int  `json:"page"`
+	From  int  `json:"from"`
+	To    int  `json:"to"`
+	Baked bool `json:"baked,omitempty"`
+}
+
+// selectedRotatePages resolves -p against ctx; nil means every page.
+func selectedRotatePages(ctx *model.Context, inFile string) (types.IntSet, error) {
+	return selectedPages(ctx, inFile, rotatePages)
+}
+
+// selectedPageList returns the selected page numbers in order.
+func selectedPageList(selected types.IntSet, pageCount int) []int {
+	pages := []int{}
+	for nr := 1; nr <= pageCount; nr++ {
+		if selected == nil || selected[nr] {
+			pages = append(pages, nr)
+		}
+	}
+	return pages
+}
+
+func runRotate(inFile string, degrees int, mode string) error {
+	ctx, err := readValidatedContext(inFile)
+	if err != nil {
+		return err
+	}
+	selected, err := selectedRotatePages(ctx, inFile)
+	if err != nil {
+		return err
 	}
 
 	pageDesc := "all pages"
+	if selected != nil {
+		pageDesc = fmt.Sprintf("pages %s", compactPageList(selectedPageList(selected, ctx.PageCount)))
+		if len(selected) == 0 {
+			pageDesc = "no pages"
+		}
+	}
+	switch mode {
+	case "reset":
+		printInfo(fmt.Sprintf("Resetting the rotation of %s in %s…", pageDesc, inFile))
+	case "absolute":
+		printInfo(fmt.Sprintf("Setting the rotation of %s to %d° in %s…", pageDesc, normalizeRotation(degrees), inFile))
+	default:
+		printInfo(fmt.Sprintf("Rotating %s by %d° in %s…", pageDesc, degrees, inFile))
+	}
+
+	rotations := map[int]int{}
+	for nr := 1; nr <= ctx.PageCount; nr++ {
+		if selected != nil && !selected[nr] {
+			continue
+		}
+		_, _, inh, err := ctx.PageDict(nr, false)
+		if err != nil {
+			return err
+		}
+		switch mode {
+		case "relative":
+			rotations[nr] = normalizeRotation(inh.Rotate + degrees)
+		default:
+			rotations[nr] = normalizeRotation(degrees)
+		}
+	}
+
+	fields := map[string]interface{}{
+		"input":    inFile,
+		"mode":     mode,
+		"degrees":  degrees,
+		"selected": selectedPageList(selected, ctx.PageCount),
+	}
 	if rotatePages != "" {
-		pageDesc = "pages " + rotatePages
+		fields["pages"] = rotatePages
 	}
-	printInfo(fmt.Sprintf("Rotating %s by %d° in %s…", pageDesc, degrees, inFile))
+	return writeRotations(ctx, inFile, rotations, fields)
+}
 
-	if rotateDryRun {
-		printInfo(fmt.Sprintf("[dry-run] would rotate %s by %d°", pageDesc, degrees))
-		if jsonOut {
-			out := rotateOutput
-			if out == "" {
-				out = inFile
-			}
-			fields := map[string]interface{}{
-				"dry_run":  true,
-				"input":    inFile,
-				"output":   out,
-				"degrees":  degrees,
-				"in_place": rotateOutput == "",
+// writeRotations sets the given pages to their new rotation (baking it into
+// the content with --bake), reports what changes and writes the result.
+func writeRotations(ctx *model.Context, inFile string, rotations map[int]int, fields map[string]interface{}) error {
+	changes := []rotateChange{}
+	for _, nr := range sortedPageNumbers(rotations) {
+		_, _, inh, err := ctx.PageDict(nr, false)
+		if err != nil {
+			return err
+		}
+		c := rotateChange{Page: nr, From: normalizeRotation(inh.Rotate), To: rotations[nr]}
+		if rotateBake && c.To != 0 {
+			c.Baked = true
+		} else if c.From == c.To {
+			continue
+		}
+		changes = append(changes, c)
+	}
+
+	out := rotateOutput
+	if out == "" {
+		out = inFile
+	}
+	fields["output"] = out
+	fields["in_place"] = out == inFile
+	fields["dry_run"] = rotateDryRun
+	fields["bake"] = rotateBake
+	fields["changed"] = changes
+	printRotateChanges(changes)
+
+	if len(changes) == 0 {
+		if out != inFile && !rotateDryRun {
+			src, err := os.ReadFile(inFile)
+			if err != nil {
+				return err
 			}
-			if rotatePages != "" {
-				fields["pages"] = rotatePages
+			if err := writeFileAtomic(out, src); err != nil {
+				return err
 			}
+			printSuccess(fmt.Sprintf("No page needs a different rotation — copied %s to %s unchanged", inFile, out))
+		} else {
+			printSuccess("No page needs a different rotation — nothing to do")
+		}
+		if jsonOut {
 			return jsonResultOK("rotate", fields)
 		}
 		return nil
 	}
-
-	outFile := rotateOutput
-	if outFile == "" {
-		outFile = inFile // in-place
+	if rotateDryRun {
+		printInfo(fmt.Sprintf("[dry-run] would change %d page(s)", len(changes)))
+		if jsonOut {
+			return jsonResultOK("rotate", fields)
+		}
+		return nil
 	}
 
-	// pdfcpu RotateFile with empty outFile rotates in-place
-	if err := api.RotateFile(inFile, outFile, degrees, pages, pdfConfig()); err != nil {
+	annots := 0
+	for _, c := range changes {
+		d, _, _, err := ctx.PageDict(c.Page, false)
+		if err != nil {
+			return err
+		}
+		d.Update("Rotate", types.Integer(c.To))
+		if c.Baked {
+			n, err := bakeRotation(ctx, c.Page)
+			if err != nil {
+				return fmt.Errorf("page %d: %w", c.Page, err)
+			}
+			annots += n
+		}
+	}
+	if annots > 0 {
+		printWarning(fmt.Sprintf("Moved %d annotation(s) with their pages; their appearance is not turned", annots))
+	}
+	data, err := writeContextBytes(ctx)
+	if err != nil {
 		return err
 	}
-
-	fi, _ := os.Stat(outFile)
-	size := ""
-	if fi != nil {
-		size = fmt.Sprintf(" (%s)", humanSize(fi.Size()))
+	if err := writeFileAtomic(out, data); err != nil {
+		return err
 	}
-	if outFile == inFile {
-		printSuccess(fmt.Sprintf("Rotated in-place: %s%s", outFile, size))
+
+	size := fmt.Sprintf(" (%s)", humanSize(int64(len(data))))
+	if out == inFile {
+		printSuccess(fmt.Sprintf("Rotated in-place: %s%s", out, size))
 	} else {
-		printSuccess(fmt.Sprintf("Created: %s%s", outFile, size))
+		printSuccess(fmt.Sprintf("Created: %s%s", out, size))
 	}
 	if jsonOut {
-		fields := map[string]interface{}{
-			"input":    inFile,
-			"output":   outFile,
-			"degrees":  degrees,
-			"in_place": outFile == inFile,
+		fields["size_bytes"] = len(data)
+		fields["size_human"] = humanSize(int64(len(data)))
+		return jsonResultOK("rotate", fields)
+	}
+	return nil
+}
+
+// printRotateChanges lists the changes grouped by old and new rotation.
+func printRotateChanges(changes []rotateChange) {
+	if quiet || len(changes) == 0 {
+		return
+	}
+	type key struct {
+		from, to int
+		baked    bool
+	}
+	groups := map[key][]int{}
+	var order []key
+	for _, c := range changes {
+		k := key{c.From, c.To, c.Baked}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
 		}
-		if rotatePages != "" {
-			fields["pages"] = rotatePages
+		groups[k] = append(groups[k], c.Page)
+	}
+	fmt.Println()
+	for _, k := range order {
+		desc := fmt.Sprintf("%d° → %d°", k.from, k.to)
+		if k.baked {
+			desc += ", baked into content (→ 0°)"
 		}
-		if fi != nil {
-			fields["size_bytes"] = fi.Size()
-			fields["size_human"] = humanSize(fi.Size())
+		fmt.Printf("  %-18s %s\n", compactPageList(groups[k]), desc)
+	}
+	fmt.Println()
+}
+
+func sortedPageNumbers(m map[int]int) []int {
+	nrs := make([]int, 0, len(m))
+	for nr := range m {
+		nrs = append(nrs, nr)
+	}
+	sort.Ints(nrs)
+	return nrs
+}
+
+// bakeRotation redraws page nr turned by its /Rotate and sets /Rotate to 0,
+// so the page looks the same but no longer depends on viewers honouring
+// /Rotate. The page boxes and annotation rectangles are turned with it; the
+// number of annotations moved is returned.
+func bakeRotation(ctx *model.Context, nr int) (int, error) {
+	d, _, inh, err := ctx.PageDict(nr, false)
+	if err != nil {
+		return 0, err
+	}
+	rot := normalizeRotation(inh.Rotate)
+	if rot == 0 {
+		return 0, nil
+	}
+	if inh.MediaBox == nil {
+		return 0, fmt.Errorf("page has no MediaBox")
+	}
+	m := rotationMatrix(rot, inh.MediaBox)
+
+	d["MediaBox"] = transformRect(m, inh.MediaBox)
+	if inh.CropBox != nil {
+		d["CropBox"] = transformRect(m, inh.CropBox)
+	}
+	for _, box := range []string{"BleedBox", "TrimBox", "ArtBox"} {
+		if r, err := rectEntry(ctx, d[box]); err == nil && r != nil {
+			d[box] = transformRect(m, r)
 		}
-		return jsonResultOK("rotate", fields)
 	}
-	return nil
+
+	if contents, ok := d["Contents"]; ok && contents != nil {
+		pre, err := newContentStream(ctx, fmt.Sprintf("q %s cm\n", formatMatrix(m)))
+		if err != nil {
+			return 0, err
+		}
+		post, err := newContentStream(ctx, "\nQ\n")
+		if err != nil {
+			return 0, err
+		}
+		parts := types.Array{*pre}
+		if a, err := ctx.DereferenceArray(contents); err == nil && a != nil {
+			parts = append(parts, a...)
+		} else {
+			parts = append(parts, contents)
+		}
+		d["Contents"] = append(parts, *post)
+	}
+
+	moved := 0
+	annots, _ := ctx.DereferenceArray(d["Annots"])
+	for _, o := range annots {
+		a, err := ctx.DereferenceDict(o)
+		if err != nil || a == nil {
+			continue
+		}
+		if r, err := rectEntry(ctx, a["Rect"]); err == nil && r != nil {
+			a["Rect"] = transformRect(m, r)
+			moved++
+		}
+	}
+
+	d["Rotate"] = types.Integer(0)
+	return moved, nil
+}
+
+// rotationMatrix maps the unrotated page space of box to a page that shows
+// the same picture with /Rotate 0, its lower left corner at the origin.
+func rotationMatrix(rot int, box *types.Rectangle) [6]float64 {
+	llx, lly, urx, ury := box.LL.X, box.LL.Y, box.UR.X, box.UR.Y
+	switch rot {
+	case 90:
+		return [6]float64{0, -1, 1, 0, -lly, urx}
+	case 180:
+		return [6]float64{-1, 0, 0, -1, urx, ury}
+	case 270:
+		return [6]float64{0, 1, -1, 0, ury, -llx}
+	}
+	return [6]float64{1, 0, 0, 1, 0, 0}
+}
+
+func transformRect(m [6]float64, r *types.Rectangle) types.Array {
+	x1, y1 := m[0]*r.LL.X+m[2]*r.LL.Y+m[4], m[1]*r.LL.X+m[3]*r.LL.Y+m[5]
+	x2, y2 := m[0]*r.UR.X+m[2]*r.UR.Y+m[4], m[1]*r.UR.X+m[3]*r.UR.Y+m[5]
+	return types.Array{
+		types.Float(math.Min(x1, x2)), types.Float(math.Min(y1, y2)),
+		types.Float(math.Max(x1, x2)), types.Float(math.Max(y1, y2)),
+	}
+}
+
+func rectEntry(ctx *model.Context, o types.Object) (*types.Rectangle, error) {
+	if o == nil {
+		return nil, nil
+	}
+	a, err := ctx.DereferenceArray(o)
+	if err != nil || len(a) != 4 {
+		return nil, err
+	}
+	var v [4]float64
+	for i := range a {
+		if v[i], err = ctx.DereferenceNumber(a[i]); err != nil {
+			return nil, err
+		}
+	}
+	return types.NewRectangle(v[0], v[1], v[2], v[3]), nil
+}
+
+func formatMatrix(m [6]float64) string {
+	parts := make([]string, len(m))
+	for i, v := range m {
+		parts[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strings.Join(parts, " ")
+}
+
+// newContentStream adds a Flate-compressed content stream holding s.
+func newContentStream(ctx *model.Context, s string) (*types.IndirectRef, error) {
+	sd, err := ctx.NewStreamDictForBuf([]byte(s))
+	if err != nil {
+		return nil, err
+	}
+	if err := sd.Encode(); err != nil {
+		return nil, err
+	}
+	return ctx.IndRefForNewObject(*sd)
 }
@@ -0,0 +1,664 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sanitizeOutput      string
+	sanitizeReportOnly  bool
+	sanitizeAttachments bool
+)
+
+var sanitizeCmd = &cobra.Command{
+	Use:   "sanitize <input.pdf>",
+	Short: "Remove JavaScript, launch actions and embedded executables",
+	Long: fmt.Sprintf(`Find and remove active content and print a risk report.
+Without -o, the file is sanitized in-place.
+
+%s
+  pdfed sanitize upload.pdf --report-only
+  pdfed sanitize upload.pdf -o safe.pdf
+  pdfed sanitize upload.pdf --strip-attachments
+
+%s
+  high    JavaScript (document scripts, open actions, field and page triggers),
+          Launch actions, javascript: links, embedded executables, RichMedia
+  medium  links to schemes other than http, https and mailto, SubmitForm and
+          ImportData actions, XFA forms, rendition scripts
+  low     links to other PDFs (GoToR/GoToE), other embedded files
+
+%s
+  High and medium findings are removed; low ones are only reported, except
+  attachments with --strip-attachments. --report-only writes nothing and
+  exits non-zero when high or medium risk content is found, for use as an
+  upload gate. The file is fully rewritten, so removed content does not
+  survive in an earlier revision, and the result is scanned again before
+  it is kept. Rewriting invalidates existing digital signatures.`,
+		bold("Examples:"), bold("Risk levels:"), bold("Notes:")),
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return runSanitize(args[0])
+	},
+}
+
+func init() {
+	sanitizeCmd.Flags().StringVarP(&sanitizeOutput, "output", "o", "", "Output file (default: in-place)")
+	sanitizeCmd.Flags().BoolVar(&sanitizeReportOnly, "report-only", false, "Only report; exit non-zero if risky content is found")
+	sanitizeCmd.Flags().BoolVar(&sanitizeAttachments, "strip-attachments", false, "Also remove embedded files that are not executables")
+	rootCmd.AddCommand(sanitizeCmd)
+}
+
+const (
+	riskHigh   = "high"
+	riskMedium = "medium"
+	riskLow    = "low"
+)
+
+// safeURISchemes are the link targets that are not reported.
+var safeURISchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// executableExts are attachment names treated as executables regardless of content.
+var executableExts = map[string]bool{
+	".exe": true, ".dll": true, ".com": true, ".scr": true, ".pif": true, ".cpl": true,
+	".msi": true, ".bat": true, ".cmd": true, ".ps1": true, ".vbs": true, ".vbe": true,
+	".js": true, ".jse": true, ".wsf": true, ".hta": true, ".reg": true, ".lnk": true,
+	".jar": true, ".sh": true, ".app": true, ".apk": true, ".docm": true, ".xlsm": true,
+	".pptm": true,
+}
+
+// executableMagic are the leading bytes of native executables and scripts.
+var executableMagic = [][]byte{
+	[]byte("MZ"),             // Windows PE
+	[]byte("\x7fELF"),        // Linux ELF
+	{0xfe, 0xed, 0xfa, 0xce}, // Mach-O 32-bit
+	{0xfe, 0xed, 0xfa, 0xcf}, // Mach-O 64-bit
+	{0xce, 0xfa, 0xed, 0xfe}, // Mach-O 32-bit, little-endian
+	{0xcf, 0xfa, 0xed, 0xfe}, // Mach-O 64-bit, little-endian
+	{0xca, 0xfe, 0xba, 0xbe}, // Mach-O universal / Java class
+	[]byte("#!"),             // script with interpreter line
+}
+
+// sanitizeFinding is one piece of active content.
+type sanitizeFinding struct {
+	Risk     string `json:"risk"`
+	Kind     string `json:"kind"`
+	Location string `json:"location"`
+	Detail   string `json:"detail,omitempty"`
+	Action   string `json:"action"` // removed or reported
+}
+
+// sanitizer scans a context for active content and, when apply is set,
+// removes it. Removal happens in place on the context's objects.
+type sanitizer struct {
+	ctx      *model.Context
+	apply    bool
+	strip    bool // remove every embedded file, not only executables
+	labels   map[int]string
+	dropped  map[int]bool // indirect objects removed as a whole
+	skip     map[int]bool // name tree nodes and leaves, handled separately
+	findings []sanitizeFinding
+}
+
+// note records a finding and reports whether it is removed.
+func (s *sanitizer) note(risk, kind, location, detail string) bool {
+	remove := risk != riskLow || kind == "embedded-file" && s.strip
+	action := "reported"
+	if remove && s.apply {
+		action = "removed"
+	}
+	// Details come from the file; keep them to one printable line.
+	detail = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, detail)
+	s.findings = append(s.findings, sanitizeFinding{risk, kind, location, truncate(detail, 70), action})
+	return remove
+}
+
+func (s *sanitizer) scan() error {
+	s.labels = map[int]string{}
+	s.dropped = map[int]bool{}
+	s.skip = map[int]bool{}
+	if s.ctx.Root != nil {
+		s.labels[s.ctx.Root.ObjectNumber.Value()] = "catalog"
+	}
+	for i := 1; i <= s.ctx.PageCount; i++ {
+		d, ref, _, err := s.ctx.PageDict(i, false)
+		if err != nil {
+			return fmt.Errorf("page %d: %w", i, err)
+		}
+		if ref != nil {
+			s.labels[ref.ObjectNumber.Value()] = fmt.Sprintf("page %d", i)
+		}
+		annots, _ := s.ctx.DereferenceArray(d["Annots"])
+		for _, a := range annots {
+			if ref, ok := a.(types.IndirectRef); ok {
+				s.labels[ref.ObjectNumber.Value()] = fmt.Sprintf("page %d annotation", i)
+			}
+		}
+	}
+
+	// Name indirect objects after where they are used, e.g. "page 1 /AA/O".
+	for _, nr := range sortedLabelled(s.labels) {
+		if e, ok := s.ctx.Table[nr]; ok && e != nil {
+			s.labelRefs(e.Object, s.labels[nr], 0)
+		}
+	}
+
+	if err := s.scanNameTrees(); err != nil {
+		return err
+	}
+	if form, err := s.ctx.DereferenceDict(s.ctx.RootDict["AcroForm"]); err == nil && form != nil {
+		if _, ok := form["XFA"]; ok && s.note(riskMedium, "xfa", "catalog /AcroForm/XFA", "XFA form, may carry scripts") && s.apply {
+			delete(form, "XFA")
+		}
+	}
+
+	nrs := make([]int, 0, len(s.ctx.Table))
+	for nr, e := range s.ctx.Table {
+		if e != nil && !e.Free && e.Object != nil && !s.skip[nr] {
+			nrs = append(nrs, nr)
+		}
+	}
+	sort.Ints(nrs)
+	// Indirect objects that are risky as a whole go first, so references to
+	// them can be cut wherever they appear.
+	for _, nr := range nrs {
+		if d, ok := s.ctx.Table[nr].Object.(types.Dict); ok {
+			if s.classify(d, s.label(nr)) {
+				s.dropped[nr] = true
+			}
+		}
+	}
+	for _, nr := range nrs {
+		if s.dropped[nr] {
+			continue
+		}
+		if d, ok := objectDict(s.ctx.Table[nr].Object); ok {
+			s.walkChildren(d, s.label(nr))
+		}
+	}
+	return nil
+}
+
+func (s *sanitizer) label(nr int) string {
+	if l, ok := s.labels[nr]; ok {
+		return l
+	}
+	return fmt.Sprintf("object %d", nr)
+}
+
+func sortedLabelled(labels map[int]string) []int {
+	nrs := make([]int, 0, len(labels))
+	for nr := range labels {
+		nrs = append(nrs, nr)
+	}
+	sort.Ints(nrs)
+	return nrs
+}
+
+// labelRefs labels the objects o refers to by the path they are found at.
+// Back-references and the page tree are not followed.
+func (s *sanitizer) labelRefs(o types.Object, path string, depth int) {
+	if depth > 8 {
+		return
+	}
+	switch v := o.(type) {
+	case types.Dict:
+		for k, e := range v {
+			switch k {
+			case "Parent", "P", "Kids", "Popup", "Annots", "Pages":
+				continue
+			}
+			s.labelRefs(e, childPath(path, k), depth+1)
+		}
+	case types.StreamDict:
+		s.labelRefs(v.Dict, path, depth)
+	case types.Array:
+		for _, e := range v {
+			s.labelRefs(e, path, depth+1)
+		}
+	case types.IndirectRef:
+		nr := v.ObjectNumber.Value()
+		if _, ok := s.labels[nr]; !ok {
+			s.labels[nr] = path
+			if e, ok := s.ctx.Table[nr]; ok && e != nil {
+				s.labelRefs(e.Object, path, depth+1)
+			}
+		}
+	}
+}
+
+func childPath(path, key string) string {
+	if strings.Contains(path, "/") {
+		return path + "/" + key
+	}
+	return path + " /" + key
+}
+
+// walk visits a direct object and returns it with risky parts cut out, or
+// nil when the object itself is to be removed.
+func (s *sanitizer) walk(o types.Object, path string) types.Object {
+	switch v := o.(type) {
+	case types.Dict:
+		if s.classify(v, path) {
+			return nil
+		}
+		s.walkChildren(v, path)
+	case types.Array:
+		kept := make(types.Array, 0, len(v))
+		for _, e := range v {
+			if ref, ok := e.(types.IndirectRef); ok && s.dropped[ref.ObjectNumber.Value()] {
+				continue
+			}
+			if e = s.walk(e, path); e != nil {
+				kept = append(kept, e)
+			}
+		}
+		if s.apply {
+			return kept
+		}
+	}
+	return o
+}
+
+func (s *sanitizer) walkChildren(d types.Dict, path string) {
+	for k, v := range d {
+		// Name trees were handled by scanNameTrees.
+		if k == "JavaScript" || k == "EmbeddedFiles" {
+			continue
+		}
+		switch v := v.(type) {
+		case types.IndirectRef:
+			if s.dropped[v.ObjectNumber.Value()] && s.apply {
+				delete(d, k)
+			}
+		case types.Dict, types.Array:
+			nv := s.walk(v, childPath(path, k))
+			if !s.apply {
+				continue
+			}
+			// An additional-actions dictionary with every trigger removed goes too.
+			if aa, ok := nv.(types.Dict); nv == nil || ok && k == "AA" && len(aa) == 0 {
+				delete(d, k)
+			} else {
+				d[k] = nv
+			}
+		}
+	}
+}
+
+// classify reports on a dictionary that is an action or annotation with
+// active content and returns whether it is to be removed.
+func (s *sanitizer) classify(d types.Dict, path string) bool {
+	if st := d.NameEntry("Subtype"); st != nil {
+		switch *st {
+		case "RichMedia":
+			return s.note(riskHigh, "rich-media", path, "RichMedia (Flash/video) annotation")
+		case "FileAttachment":
+			name, exe := s.fileSpecInfo(d["FS"])
+			if exe {
+				return s.note(riskHigh, "embedded-executable", path, name)
+			}
+			return s.note(riskLow, "embedded-file", path, name)
+		}
+	}
+	if t := d.NameEntry("Type"); t != nil && *t != "Action" {
+		return false
+	}
+	st := d.NameEntry("S")
+	if st == nil {
+		return false
+	}
+	switch *st {
+	case "JavaScript":
+		return s.note(riskHigh, "javascript", path, s.scriptText(d["JS"]))
+	case "Launch":
+		target, _ := s.fileSpecInfo(d["F"])
+		if win, err := s.ctx.DereferenceDict(d["Win"]); err == nil && win != nil {
+			if f, ok := decodePDFText(win["F"]); ok {
+				target = f
+			}
+		}
+		return s.note(riskHigh, "launch", path, target)
+	case "URI":
+		uri, _ := s.ctx.Dereference(d["URI"])
+		target, _ := decodePDFText(uri)
+		scheme := ""
+		if u, err := url.Parse(strings.TrimSpace(target)); err == nil {
+			scheme = strings.ToLower(u.Scheme)
+		}
+		switch {
+		case scheme == "javascript":
+			return s.note(riskHigh, "uri", path, target)
+		case scheme == "" || safeURISchemes[scheme]:
+			return false
+		}
+		return s.note(riskMedium, "uri", path, target)
+	case "SubmitForm":
+		target, _ := s.fileSpecInfo(d["F"])
+		return s.note(riskMedium, "submit-form", path, target)
+	case "ImportData":
+		target, _ := s.fileSpecInfo(d["F"])
+		return s.note(riskMedium, "import-data", path, target)
+	case "Rendition":
+		if _, ok := d["JS"]; ok {
+			return s.note(riskMedium, "rendition-script", path, s.scriptText(d["JS"]))
+		}
+	case "RichMediaExecute":
+		return s.note(riskMedium, "rich-media", path, "RichMediaExecute action")
+	case "GoToR", "GoToE":
+		target, _ := s.fileSpecInfo(d["F"])
+		return s.note(riskLow, "remote-goto", path, target)
+	}
+	return false
+}
+
+// scanNameTrees handles the document-level JavaScript and EmbeddedFiles trees.
+func (s *sanitizer) scanNameTrees() error {
+	names, err := s.ctx.DereferenceDict(s.ctx.RootDict["Names"])
+	if err != nil || names == nil {
+		return err
+	}
+	if o, ok := names["JavaScript"]; ok {
+		removed := false
+		if err := s.visitNameTree("JavaScript", o, 0, func(name string, v types.Object) bool {
+			script := ""
+			if d, err := s.ctx.DereferenceDict(v); err == nil && d != nil {
+				script = s.scriptText(d["JS"])
+			}
+			if s.note(riskHigh, "javascript", fmt.Sprintf("document script %q", name), script) {
+				removed = true
+				return false
+			}
+			return true
+		}); err != nil {
+			return err
+		}
+		if removed && s.apply {
+			delete(names, "JavaScript")
+			delete(s.ctx.Names, "JavaScript")
+		}
+	}
+	if o, ok := names["EmbeddedFiles"]; ok {
+		return s.visitNameTree("EmbeddedFiles", o, 0, func(name string, v types.Object) bool {
+			file, exe := s.fileSpecInfo(v)
+			if file == "" {
+				file = name
+			}
+			loc := fmt.Sprintf("attachment %q", name)
+			if exe {
+				return !s.note(riskHigh, "embedded-executable", loc, file)
+			}
+			return !s.note(riskLow, "embedded-file", loc, file)
+		})
+	}
+	return nil
+}
+
+// visitNameTree calls keep for every leaf of the named tree and, when
+// applying, drops the leaves it rejects.
+func (s *sanitizer) visitNameTree(tree string, o types.Object, depth int, keep func(name string, v types.Object) bool) error {
+	if ref, ok := o.(types.IndirectRef); ok {
+		s.skip[ref.ObjectNumber.Value()] = true
+	}
+	node, err := s.ctx.DereferenceDict(o)
+	if err != nil || node == nil || depth > 32 {
+		return err
+	}
+	kids, err := s.ctx.DereferenceArray(node["Kids"])
+	if err != nil {
+		return err
+	}
+	for _, k := range kids {
+		if err := s.visitNameTree(tree, k, depth+1, keep); err != nil {
+			return err
+		}
+	}
+	leaves, err := s.ctx.DereferenceArray(node["Names"])
+	if err != nil || leaves == nil {
+		return err
+	}
+	kept := types.Array{}
+	for i := 0; i+1 < len(leaves); i += 2 {
+		if ref, ok := leaves[i+1].(types.IndirectRef); ok {
+			s.skip[ref.ObjectNumber.Value()] = true
+		}
+		name, _ := decodePDFText(leaves[i])
+		if keep(name, leaves[i+1]) {
+			kept = append(kept, leaves[i], leaves[i+1])
+		}
+	}
+	if s.apply && len(kept) != len(leaves) {
+		node["Names"] = kept
+		// pdfcpu rebuilds the trees it indexed while validating on write;
+		// a tree edited here has to be written as it stands.
+		delete(s.ctx.Names, tree)
+	}
+	return nil
+}
+
+// fileSpecInfo returns the file name of a file specification and whether its
+// embedded file, if any, looks like an executable.
+func (s *sanitizer) fileSpecInfo(o types.Object) (string, bool) {
+	o, _ = s.ctx.Dereference(o)
+	if name, ok := decodePDFText(o); ok {
+		return name, executableExts[strings.ToLower(filepath.Ext(name))]
+	}
+	fs, ok := o.(types.Dict)
+	if !ok {
+		return "", false
+	}
+	var name string
+	for _, k := range []string{"UF", "F", "Unix", "DOS", "Mac"} {
+		if v, ok := decodePDFText(fs[k]); ok && v != "" {
+			name = v
+			break
+		}
+	}
+	if kind, ok := decodePDFText(fs["FS"]); ok && kind == "URL" {
+		return name, false
+	}
+	exe := executableExts[strings.ToLower(filepath.Ext(name))]
+	ef, err := s.ctx.DereferenceDict(fs["EF"])
+	if err != nil || ef == nil || exe {
+		return name, exe
+	}
+	for _, k := range []string{"F", "UF"} {
+		sd, _, err := s.ctx.DereferenceStreamDict(ef[k])
+		if err != nil || sd == nil {
+			continue
+		}
+		if err := sd.Decode(); err != nil {
+			continue
+		}
+		for _, magic := range executableMagic {
+			if bytes.HasPrefix(sd.Content, magic) {
+				return name, true
+			}
+		}
+	}
+	return name, false
+}
+
+// scriptText returns the start of a JavaScript action's code on one line.
+func (s *sanitizer) scriptText(o types.Object) string {
+	var code string
+	switch v := o.(type) {
+	case types.IndirectRef:
+		if sd, _, err := s.ctx.DereferenceStreamDict(v); err == nil && sd != nil {
+			if sd.Decode() == nil {
+				code = string(sd.Content)
+			}
+		} else if obj, err := s.ctx.Dereference(v); err == nil {
+			code, _ = decodePDFText(obj)
+		}
+	default:
+		code, _ = decodePDFText(o)
+	}
+	return strings.Join(strings.Fields(code), " ")
+}
+
+func countRisks(findings []sanitizeFinding) map[string]int {
+	counts := map[string]int{riskHigh: 0, riskMedium: 0, riskLow: 0}
+	for _, f := range findings {
+		counts[f.Risk]++
+	}
+	return counts
+}
+
+func runSanitize(inFile string) error {
+	ctx, err := readValidatedContext(inFile)
+	if err != nil {
+		return err
+	}
+	if ctx.Encrypt != nil {
+		return fmt.Errorf("%s is encrypted; decrypt it first", inFile)
+	}
+
+	s := &sanitizer{ctx: ctx, apply: !sanitizeReportOnly, strip: sanitizeAttachments}
+	if err := s.scan(); err != nil {
+		return err
+	}
+	counts := countRisks(s.findings)
+	risky := counts[riskHigh] + counts[riskMedium]
+	removed := 0
+	for _, f := range s.findings {
+		if f.Action == "removed" {
+			removed++
+		}
+	}
+
+	out := sanitizeOutput
+	if out == "" {
+		out = inFile
+	}
+	fields := map[string]interface{}{
+		"input":       inFile,
+		"report_only": sanitizeReportOnly,
+		"findings":    s.findings,
+		"counts":      counts,
+		"risky":       risky,
+	}
+	if s.findings == nil {
+		fields["findings"] = []sanitizeFinding{}
+	}
+
+	printInfo(fmt.Sprintf("Scanning %s for active content…", inFile))
+	printSanitizeReport(s.findings, counts)
+
+	if sanitizeReportOnly {
+		fields["clean"] = risky == 0
+		if risky > 0 {
+			return &jsonFailure{"sanitize", fields,
+				fmt.Errorf("%d risky item(s) found (%d high, %d medium)", risky, counts[riskHigh], counts[riskMedium])}
+		}
+		printSuccess("No risky content found")
+		if jsonOut {
+			return jsonResultOK("sanitize", fields)
+		}
+		return nil
+	}
+
+	fields["output"] = out
+	fields["in_place"] = out == inFile
+	fields["removed"] = removed
+	if removed == 0 {
+		printSuccess("No active content to remove")
+		if jsonOut {
+			fields["clean"] = true
+			return jsonResultOK("sanitize", fields)
+		}
+		return nil
+	}
+
+	data, err := rewriteKeepingInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to write sanitized PDF: %w", err)
+	}
+	// Scan the result again before touching the destination.
+	check, err := readContextFrom(bytes.NewReader(data), pdfConfig())
+	if err != nil {
+		return fmt.Errorf("sanitized file does not read back: %w", err)
+	}
+	v := &sanitizer{ctx: check, strip: sanitizeAttachments}
+	if err := v.scan(); err != nil {
+		return fmt.Errorf("sanitized file: %w", err)
+	}
+	var remaining []sanitizeFinding
+	for _, f := range v.findings {
+		if f.Risk != riskLow || f.Kind == "embedded-file" && sanitizeAttachments {
+			remaining = append(remaining, f)
+		}
+	}
+	if len(remaining) > 0 {
+		for _, f := range remaining {
+			printError(fmt.Sprintf("still present: %s %s", f.Kind, f.Location))
+		}
+		return fmt.Errorf("verification failed: %d item(s) survived sanitizing; %s was not written", len(remaining), out)
+	}
+
+	if err := writeFileAtomic(out, data); err != nil {
+		return err
+	}
+	if out == inFile {
+		printSuccess(fmt.Sprintf("Sanitized in-place: %s (%d item(s) removed, %s)", out, removed, humanSize(int64(len(data)))))
+	} else {
+		printSuccess(fmt.Sprintf("Created: %s (%d item(s) removed, %s)", out, removed, humanSize(int64(len(data)))))
+	}
+	if jsonOut {
+		fields["clean"] = true
+		fields["size_bytes"] = len(data)
+		fields["size_human"] = humanSize(int64(len(data)))
+		return jsonResultOK("sanitize", fields)
+	}
+	return nil
+}
+
+func printSanitizeReport(findings []sanitizeFinding, counts map[string]int) {
+	if quiet {
+		return
+	}
+	fmt.Println()
+	if len(findings) == 0 {
+		fmt.Println("  " + dimStyle.Render("No active content found."))
+		fmt.Println()
+		return
+	}
+	fmt.Println("  " + bold(fmt.Sprintf("%-7s %-20s %-28s %s", "RISK", "KIND", "LOCATION", "DETAIL")))
+	for _, f := range findings {
+		risk := fmt.Sprintf("%-7s", f.Risk)
+		switch f.Risk {
+		case riskHigh:
+			risk = red(risk)
+		case riskMedium:
+			risk = yellow(risk)
+		default:
+			risk = dimStyle.Render(risk)
+		}
+		action := dimStyle.Render(f.Action)
+		if f.Action == "removed" {
+			action = green(f.Action)
+		}
+		detail := ""
+		if f.Detail != "" {
+			detail = f.Detail + " "
+		}
+		fmt.Printf("  %s %-20s %-28s %s%s\n", risk, f.Kind, f.Location, detail, action)
+	}
+	fmt.Printf("\n  %d high, %d medium, %d low\n\n", counts[riskHigh], counts[riskMedium], counts[riskLow])
+}
@@ -0,0 +1,584 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scrubOutput string
+	scrubKeep   []string
+	scrubDryRun bool
+)
+
+var scrubCmd = &cobra.Command{
+	Use:   "scrub <input.pdf>",
+	Short: "Remove authors, software fingerprints, IDs and comments before publishing",
+	Long: fmt.Sprintf(`Find and remove data that identifies people, tools or earlier versions
+of a document. Without -o, the file is scrubbed in-place.
+
+%s
+  pdfed scrub draft.pdf --dry-run
+  pdfed scrub draft.pdf -o public.pdf
+  pdfed scrub draft.pdf --keep comments --keep Creator
+
+%s
+  author      Info Author, XMP dc:creator, pdf:Author, xmp:Author
+  software    Info Creator/Producer, XMP xmp:CreatorTool, pdf:Producer, pdf:Creator, x:xmptk
+  history     XMP xmpMM:History, DerivedFrom, Ingredients, Pantry, …
+  ids         trailer /ID (replaced by a fresh one), XMP document/instance IDs
+  thumbnails  page /Thumb images, XMP xmp:Thumbnails
+  private     /PieceInfo application data, custom Info keys and their XMP pdfx: copies
+  comments    markup annotations (notes, highlights, ink, stamps, …) and their popups
+
+%s
+  --keep takes a category or a single field (Author, xmpMM:History, Thumb,
+  Highlight, a custom Info key, …); repeat it or separate with commas.
+  XMP packets on pages and images are scrubbed as well as the catalog's.
+  The file is rewritten, so removed objects do not survive in earlier
+  revisions; the result is read back and scanned again, including for
+  removed Info values left in any XMP property, before it is kept.`,
+		bold("Examples:"), bold("Categories:"), bold("Notes:")),
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return runScrub(args[0])
+	},
+}
+
+func init() {
+	scrubCmd.Flags().StringVarP(&scrubOutput, "output", "o", "", "Output file (default: in-place)")
+	scrubCmd.Flags().StringSliceVar(&scrubKeep, "keep", nil, "Category or field to leave alone (repeatable)")
+	scrubCmd.Flags().BoolVarP(&scrubDryRun, "dry-run", "n", false, "Report what would be removed without writing")
+	rootCmd.AddCommand(scrubCmd)
+}
+
+var scrubCategories = []string{"author", "software", "history", "ids", "thumbnails", "private", "comments"}
+
+// scrubInfoKeys are the standard Info keys that are scrubbed. Custom keys are
+// treated as private application data.
+var scrubInfoKeys = map[string]string{
+	"Author":   "author",
+	"Creator":  "software",
+	"Producer": "software",
+}
+
+// scrubXMPKeys lists the XMP properties that are scrubbed. Containers come
+// before the IDs they may nest.
+var scrubXMPKeys = []struct{ key, category string }{
+	{"dc:creator", "author"},
+	{"pdf:Author", "author"}, // legacy mirrors of the Info entries
+	{"xmp:Author", "author"},
+	{"photoshop:AuthorsPosition", "author"},
+	{"photoshop:CaptionWriter", "author"},
+	{"xmp:CreatorTool", "software"},
+	{"pdf:Producer", "software"},
+	{"pdf:Creator", "software"},
+	{"xmpMM:History", "history"},
+	{"xmpMM:DerivedFrom", "history"},
+	{"xmpMM:Ingredients", "history"},
+	{"xmpMM:Pantry", "history"},
+	{"xmpMM:Manifest", "history"},
+	{"xmpMM:Versions", "history"},
+	{"xmpMM:VersionID", "history"},
+	{"xmpMM:DocumentID", "ids"},
+	{"xmpMM:InstanceID", "ids"},
+	{"xmpMM:OriginalDocumentID", "ids"},
+	{"xmp:Thumbnails", "thumbnails"},
+}
+
+const pdfxNS = "http://ns.adobe.com/pdfx/1.3/"
+
+var xmpToolkitRe = regexp.MustCompile(`\s+x:xmptk\s*=\s*("[^"]*"|'[^']*')`)
+
+// commentSubtypes are the markup annotations removed as comments. Links,
+// widgets and redactions are left alone; popups follow their parent.
+var commentSubtypes = map[string]bool{
+	"Text": true, "FreeText": true, "Line": true, "Square": true, "Circle": true,
+	"Polygon": true, "PolyLine": true, "Highlight": true, "Underline": true,
+	"Squiggly": true, "StrikeOut": true, "Caret": true, "Ink": true, "Stamp": true,
+	"FileAttachment": true, "Sound": true,
+}
+
+// scrubFinding is one piece of identifying data found in the document.
+type scrubFinding struct {
+	Category string `json:"category"`
+	Location string `json:"location"`
+	Field    string `json:"field"`
+	Value    string `json:"value,omitempty"`
+	Action   string `json:"action"` // removed, replaced or kept
+}
+
+// scrubber scans a context for identifying data and, when apply is set,
+// removes whatever the keep-list does not protect.
+type scrubber struct {
+	ctx      *model.Context
+	keep     []string
+	used     map[string]bool
+	apply    bool
+	findings []scrubFinding
+	// removed holds the Info values a scrub removed; verifying a scrubbed
+	// file reports them wherever they survive in an XMP packet.
+	removed []scrubFinding
+}
+
+// note records a finding and reports whether it should be removed.
+func (s *scrubber) note(category, location, field, value string) bool {
+	action := "removed"
+	for _, k := range s.keep {
+		if strings.EqualFold(k, category) || strings.EqualFold(k, field) {
+			action = "kept"
+			s.used[k] = true
+		}
+	}
+	if action == "removed" && field == "ID" {
+		action = "replaced"
+	}
+	s.findings = append(s.findings, scrubFinding{category, location, field, value, action})
+	return action != "kept"
+}
+
+func (s *scrubber) scan() error {
+	pageObjs := map[int]int{}
+	for i := 1; i <= s.ctx.PageCount; i++ {
+		_, ref, _, err := s.ctx.PageDict(i, false)
+		if err != nil {
+			return fmt.Errorf("page %d: %w", i, err)
+		}
+		if ref != nil {
+			pageObjs[ref.ObjectNumber.Value()] = i
+		}
+	}
+	if err := s.scanInfo(); err != nil {
+		return err
+	}
+	if len(s.ctx.ID) > 0 {
+		if s.note("ids", "trailer", "ID", strings.Trim(s.ctx.ID[0].String(), "<>()")) && s.apply {
+			s.ctx.ID = nil
+		}
+	}
+	if err := s.scanObjects(pageObjs); err != nil {
+		return err
+	}
+	for i := 1; i <= s.ctx.PageCount; i++ {
+		if err := s.scanPage(i); err != nil {
+			return fmt.Errorf("page %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (s *scrubber) scanInfo() error {
+	d, err := readInfoDict(s.ctx)
+	if err != nil || d == nil {
+		return err
+	}
+	info := infoDictStrings(d)
+	for _, k := range sortedInfoKeys(info) {
+		cat := scrubInfoKeys[k]
+		// GTS_ keys identify PDF/X conformance, not the author.
+		if cat == "" && !isStandardInfoKey(k) && !strings.HasPrefix(k, "GTS_") {
+			cat = "private"
+		}
+		if cat != "" && s.note(cat, "Info", k, info[k]) && s.apply {
+			delete(d, k)
+		}
+	}
+	return nil
+}
+
+// scanObjects handles the data that can sit on any object: XMP packets and
+// /PieceInfo dictionaries.
+func (s *scrubber) scanObjects(pageObjs map[int]int) error {
+	var catalogXMP int
+	if ref := s.ctx.RootDict.IndirectRefEntry("Metadata"); ref != nil {
+		catalogXMP = ref.ObjectNumber.Value()
+	}
+	nrs := make([]int, 0, len(s.ctx.Table))
+	for nr := range s.ctx.Table {
+		nrs = append(nrs, nr)
+	}
+	sort.Ints(nrs)
+
+	for _, nr := range nrs {
+		entry := s.ctx.Table[nr]
+		if entry == nil || entry.Free || entry.Object == nil {
+			continue
+		}
+		location := fmt.Sprintf("object %d", nr)
+		switch {
+		case s.ctx.Root != nil && nr == s.ctx.Root.ObjectNumber.Value():
+			location = "catalog"
+		case pageObjs[nr] > 0:
+			location = fmt.Sprintf("page %d", pageObjs[nr])
+		}
+
+		d, ok := objectDict(entry.Object)
+		if !ok {
+			continue
+		}
+		if pi, err := s.ctx.DereferenceDict(d["PieceInfo"]); err == nil && pi != nil {
+			apps := make([]string, 0, len(pi))
+			for app := range pi {
+				apps = append(apps, app)
+			}
+			sort.Strings(apps)
+			if s.note("private", location, "PieceInfo", strings.Join(apps, ", ")) && s.apply {
+				delete(d, "PieceInfo")
+			}
+		}
+
+		sd, ok := entry.Object.(types.StreamDict)
+		if !ok || sd.Type() == nil || *sd.Type() != "Metadata" {
+			continue
+		}
+		if nr == catalogXMP {
+			location = "catalog XMP"
+		} else {
+			location = fmt.Sprintf("XMP, object %d", nr)
+		}
+		if err := sd.Decode(); err != nil {
+			printWarning(fmt.Sprintf("%s: cannot decode XMP packet: %v", location, err))
+			continue
+		}
+		s.findRemovedValues(sd.Content, location)
+		packet, changed, err := s.scrubXMP(sd.Content, location)
+		if err != nil {
+			return err
+		}
+		if changed && s.apply {
+			n := int64(len(packet))
+			nsd := types.NewStreamDict(types.Dict{
+				"Type":    types.Name("Metadata"),
+				"Subtype": types.Name("XML"),
+				"Length":  types.Integer(n),
+			}, 0, &n, nil, nil)
+			nsd.Content, nsd.Raw = packet, packet
+			entry.Object = nsd
+		}
+	}
+	return nil
+}
+
+// findRemovedValues notes every removed Info value that a packet still
+// holds as a whole element or attribute value, whatever property holds it.
+func (s *scrubber) findRemovedValues(data []byte, location string) {
+	for _, fd := range s.removed {
+		values := []string{fd.Value}
+		if fd.Field == "Author" {
+			values = append(values, strings.Split(fd.Value, ";")...)
+		}
+		for _, v := range values {
+			if v = strings.TrimSpace(v); v == "" {
+				continue
+			}
+			var esc strings.Builder
+			_ = xml.EscapeText(&esc, []byte(v))
+			re := regexp.MustCompile(`[>"']\s*(` + regexp.QuoteMeta(v) + `|` + regexp.QuoteMeta(esc.String()) + `)\s*[<"']`)
+			if re.Match(data) {
+				s.findings = append(s.findings, scrubFinding{fd.Category, location, "Info " + fd.Field + " value", v, "removed"})
+				break
+			}
+		}
+	}
+}
+
+// scrubXMP removes the scrubbed properties from one packet.
+func (s *scrubber) scrubXMP(data []byte, location string) ([]byte, bool, error) {
+	x, err := parseXMP(data)
+	if err != nil {
+		printWarning(fmt.Sprintf("%s: %v", location, err))
+		return data, false, nil
+	}
+	changed := false
+	for _, p := range scrubXMPKeys {
+		values := x.get(p.key)
+		if len(values) == 0 {
+			continue
+		}
+		if s.note(p.category, location, p.key, strings.Join(values, "; ")) {
+			data = removeXMPProperty(data, p.key)
+			changed = true
+		}
+	}
+	// pdfx holds the custom Info entries, which are scrubbed as private data.
+	for _, p := range x.Properties {
+		if p.Namespace != pdfxNS {
+			continue
+		}
+		if s.note("private", location, p.Key, strings.Join(p.Values, "; ")) {
+			data = removeXMPProperty(data, p.Key)
+			changed = true
+		}
+	}
+	if m := xmpToolkitRe.FindSubmatch(data); m != nil {
+		if s.note("software", location, "x:xmptk", strings.Trim(string(m[1]), `"'`)) {
+			data = xmpToolkitRe.ReplaceAll(data, nil)
+			changed = true
+		}
+	}
+	if changed {
+		if _, err := parseXMP(data); err != nil {
+			return nil, false, fmt.Errorf("%s: scrubbed XMP packet is not well-formed: %w", location, err)
+		}
+	}
+	return data, changed, nil
+}
+
+func (s *scrubber) scanPage(pageNr int) error {
+	d, _, _, err := s.ctx.PageDict(pageNr, false)
+	if err != nil || d == nil {
+		return err
+	}
+	location := fmt.Sprintf("page %d", pageNr)
+	if _, ok := d["Thumb"]; ok {
+		if s.note("thumbnails", location, "Thumb", "") && s.apply {
+			delete(d, "Thumb")
+		}
+	}
+
+	annots, err := s.ctx.DereferenceArray(d["Annots"])
+	if err != nil || len(annots) == 0 {
+		return err
+	}
+	removed := map[int]bool{}
+	drop := make([]bool, len(annots))
+	var popups []int
+	for i, o := range annots {
+		ad, err := s.ctx.DereferenceDict(o)
+		if err != nil || ad == nil {
+			continue
+		}
+		st := ad.NameEntry("Subtype")
+		if st == nil {
+			continue
+		}
+		if *st == "Popup" {
+			popups = append(popups, i)
+			continue
+		}
+		if !commentSubtypes[*st] {
+			continue
+		}
+		var parts []string
+		for _, k := range []string{"T", "Contents"} {
+			if v, ok := decodePDFText(ad[k]); ok && v != "" {
+				parts = append(parts, v)
+			}
+		}
+		if s.note("comments", location, *st, truncate(strings.Join(parts, ": "), 60)) {
+			drop[i] = true
+			if ref, ok := o.(types.IndirectRef); ok {
+				removed[ref.ObjectNumber.Value()] = true
+			}
+		}
+	}
+	// A popup goes with its parent; one without a parent is a comment of its own.
+	for _, i := range popups {
+		ad, _ := s.ctx.DereferenceDict(annots[i])
+		if parent := ad.IndirectRefEntry("Parent"); parent != nil {
+			drop[i] = removed[parent.ObjectNumber.Value()]
+		} else {
+			drop[i] = s.note("comments", location, "Popup", "")
+		}
+	}
+
+	if !s.apply {
+		return nil
+	}
+	kept := types.Array{}
+	for i, o := range annots {
+		if !drop[i] {
+			kept = append(kept, o)
+		}
+	}
+	if len(kept) == len(annots) {
+		return nil
+	}
+	if len(kept) == 0 {
+		delete(d, "Annots")
+	} else {
+		d["Annots"] = kept
+	}
+	return nil
+}
+
+func runScrub(inFile string) error {
+	ctx, err := readValidatedContext(inFile)
+	if err != nil {
+		return err
+	}
+	if ctx.Encrypt != nil {
+		return fmt.Errorf("%s is encrypted; decrypt it first", inFile)
+	}
+	origID := ""
+	if len(ctx.ID) > 0 {
+		origID = ctx.ID[0].String()
+	}
+
+	s := &scrubber{ctx: ctx, keep: scrubKeep, used: map[string]bool{}, apply: !scrubDryRun}
+	if err := s.scan(); err != nil {
+		return err
+	}
+	for _, k := range scrubKeep {
+		if !s.used[k] && !isScrubCategory(k) {
+			printWarning(fmt.Sprintf("--keep %s matched nothing in %s", k, inFile))
+		}
+	}
+
+	out := scrubOutput
+	if out == "" {
+		out = inFile
+	}
+	removed := 0
+	for _, fd := range s.findings {
+		if fd.Action != "kept" {
+			removed++
+		}
+	}
+	fields := map[string]interface{}{
+		"input":    inFile,
+		"output":   out,
+		"in_place": out == inFile,
+		"findings": nonNilFindings(s.findings),
+		"removed":  removed,
+		"kept":     len(s.findings) - removed,
+	}
+
+	printInfo(fmt.Sprintf("Scrubbing %s…", inFile))
+	printScrubReport(s.findings)
+
+	if removed == 0 {
+		printSuccess("Nothing to scrub")
+		if jsonOut {
+			fields["dry_run"] = scrubDryRun
+			return jsonResultOK("scrub", fields)
+		}
+		return nil
+	}
+	if scrubDryRun {
+		printInfo(fmt.Sprintf("[dry-run] would remove %d item(s) and write %s", removed, out))
+		if jsonOut {
+			fields["dry_run"] = true
+			return jsonResultOK("scrub", fields)
+		}
+		return nil
+	}
+
+	data, err := rewriteKeepingInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to write scrubbed PDF: %w", err)
+	}
+	// Verify the bytes before touching the destination.
+	remaining, err := verifyScrub(data, origID, s.findings)
+	if err != nil {
+		return err
+	}
+	if len(remaining) > 0 {
+		for _, fd := range remaining {
+			printError(fmt.Sprintf("still present: %s %s (%s)", fd.Location, fd.Field, fd.Category))
+		}
+		return fmt.Errorf("verification failed: %d item(s) survived scrubbing; %s was not written", len(remaining), out)
+	}
+
+	if err := writeFileAtomic(out, data); err != nil {
+		return err
+	}
+	if out == inFile {
+		printSuccess(fmt.Sprintf("Scrubbed in-place: %s (%d item(s) removed, %s)", out, removed, humanSize(int64(len(data)))))
+	} else {
+		printSuccess(fmt.Sprintf("Created: %s (%d item(s) removed, %s)", out, removed, humanSize(int64(len(data)))))
+	}
+	if jsonOut {
+		fields["verified"] = true
+		fields["size_bytes"] = len(data)
+		fields["size_human"] = humanSize(int64(len(data)))
+		return jsonResultOK("scrub", fields)
+	}
+	return nil
+}
+
+func isScrubCategory(k string) bool {
+	for _, c := range scrubCategories {
+		if strings.EqualFold(k, c) {
+			return true
+		}
+	}
+	return false
+}
+
+func nonNilFindings(f []scrubFinding) []scrubFinding {
+	if f == nil {
+		return []scrubFinding{}
+	}
+	return f
+}
+
+// verifyScrub re-reads a scrubbed file and returns every finding that should
+// have been removed, including Info values removed by the scrub (in findings)
+// that some XMP property still holds. The trailer ID only has to differ from
+// the original one.
+func verifyScrub(data []byte, origID string, findings []scrubFinding) ([]scrubFinding, error) {
+	ctx, err := readContextFrom(bytes.NewReader(data), pdfConfig())
+	if err != nil {
+		return nil, fmt.Errorf("scrubbed file does not read back: %w", err)
+	}
+	s := &scrubber{ctx: ctx, keep: scrubKeep, used: map[string]bool{}}
+	for _, fd := range findings {
+		if fd.Location == "Info" && fd.Action == "removed" && fd.Value != "" {
+			s.removed = append(s.removed, fd)
+		}
+	}
+	if err := s.scan(); err != nil {
+		return nil, fmt.Errorf("scrubbed file: %w", err)
+	}
+	var remaining []scrubFinding
+	for _, fd := range s.findings {
+		switch {
+		case fd.Action == "kept":
+		case fd.Field == "ID" && fd.Location == "trailer":
+			if len(ctx.ID) > 0 && ctx.ID[0].String() == origID {
+				remaining = append(remaining, fd)
+			}
+		default:
+			remaining = append(remaining, fd)
+		}
+	}
+	return remaining, nil
+}
+
+func printScrubReport(findings []scrubFinding) {
+	if quiet {
+		return
+	}
+	fmt.Println()
+	if len(findings) == 0 {
+		fmt.Println("  " + dimStyle.Render("No identifying data found."))
+		fmt.Println()
+		return
+	}
+	fmt.Println("  " + bold(fmt.Sprintf("%-11s %-16s %-26s %s", "CATEGORY", "LOCATION", "FIELD", "VALUE")))
+	for _, fd := range findings {
+		action := red("removed")
+		switch fd.Action {
+		case "kept":
+			action = yellow("kept")
+		case "replaced":
+			action = yellow("replaced")
+		}
+		value := truncate(fd.Value, 40)
+		if value != "" {
+			value = fmt.Sprintf("%q ", value)
+		}
+		fmt.Printf("  %s %-16s %-26s %s%s\n", cyan(fmt.Sprintf("%-11s", fd.Category)), fd.Location, fd.Field, value, action)
+	}
+	fmt.Println()
+}
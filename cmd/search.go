@@ -61,10 +61,12 @@ return fmt.Errorf("input file must be a PDF: %s", inputFile)
 }
 
 printInfo(fmt.Sprintf("Loading text from %s...", inputFile))
-allLines, err := loadLines(inputFile)
+pageTexts, err := extractTextByPage(inputFile)
 if err != nil {
 return fmt.Errorf("failed to extract text: %w", err)
 }
+allLines := indexLines(pageTexts)
+warnUnsearchable(inputFile, pageTexts)
 if len(allLines) == 0 {
 return fmt.Errorf("no extractable text found in %s", inputFile)
 }
@@ -125,6 +127,10 @@ pageTexts, err := extractTextByPage(filename)
 if err != nil {
 return nil, err
 }
+return indexLines(pageTexts), nil
+}
+
+func indexLines(pageTexts map[int]string) []indexedLine {
 pages := make([]int, 0, len(pageTexts))
 for p := range pageTexts {
 pages = append(pages, p)
@@ -140,7 +146,22 @@ lines = append(lines, indexedLine{page: page, text: l})
 }
 }
 }
-return lines, nil
+return lines
+}
+
+// warnUnsearchable tells the user which pages have images but no text layer,
+// since search silently finds nothing there.
+func warnUnsearchable(filename string, pageTexts map[int]string) {
+kinds, err := classifyPages(filename, pageTexts)
+if err != nil {
+return
+}
+scans := pagesOfKind(kinds, kindImage)
+if len(scans) == 0 {
+return
+}
+printWarning(fmt.Sprintf("%d of %d pages are image-only (no text layer) and cannot be searched: %s",
+len(scans), len(kinds), compactPageList(scans)))
 }
 
 func lineStrings(lines []indexedLine) []string {
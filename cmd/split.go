@@ -32,8 +32,11 @@ var splitCmd = &cobra.Command{
   pdfed split input.pdf -p 1-3,7,10-15       Extract multiple ranges
   pdfed split input.pdf -p 1-5 -o chap.pdf   Specify output filename
   pdfed split input.pdf -p 1-5 -o ./out      Specify output directory
+  pdfed split input.pdf -p '"A-1"-"A-5"'     Labels containing a dash
+  pdfed split input.pdf -p 1@2-5@2           Pages 1-5 of the second "1"
   pdfed split input.pdf -P 1-5               Use raw PDF page indices (short form)
   pdfed split input.pdf --pdf-pages 1-5      Use raw PDF page indices (long form)
+  pdfed split input.pdf -P image             Extract the image-only (scanned) pages
   pdfed split input.pdf -e                   Extract each page to separate files
   pdfed split input.pdf -e -o ./pages        Extract all to directory
 
@@ -43,7 +46,11 @@ var splitCmd = &cobra.Command{
   Combine both: 1-3,5,7-10
 
   Use -p/--pages for real (printed) page numbers.
-  Use -P/--pdf-pages for raw PDF page indices (1-based).`, bold("Examples:"), bold("Page Syntax:")),
+  Use -P/--pdf-pages for raw PDF page indices (1-based).
+  Quote or escape printed labels that contain - , or @: -p '"A-1"-"A-5"'
+  or -p 'A\-1-A\-5'. When a label is used by several pages, 1@2 picks the
+  second page labelled 1 and 1@* all of them.
+  -P also accepts the page kinds text, image, mixed and blank, e.g. -P 1,image.`, bold("Examples:"), bold("Page Syntax:")),
 	Args: cobra.ExactArgs(1),
 	RunE: runSplit,
 }
@@ -187,8 +194,11 @@ func extractPageRanges(inputFile string, pageCount int) error {
 
 	if pdfPages != "" {
 		rangeStr = pdfPages
-		var err error
-		pageList, err = parsePageRanges(rangeStr, pageCount)
+		expanded, err := expandPageKinds(inputFile, rangeStr, false)
+		if err != nil {
+			return err
+		}
+		pageList, err = parsePageRanges(expanded, pageCount)
 		if err != nil {
 			return err
 		}
@@ -200,13 +210,13 @@ func extractPageRanges(inputFile string, pageCount int) error {
 		}
 		pageList, err = resolveRealPages(rangeStr, labelsMap, pageCount)
 		if err != nil {
-			return err
+			return fmt.Errorf("%w (run 'pdfed labels %s' to list the page labels)", err, inputFile)
 		}
 	}
 
 	var outputFile string
 	if output == "" {
-		sanitizedPages := strings.ReplaceAll(rangeStr, ",", "_")
+		sanitizedPages := rangeFileTag(rangeStr)
 		outputFile = fmt.Sprintf("%s_pages_%s.pdf", baseName, sanitizedPages)
 	} else if strings.HasSuffix(strings.ToLower(output), ".pdf") {
 		outputFile = output
@@ -214,7 +224,7 @@ func extractPageRanges(inputFile string, pageCount int) error {
 		if err := os.MkdirAll(output, 0755); err != nil {
 			return fmt.Errorf("failed to create output directory: %w", err)
 		}
-		sanitizedPages := strings.ReplaceAll(rangeStr, ",", "_")
+		sanitizedPages := rangeFileTag(rangeStr)
 		outputFile = filepath.Join(output, fmt.Sprintf("%s_pages_%s.pdf", baseName, sanitizedPages))
 	}
 
@@ -226,7 +236,7 @@ func extractPageRanges(inputFile string, pageCount int) error {
 	printInfo(fmt.Sprintf("Extracting %d pages (PDF pages %v)", len(pageList), pageList))
 
 	if dryRun {
-		sanitizedPages := strings.ReplaceAll(rangeStr, ",", "_")
+		sanitizedPages := rangeFileTag(rangeStr)
 		previewFile := outputFile
 		if previewFile == "" {
 			previewFile = fmt.Sprintf("%s_pages_%s.pdf", baseName, sanitizedPages)
@@ -343,3 +353,6 @@ func parsePageRanges(rangeStr string, maxPage int) ([]int, error) {
 
 	return pages, nil
 }
+
+// rangeFileTag turns a page selection into a file-name fragment.
+var rangeFileTag = strings.NewReplacer(",", "_", "\"", "", "'", "", "\\", "", "/", "_", "@", "at", "*", "all", " ", "").Replace
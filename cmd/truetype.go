@@ -0,0 +1,333 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// TrueType font programs (FontFile2) as far as subsetting needs them: the
+// table directory, cmap lookups, and glyf/loca with composite glyphs.
+
+// sfntFont is a parsed TrueType table directory.
+type sfntFont struct {
+	version uint32
+	tables  map[string][]byte
+}
+
+func parseSFNT(b []byte) (*sfntFont, error) {
+	if len(b) < 12 {
+		return nil, fmt.Errorf("font program too short")
+	}
+	f := &sfntFont{version: binary.BigEndian.Uint32(b), tables: map[string][]byte{}}
+	switch f.version {
+	case 0x00010000, 0x74727565: // 1.0 or 'true'
+	case 0x4F54544F: // 'OTTO'
+		return nil, fmt.Errorf("OpenType font with CFF outlines")
+	default:
+		return nil, fmt.Errorf("not a TrueType font")
+	}
+	n := int(binary.BigEndian.Uint16(b[4:]))
+	if len(b) < 12+16*n {
+		return nil, fmt.Errorf("truncated table directory")
+	}
+	for i := 0; i < n; i++ {
+		rec := b[12+16*i:]
+		tag := string(rec[:4])
+		off := int(binary.BigEndian.Uint32(rec[8:]))
+		length := int(binary.BigEndian.Uint32(rec[12:]))
+		if off < 0 || length < 0 || off+length > len(b) {
+			return nil, fmt.Errorf("table %q out of bounds", tag)
+		}
+		f.tables[tag] = b[off : off+length]
+	}
+	for _, tag := range []string{"head", "maxp", "loca", "glyf"} {
+		if f.tables[tag] == nil {
+			return nil, fmt.Errorf("no %s table", tag)
+		}
+	}
+	if len(f.tables["head"]) < 54 || len(f.tables["maxp"]) < 6 {
+		return nil, fmt.Errorf("truncated head or maxp table")
+	}
+	return f, nil
+}
+
+func (f *sfntFont) numGlyphs() int {
+	return int(binary.BigEndian.Uint16(f.tables["maxp"][4:]))
+}
+
+func (f *sfntFont) longLoca() bool {
+	return binary.BigEndian.Uint16(f.tables["head"][50:]) != 0
+}
+
+// glyphOffsets returns the numGlyphs+1 glyf offsets from loca.
+func (f *sfntFont) glyphOffsets() ([]int, error) {
+	n := f.numGlyphs()
+	loca := f.tables["loca"]
+	offs := make([]int, n+1)
+	for i := range offs {
+		if f.longLoca() {
+			if 4*i+4 > len(loca) {
+				return nil, fmt.Errorf("truncated loca table")
+			}
+			offs[i] = int(binary.BigEndian.Uint32(loca[4*i:]))
+		} else {
+			if 2*i+2 > len(loca) {
+				return nil, fmt.Errorf("truncated loca table")
+			}
+			offs[i] = 2 * int(binary.BigEndian.Uint16(loca[2*i:]))
+		}
+	}
+	glyf := len(f.tables["glyf"])
+	for i := 1; i < len(offs); i++ {
+		if offs[i] < offs[i-1] || offs[i] > glyf {
+			return nil, fmt.Errorf("bad loca entry for glyph %d", i-1)
+		}
+	}
+	return offs, nil
+}
+
+// glyphComponents returns the glyphs a composite glyph is built from.
+func glyphComponents(g []byte) []int {
+	if len(g) < 10 || int16(binary.BigEndian.Uint16(g)) >= 0 {
+		return nil
+	}
+	var out []int
+	for p := 10; p+4 <= len(g); {
+		flags := binary.BigEndian.Uint16(g[p:])
+		out = append(out, int(binary.BigEndian.Uint16(g[p+2:])))
+		p += 4
+		if flags&0x0001 != 0 { // ARG_1_AND_2_ARE_WORDS
+			p += 4
+		} else {
+			p += 2
+		}
+		switch {
+		case flags&0x0008 != 0: // WE_HAVE_A_SCALE
+			p += 2
+		case flags&0x0040 != 0: // WE_HAVE_AN_X_AND_Y_SCALE
+			p += 4
+		case flags&0x0080 != 0: // WE_HAVE_A_TWO_BY_TWO
+			p += 8
+		}
+		if flags&0x0020 == 0 { // MORE_COMPONENTS
+			break
+		}
+	}
+	return out
+}
+
+// subsetTrueType empties the outlines of every glyph not in keep (or used
+// by a composite in keep) and drops the tables PDF viewers do not read.
+// Glyph ids stay the same, so nothing that refers to them has to change.
+// It returns the new font and the number of glyphs with outlines before
+// and after.
+func subsetTrueType(b []byte, keep map[int]bool) ([]byte, int, int, error) {
+	f, err := parseSFNT(b)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	offs, err := f.glyphOffsets()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	glyf := f.tables["glyf"]
+	n := f.numGlyphs()
+	glyph := func(gid int) []byte { return glyf[offs[gid]:offs[gid+1]] }
+
+	kept := map[int]bool{}
+	var add func(gid, depth int)
+	add = func(gid, depth int) {
+		if gid < 0 || gid >= n || kept[gid] || depth > 16 {
+			return
+		}
+		kept[gid] = true
+		for _, c := range glyphComponents(glyph(gid)) {
+			add(c, depth+1)
+		}
+	}
+	add(0, 0) // .notdef
+	for gid := range keep {
+		add(gid, 0)
+	}
+
+	var newGlyf []byte
+	newOffs := make([]int, n+1)
+	before, after := 0, 0
+	for gid := 0; gid < n; gid++ {
+		newOffs[gid] = len(newGlyf)
+		g := glyph(gid)
+		if len(g) > 0 {
+			before++
+		}
+		if !kept[gid] || len(g) == 0 {
+			continue
+		}
+		after++
+		newGlyf = append(newGlyf, g...)
+		for len(newGlyf)%4 != 0 {
+			newGlyf = append(newGlyf, 0)
+		}
+	}
+	newOffs[n] = len(newGlyf)
+
+	long := f.longLoca() || len(newGlyf) > 0x1FFFE
+	var loca []byte
+	for _, o := range newOffs {
+		if long {
+			loca = binary.BigEndian.AppendUint32(loca, uint32(o))
+		} else {
+			loca = binary.BigEndian.AppendUint16(loca, uint16(o/2))
+		}
+	}
+	head := append([]byte(nil), f.tables["head"]...)
+	if long {
+		binary.BigEndian.PutUint16(head[50:], 1)
+	} else {
+		binary.BigEndian.PutUint16(head[50:], 0)
+	}
+
+	out := map[string][]byte{"glyf": newGlyf, "loca": loca, "head": head}
+	for _, tag := range []string{"cmap", "cvt ", "fpgm", "gasp", "hhea", "hmtx", "maxp", "name", "OS/2", "post", "prep", "vhea", "vmtx"} {
+		if t, ok := f.tables[tag]; ok {
+			out[tag] = t
+		}
+	}
+	return writeSFNT(f.version, out), before, after, nil
+}
+
+func sfntChecksum(b []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(b); i += 4 {
+		var w [4]byte
+		copy(w[:], b[i:])
+		sum += binary.BigEndian.Uint32(w[:])
+	}
+	return sum
+}
+
+// writeSFNT assembles a font from its tables, with checksums and the head
+// table's checkSumAdjustment filled in.
+func writeSFNT(version uint32, tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	n := len(tags)
+	pow, shift := 1, 0
+	for pow*2 <= n {
+		pow, shift = pow*2, shift+1
+	}
+	out := binary.BigEndian.AppendUint32(nil, version)
+	for _, v := range []int{n, pow * 16, shift, n*16 - pow*16} {
+		out = binary.BigEndian.AppendUint16(out, uint16(v))
+	}
+	dir := len(out)
+	out = append(out, make([]byte, 16*n)...)
+	headAt := -1
+	for i, tag := range tags {
+		t := tables[tag]
+		if tag == "head" {
+			t = append([]byte(nil), t...)
+			binary.BigEndian.PutUint32(t[8:], 0)
+			headAt = len(out)
+		}
+		rec := out[dir+16*i:]
+		copy(rec, tag)
+		binary.BigEndian.PutUint32(rec[4:], sfntChecksum(t))
+		binary.BigEndian.PutUint32(rec[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(rec[12:], uint32(len(t)))
+		out = append(out, t...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	if headAt >= 0 {
+		binary.BigEndian.PutUint32(out[headAt+8:], 0xB1B0AFBA-sfntChecksum(out))
+	}
+	return out
+}
+
+// cmapSubtable returns the cmap subtable for a platform and encoding, or nil.
+func (f *sfntFont) cmapSubtable(platform, encoding uint16) []byte {
+	cmap := f.tables["cmap"]
+	if len(cmap) < 4 {
+		return nil
+	}
+	n := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < n && 4+8*i+8 <= len(cmap); i++ {
+		rec := cmap[4+8*i:]
+		if binary.BigEndian.Uint16(rec) != platform || binary.BigEndian.Uint16(rec[2:]) != encoding {
+			continue
+		}
+		off := int(binary.BigEndian.Uint32(rec[4:]))
+		if off+4 > len(cmap) {
+			return nil
+		}
+		return cmap[off:]
+	}
+	return nil
+}
+
+// cmapLookup maps a character code through a cmap subtable of format 0, 4,
+// 6 or 12. It returns 0 (.notdef) for codes the subtable does not map.
+func cmapLookup(sub []byte, c uint32) int {
+	if len(sub) < 4 {
+		return 0
+	}
+	u16 := func(p int) int {
+		if p < 0 || p+2 > len(sub) {
+			return 0
+		}
+		return int(binary.BigEndian.Uint16(sub[p:]))
+	}
+	u32 := func(p int) uint32 {
+		if p < 0 || p+4 > len(sub) {
+			return 0
+		}
+		return binary.BigEndian.Uint32(sub[p:])
+	}
+	switch u16(0) {
+	case 0:
+		if c < 256 && 6+int(c) < len(sub) {
+			return int(sub[6+c])
+		}
+	case 4:
+		segs := u16(6) / 2
+		ends, starts := 14, 16+2*segs
+		deltas, ranges := starts+2*segs, starts+4*segs
+		for i := 0; i < segs; i++ {
+			if uint32(u16(ends+2*i)) < c {
+				continue
+			}
+			start := uint32(u16(starts + 2*i))
+			if c < start {
+				return 0
+			}
+			delta, ro := u16(deltas+2*i), u16(ranges+2*i)
+			if ro == 0 {
+				return (int(c) + delta) & 0xFFFF
+			}
+			g := u16(ranges + 2*i + ro + 2*int(c-start))
+			if g == 0 {
+				return 0
+			}
+			return (g + delta) & 0xFFFF
+		}
+	case 6:
+		first, count := uint32(u16(6)), uint32(u16(8))
+		if c >= first && c-first < count {
+			return u16(10 + 2*int(c-first))
+		}
+	case 12:
+		groups := int(u32(12))
+		for i := 0; i < groups; i++ {
+			g := 16 + 12*i
+			if start, end := u32(g), u32(g+4); c >= start && c <= end {
+				return int(u32(g+8) + c - start)
+			}
+		}
+	}
+	return 0
+}
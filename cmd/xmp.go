@@ -0,0 +1,672 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+const (
+	rdfNS = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmlNS = "http://www.w3.org/XML/1998/namespace"
+)
+
+// xmpPrefixes maps well-known XMP namespaces to their conventional prefix, so
+// lookups like "dc:title" work regardless of the prefix a producer chose.
+var xmpPrefixes = map[string]string{
+	"http://purl.org/dc/elements/1.1/":                 "dc",
+	"http://ns.adobe.com/xap/1.0/":                     "xmp",
+	"http://ns.adobe.com/pdf/1.3/":                     "pdf",
+	"http://ns.adobe.com/xap/1.0/mm/":                  "xmpMM",
+	"http://ns.adobe.com/xap/1.0/rights/":              "xmpRights",
+	"http://ns.adobe.com/xap/1.0/sType/ResourceEvent#": "stEvt",
+	"http://ns.adobe.com/xap/1.0/sType/ResourceRef#":   "stRef",
+	"http://ns.adobe.com/photoshop/1.0/":               "photoshop",
+	"http://ns.adobe.com/pdfx/1.3/":                    "pdfx",
+	"http://www.aiim.org/pdfa/ns/id/":                  "pdfaid",
+	"http://www.aiim.org/pdfua/ns/id/":                 "pdfuaid",
+	"http://www.aiim.org/pdfa/ns/extension/":           "pdfaExtension",
+	"http://www.aiim.org/pdfa/ns/schema#":              "pdfaSchema",
+	"http://www.aiim.org/pdfa/ns/property#":            "pdfaProperty",
+	"http://ns.adobe.com/xap/1.0/t/pg/":                "xmpTPg",
+	"http://prismstandard.org/namespaces/basic/2.0/":   "prism",
+}
+
+// xmpProperty is one top-level property of an XMP packet. Arrays yield one value
+// per item; structures are rendered as "field=value; …".
+type xmpProperty struct {
+	Key       string   `json:"key"`
+	Namespace string   `json:"namespace"`
+	Values    []string `json:"values"`
+}
+
+// xmpPacket is the parsed content of a catalog Metadata stream.
+type xmpPacket struct {
+	Properties []xmpProperty
+	Size       int // raw packet length in bytes
+}
+
+// get returns the values of a property by canonical key (e.g. "dc:title").
+func (x *xmpPacket) get(key string) []string {
+	if x == nil {
+		return nil
+	}
+	for _, p := range x.Properties {
+		if p.Key == key {
+			return p.Values
+		}
+	}
+	return nil
+}
+
+// first returns the first value of a property, or "".
+func (x *xmpPacket) first(key string) string {
+	if v := x.get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// pdfA returns the PDF/A identification (e.g. "PDF/A-2b"), or "".
+func (x *xmpPacket) pdfA() string {
+	part := x.first("pdfaid:part")
+	if part == "" {
+		return ""
+	}
+	return "PDF/A-" + part + strings.ToLower(x.first("pdfaid:conformance"))
+}
+
+// readCatalogXMP returns the raw XMP packet referenced by the catalog, or nil.
+func readCatalogXMP(ctx *model.Context) ([]byte, error) {
+	if ctx.RootDict == nil {
+		return nil, nil
+	}
+	obj, found := ctx.RootDict.Find("Metadata")
+	if !found {
+		return nil, nil
+	}
+	sd, _, err := ctx.DereferenceStreamDict(obj)
+	if err != nil || sd == nil {
+		return nil, err
+	}
+	if err := sd.Decode(); err != nil {
+		return nil, fmt.Errorf("decode XMP metadata: %w", err)
+	}
+	return sd.Content, nil
+}
+
+// parseXMP parses an XMP packet into its top-level properties, in document order.
+func parseXMP(data []byte) (*xmpPacket, error) {
+	p := &xmpParser{
+		dec:      xml.NewDecoder(bytes.NewReader(data)),
+		prefixes: map[string]string{},
+		packet:   &xmpPacket{Size: len(data)},
+	}
+	p.dec.Strict = false
+
+	for {
+		tok, err := p.dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("parse XMP: %w", err)
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		p.noteNamespaces(el)
+		if el.Name.Space == rdfNS && el.Name.Local == "Description" {
+			err := p.readDescription(el, func(name xml.Name, values []string) {
+				p.packet.add(p.qname(name), name.Space, values)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("parse XMP: %w", err)
+			}
+		}
+	}
+	return p.packet, nil
+}
+
+func (x *xmpPacket) add(key, ns string, values []string) {
+	for i := range x.Properties {
+		if x.Properties[i].Key == key {
+			x.Properties[i].Values = append(x.Properties[i].Values, values...)
+			return
+		}
+	}
+	x.Properties = append(x.Properties, xmpProperty{Key: key, Namespace: ns, Values: values})
+}
+
+type xmpParser struct {
+	dec      *xml.Decoder
+	prefixes map[string]string // namespace URI → prefix declared in the packet
+	packet   *xmpPacket
+}
+
+func (p *xmpParser) noteNamespaces(el xml.StartElement) {
+	for _, a := range el.Attr {
+		if a.Name.Space == "xmlns" {
+			if _, seen := p.prefixes[a.Value]; !seen {
+				p.prefixes[a.Value] = a.Name.Local
+			}
+		}
+	}
+}
+
+// qname renders a name as prefix:local, preferring the conventional prefix.
+func (p *xmpParser) qname(n xml.Name) string {
+	if pre, ok := xmpPrefixes[n.Space]; ok {
+		return pre + ":" + n.Local
+	}
+	if pre, ok := p.prefixes[n.Space]; ok && pre != "" {
+		return pre + ":" + n.Local
+	}
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + n.Local
+}
+
+// isPropertyAttr reports whether an attribute is an XMP property in shorthand form.
+func isPropertyAttr(a xml.Attr) bool {
+	switch {
+	case a.Name.Space == "xmlns", a.Name.Space == "" && a.Name.Local == "xmlns":
+		return false
+	case a.Name.Space == rdfNS, a.Name.Space == xmlNS, a.Name.Space == "xml":
+		return false
+	case a.Name.Space == "":
+		return false
+	}
+	return true
+}
+
+// readDescription consumes an rdf:Description (or a parseType="Resource"
+// property) and reports each property it holds.
+func (p *xmpParser) readDescription(start xml.StartElement, emit func(xml.Name, []string)) error {
+	for _, a := range start.Attr {
+		if isPropertyAttr(a) {
+			emit(a.Name, []string{a.Value})
+		}
+	}
+	for {
+		tok, err := p.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			p.noteNamespaces(t)
+			values, err := p.readValue(t)
+			if err != nil {
+				return err
+			}
+			emit(t.Name, values)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// readValue consumes a property element and returns its values.
+func (p *xmpParser) readValue(start xml.StartElement) ([]string, error) {
+	var fields []string
+	field := func(name xml.Name, values []string) {
+		fields = append(fields, p.qname(name)+"="+strings.Join(values, ", "))
+	}
+
+	resource := ""
+	isStruct := false
+	for _, a := range start.Attr {
+		switch {
+		case a.Name.Space == rdfNS && a.Name.Local == "resource":
+			resource = a.Value
+		case a.Name.Space == rdfNS && a.Name.Local == "parseType" && a.Value == "Resource":
+			isStruct = true
+		case isPropertyAttr(a):
+			field(a.Name, []string{a.Value})
+		}
+	}
+	if isStruct {
+		if err := p.readDescription(xml.StartElement{}, field); err != nil {
+			return nil, err
+		}
+		return []string{strings.Join(fields, "; ")}, nil
+	}
+
+	var text strings.Builder
+	var values []string
+	for {
+		tok, err := p.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			p.noteNamespaces(t)
+			switch {
+			case t.Name.Space == rdfNS && (t.Name.Local == "Alt" || t.Name.Local == "Seq" || t.Name.Local == "Bag"):
+				items, err := p.readItems(t.Name.Local == "Alt")
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, items...)
+			case t.Name.Space == rdfNS && t.Name.Local == "Description":
+				if err := p.readDescription(t, field); err != nil {
+					return nil, err
+				}
+			default:
+				v, err := p.readValue(t)
+				if err != nil {
+					return nil, err
+				}
+				field(t.Name, v)
+			}
+		case xml.EndElement:
+			switch {
+			case len(fields) > 0:
+				return []string{strings.Join(fields, "; ")}, nil
+			case values != nil:
+				return values, nil
+			case resource != "":
+				return []string{resource}, nil
+			}
+			if s := strings.TrimSpace(text.String()); s != "" {
+				return []string{s}, nil
+			}
+			return nil, nil
+		}
+	}
+}
+
+// readItems consumes the rdf:li items of an rdf:Alt/Seq/Bag. For language
+// alternatives the x-default entry comes first.
+func (p *xmpParser) readItems(alt bool) ([]string, error) {
+	var items []string
+	for {
+		tok, err := p.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			p.noteNamespaces(t)
+			v, err := p.readValue(t)
+			if err != nil {
+				return nil, err
+			}
+			s := strings.Join(v, ", ")
+			if s == "" {
+				continue
+			}
+			isDefault := false
+			for _, a := range t.Attr {
+				if a.Name.Local == "lang" && a.Value == "x-default" {
+					isDefault = true
+				}
+			}
+			if alt && isDefault {
+				items = append([]string{s}, items...)
+			} else {
+				items = append(items, s)
+			}
+		case xml.EndElement:
+			return items, nil
+		}
+	}
+}
+
+// ── Info dictionary vs. XMP ──────────────────────────────────────────────────
+
+// metaConflict is a field whose Info dictionary and XMP values disagree.
+type metaConflict struct {
+	Field  string `json:"field"`
+	XMPKey string `json:"xmp_key"`
+	Info   string `json:"info"`
+	XMP    string `json:"xmp"`
+}
+
+// infoXMPPairs lists Info dictionary fields and their XMP counterparts.
+var infoXMPPairs = []struct{ field, key string }{
+	{"Title", "dc:title"},
+	{"Author", "dc:creator"},
+	{"Subject", "dc:description"},
+	{"Keywords", "pdf:Keywords"},
+	{"Creator", "xmp:CreatorTool"},
+	{"Producer", "pdf:Producer"},
+	{"CreationDate", "xmp:CreateDate"},
+	{"ModDate", "xmp:ModifyDate"},
+}
+
+// compareInfoXMP reports fields set in both places whose values disagree.
+// info maps Info dictionary field names to their values.
+func compareInfoXMP(info map[string]string, x *xmpPacket) []metaConflict {
+	if x == nil {
+		return nil
+	}
+	var out []metaConflict
+	for _, pair := range infoXMPPairs {
+		iv := strings.TrimSpace(info[pair.field])
+		xv := x.get(pair.key)
+		if iv == "" || len(xv) == 0 {
+			continue
+		}
+		if !metaValuesAgree(pair.field, iv, xv) {
+			out = append(out, metaConflict{Field: pair.field, XMPKey: pair.key, Info: iv, XMP: strings.Join(xv, "; ")})
+		}
+	}
+	return out
+}
+
+func metaValuesAgree(field, iv string, xv []string) bool {
+	switch field {
+	case "CreationDate", "ModDate":
+		it, ok1 := types.DateTime(iv, true)
+		xt, ok2 := parseXMPDate(xv[0])
+		if !ok1 || !ok2 {
+			return iv == xv[0]
+		}
+		return it.Truncate(time.Second).Equal(xt.Truncate(time.Second))
+	case "Author":
+		for _, sep := range []string{"; ", ", ", ";", ","} {
+			if iv == strings.Join(xv, sep) {
+				return true
+			}
+		}
+		return false
+	case "Keywords":
+		return sameWordSet(iv, strings.Join(xv, ","))
+	}
+	return iv == strings.TrimSpace(xv[0])
+}
+
+func sameWordSet(a, b string) bool {
+	split := func(s string) []string {
+		f := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' })
+		for i := range f {
+			f[i] = strings.TrimSpace(f[i])
+		}
+		sort.Strings(f)
+		return f
+	}
+	return strings.Join(split(a), "\x00") == strings.Join(split(b), "\x00")
+}
+
+// parseXMPDate parses the ISO 8601 subset used by XMP dates.
+func parseXMPDate(s string) (time.Time, bool) {
+	for _, layout := range []string{
+		time.RFC3339Nano, time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04Z07:00",
+		"2006-01-02T15:04", "2006-01-02", "2006-01", "2006",
+	} {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ── editing ───────────────────────────────────────────────────────────────────
+
+// xmpArrayKinds lists the properties whose value is an RDF container.
+var xmpArrayKinds = map[string]string{
+	"dc:title":       "Alt",
+	"dc:description": "Alt",
+	"dc:rights":      "Alt",
+	"dc:creator":     "Seq",
+	"dc:subject":     "Bag",
+}
+
+// xmpNamespace returns the namespace URI of a well-known prefix.
+func xmpNamespace(prefix string) string {
+	for ns, p := range xmpPrefixes {
+		if p == prefix {
+			return ns
+		}
+	}
+	return ""
+}
+
+// xmpDeclaredPrefixes returns every prefix a packet binds to ns, anywhere.
+func xmpDeclaredPrefixes(data []byte, ns string) []string {
+	re := regexp.MustCompile(`xmlns:([A-Za-z_][\w.-]*)\s*=\s*["']` + regexp.QuoteMeta(ns) + `["']`)
+	var prefixes []string
+	for _, m := range re.FindAllSubmatch(data, -1) {
+		if p := string(m[1]); !slices.Contains(prefixes, p) {
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes
+}
+
+// removeXMPProperty deletes every occurrence of a property, in element or
+// attribute form, from a packet. key is canonical, e.g. "pdf:Producer".
+func removeXMPProperty(data []byte, key string) []byte {
+	prefix, local, _ := strings.Cut(key, ":")
+	prefixes := xmpDeclaredPrefixes(data, xmpNamespace(prefix))
+	if len(prefixes) == 0 {
+		prefixes = []string{prefix}
+	}
+	for _, p := range prefixes {
+		name := regexp.QuoteMeta(p + ":" + local)
+		for _, re := range []*regexp.Regexp{
+			regexp.MustCompile(`(?s)\s*<` + name + `(\s[^>]*)?>.*?</` + name + `\s*>`),
+			regexp.MustCompile(`\s*<` + name + `(\s[^>]*)?/>`),
+			regexp.MustCompile(`\s+` + name + `\s*=\s*("[^"]*"|'[^']*')`),
+		} {
+			data = re.ReplaceAll(data, nil)
+		}
+	}
+	return data
+}
+
+// xmpDescription is one rdf:Description start tag of a packet.
+type xmpDescription struct {
+	start, end int               // byte offsets of the start tag
+	rdf        string            // the tag's own prefix
+	scope      map[string]string // prefixes in scope there, to namespaces
+}
+
+// scanXMPDescriptions returns a packet's rdf:Description start tags. Unlike
+// parseXMP, it fails on a prefix used where it is not bound.
+func scanXMPDescriptions(data []byte) ([]xmpDescription, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	scopes := []map[string]string{{"xml": xmlNS}}
+	var descs []xmpDescription
+	for {
+		start := int(dec.InputOffset())
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			return descs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			scope := maps.Clone(scopes[len(scopes)-1])
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" {
+					scope[a.Name.Local] = a.Value
+				}
+			}
+			bound := func(n xml.Name) error {
+				if _, ok := scope[n.Space]; n.Space != "" && n.Space != "xmlns" && !ok {
+					return fmt.Errorf("unbound prefix %q in %s:%s", n.Space, n.Space, n.Local)
+				}
+				return nil
+			}
+			if err := bound(t.Name); err != nil {
+				return nil, err
+			}
+			for _, a := range t.Attr {
+				if err := bound(a.Name); err != nil {
+					return nil, err
+				}
+			}
+			if t.Name.Local == "Description" && scope[t.Name.Space] == rdfNS {
+				descs = append(descs, xmpDescription{start: start, end: int(dec.InputOffset()), rdf: t.Name.Space, scope: scope})
+			}
+			scopes = append(scopes, scope)
+		case xml.EndElement:
+			if len(scopes) > 1 {
+				scopes = scopes[:len(scopes)-1]
+			}
+		}
+	}
+}
+
+// insertXMPProperty adds a property as an element of the first
+// rdf:Description in whose scope its namespace is bound, or else of the
+// first one, declaring the namespace on it.
+func insertXMPProperty(data []byte, key string, values []string) ([]byte, error) {
+	prefix, local, _ := strings.Cut(key, ":")
+	ns := xmpNamespace(prefix)
+	descs, err := scanXMPDescriptions(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(descs) == 0 {
+		return nil, fmt.Errorf("XMP packet has no rdf:Description")
+	}
+	target, declare := -1, ""
+	for i, d := range descs {
+		for p, u := range d.scope {
+			if u == ns && (target < 0 || p < prefix) {
+				target, prefix = i, p
+			}
+		}
+		if target >= 0 {
+			break
+		}
+	}
+	if target < 0 {
+		target = 0
+		declare = fmt.Sprintf(` xmlns:%s="%s"`, prefix, ns)
+	}
+	d := descs[target]
+	rdf := d.rdf
+
+	var el strings.Builder
+	name := prefix + ":" + local
+	esc := func(s string) string {
+		var b strings.Builder
+		_ = xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	if kind, ok := xmpArrayKinds[key]; ok {
+		fmt.Fprintf(&el, "\n   <%s><%s:%s>", name, rdf, kind)
+		for _, v := range values {
+			if kind == "Alt" {
+				fmt.Fprintf(&el, `<%s:li xml:lang="x-default">%s</%s:li>`, rdf, esc(v), rdf)
+			} else {
+				fmt.Fprintf(&el, "<%s:li>%s</%s:li>", rdf, esc(v), rdf)
+			}
+		}
+		fmt.Fprintf(&el, "</%s:%s></%s>", rdf, kind, name)
+	} else {
+		fmt.Fprintf(&el, "\n   <%s>%s</%s>", name, esc(strings.Join(values, ", ")), name)
+	}
+
+	var out bytes.Buffer
+	if tag := bytes.TrimRight(data[d.start:d.end-1], " \t\r\n"); bytes.HasSuffix(tag, []byte("/")) { // self-closing <rdf:Description …/>
+		out.Write(data[:d.start])
+		out.Write(tag[:len(tag)-1])
+		out.WriteString(declare + ">" + el.String() + fmt.Sprintf("\n  </%s:Description>", rdf))
+	} else {
+		out.Write(data[:d.end-1])
+		out.WriteString(declare + ">" + el.String())
+	}
+	out.Write(data[d.end:])
+	return out.Bytes(), nil
+}
+
+// xmpDate converts a PDF date string to the ISO 8601 form XMP uses.
+func xmpDate(pdfDate string) (string, bool) {
+	t, ok := types.DateTime(pdfDate, true)
+	if !ok {
+		return "", false
+	}
+	return t.Format(time.RFC3339), true
+}
+
+// syncXMP mirrors Info dictionary changes into an XMP packet. changes maps
+// Info field names to their new value, nil meaning the field was removed;
+// fields without an XMP counterpart are ignored. xmp:MetadataDate is set to now.
+func syncXMP(data []byte, changes map[string]*string, now time.Time) ([]byte, error) {
+	set := func(key string, values []string) error {
+		data = removeXMPProperty(data, key)
+		if len(values) == 0 {
+			return nil
+		}
+		var err error
+		data, err = insertXMPProperty(data, key, values)
+		return err
+	}
+	split := func(s string, seps string) []string {
+		var out []string
+		for _, f := range strings.FieldsFunc(s, func(r rune) bool { return strings.ContainsRune(seps, r) }) {
+			if f = strings.TrimSpace(f); f != "" {
+				out = append(out, f)
+			}
+		}
+		return out
+	}
+
+	for _, pair := range infoXMPPairs {
+		v, ok := changes[pair.field]
+		if !ok {
+			continue
+		}
+		var values []string
+		if v != nil && *v != "" {
+			switch pair.field {
+			case "Author":
+				values = split(*v, ";")
+			case "CreationDate", "ModDate":
+				d, ok := xmpDate(*v)
+				if !ok {
+					return nil, fmt.Errorf("invalid date for %s: %q", pair.field, *v)
+				}
+				values = []string{d}
+			default:
+				values = []string{*v}
+			}
+		}
+		if err := set(pair.key, values); err != nil {
+			return nil, err
+		}
+		if pair.field == "Keywords" {
+			var kw []string
+			if v != nil {
+				kw = split(*v, ",;")
+			}
+			if err := set("dc:subject", kw); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := set("xmp:MetadataDate", []string{now.Format(time.RFC3339)}); err != nil {
+		return nil, err
+	}
+	if _, err := parseXMP(data); err != nil {
+		return nil, fmt.Errorf("updated XMP packet is not well-formed: %w", err)
+	}
+	if _, err := scanXMPDescriptions(data); err != nil {
+		return nil, fmt.Errorf("updated XMP packet is not well-formed: %w", err)
+	}
+	return data, nil
+}
@@ -41,7 +41,7 @@ const rpc = BrowserView.defineRPC<PdfedDesktopRPC>({
         if (!folder) return { path: null };
         return { path: resolve(folder, suggestedName || "optimized.pdf") };
       },
-      runPdfedArgs: async ({ args, cwd }) => runPdfed(args, cwd || process.cwd()),
+      runPdfedArgs: async ({ args, cwd, stdin }) => runPdfed(args, cwd || process.cwd(), stdin),
       cancelRun: async () => {
         if (!activeRun) return { ok: true, cancelled: false };
         try {
@@ -168,10 +168,10 @@ async function showShortcutDialog() {
   });
 }
 
-async function runPdfed(args: string[], cwd: string) {
+async function runPdfed(args: string[], cwd: string, stdin?: string) {
   const bin = process.env.PDFED_BIN || "pdfed";
   const fullArgs = ["--json", ...args];
-  const { stdout, stderr, code } = await spawnCollect(bin, fullArgs, cwd);
+  const { stdout, stderr, code } = await spawnCollect(bin, fullArgs, cwd, stdin);
 
   const text = stdout.trim();
   if (!text) {
@@ -218,10 +218,13 @@ function normalizeResultPaths(json: PdfedResponse, cwd: string) {
   }
 }
 
-function spawnCollect(bin: string, args: string[], cwd: string) {
+// stdin, if given, is written to the child's standard input; passwords go
+// this way so they never appear in its argument list.
+function spawnCollect(bin: string, args: string[], cwd: string, stdin?: string) {
   return new Promise<{ stdout: string; stderr: string; code: number | null }>((resolvePromise, reject) => {
-    const child = spawn(bin, args, { cwd, stdio: ["ignore", "pipe", "pipe"] });
+    const child = spawn(bin, args, { cwd, stdio: [stdin === undefined ? "ignore" : "pipe", "pipe", "pipe"] });
     activeRun = child;
+    if (stdin !== undefined) child.stdin?.end(stdin);
     let stdout = "";
     let stderr = "";
     child.stdout.on("data", (chunk) => (stdout += String(chunk)));
@@ -10,7 +10,7 @@ export type PdfedDesktopRPC = {
       };
       pickOutputPdf: { params: { suggestedName: string }; response: { path: string | null } };
       runPdfedArgs: {
-        params: { args: string[]; cwd?: string };
+        params: { args: string[]; cwd?: string; stdin?: string };
         response: { ok: boolean; json?: unknown; error?: string };
       };
       cancelRun: { params: {}; response: { ok: boolean; cancelled: boolean; error?: string } };